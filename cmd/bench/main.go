@@ -0,0 +1,102 @@
+// Command bench runs the workloads of the bench package against SkipTrie,
+// a sync.Map with a sorted key copy and a mutex-protected B-tree, printing
+// the results in the standard Go benchmark text format
+//
+// With -queries it instead runs the read-only query suite on pre-built
+// tries of several sizes and configurations, and exits non-zero if any
+// query allocates more than its budget
+//
+//	go run ./cmd/bench                                  # full matrix
+//	go run ./cmd/bench -impl SkipTrie,BTree -g 1,8,64 -workload zipfian
+//	go run ./cmd/bench -count 5 > new.txt && benchstat new.txt
+//	go run ./cmd/bench -queries
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie/bench"
+)
+
+// goroutineList parses a comma-separated list of goroutine counts
+func goroutineList(s string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		g, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || g < 1 {
+			return nil, fmt.Errorf("bad goroutine count %q", f)
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// selected reports whether name matches one of the comma-separated
+// substrings in filter, or filter is empty
+func selected(name, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, f := range strings.Split(filter, ",") {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	var cfg bench.Config
+	implFilter := flag.String("impl", "", "only run implementations whose name contains one of these comma-separated strings")
+	workloadFilter := flag.String("workload", "", "only run workloads whose name contains one of these comma-separated strings")
+	gs := flag.String("g", "1,2,4,8,16,32,64", "comma-separated goroutine counts")
+	flag.IntVar(&cfg.Keys, "keys", 0, "size of the key space, half of it preloaded (0 for the default)")
+	flag.Float64Var(&cfg.Skew, "skew", 0, "Zipf exponent for zipfian workloads, above 1 (0 for the default)")
+	count := flag.Int("count", 1, "runs per benchmark")
+	queries := flag.Bool("queries", false, "run the query suite and check its allocation budgets")
+	flag.Parse()
+	
+	procs := runtime.GOMAXPROCS(0)
+	report := func(r bench.Result) {
+		fmt.Printf("Benchmark%s-%d\t%d\t%.2f ns/op\t%d B/op\t%d allocs/op\n",
+			r.Name, procs, r.N, float64(r.T.Nanoseconds())/float64(r.N),
+			r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+	if *queries {
+		for i := 0; i < *count; i++ {
+			bench.RunQueries(bench.Queries, bench.QueryConfigs, bench.QuerySizes, report)
+		}
+		if err := bench.CheckAllocs(bench.Queries, bench.QueryConfigs, bench.QuerySizes); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	
+	goroutines, err := goroutineList(*gs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		os.Exit(2)
+	}
+	var impls []bench.Impl
+	for _, impl := range bench.Impls {
+		if selected(impl.Name, *implFilter) {
+			impls = append(impls, impl)
+		}
+	}
+	var workloads []bench.Workload
+	for _, w := range bench.Workloads {
+		if selected(w.Name, *workloadFilter) {
+			workloads = append(workloads, w)
+		}
+	}
+	
+	for i := 0; i < *count; i++ {
+		bench.Run(impls, workloads, goroutines, cfg, report)
+	}
+}
@@ -0,0 +1,330 @@
+// Command benchgate runs the core SkipTrie benchmarks and compares them
+// against a stored baseline, exiting non-zero when time or allocations per
+// operation regress beyond the given thresholds
+//
+// Results and baselines use the standard Go benchmark text format, so they
+// can also be fed to benchstat for a fuller statistical comparison:
+//
+//	go run ./cmd/benchgate -update        # record a new baseline
+//	go run ./cmd/benchgate                # compare against it
+//	go run ./cmd/benchgate -o new.txt && benchstat cmd/benchgate/baseline.txt new.txt
+//
+// Baselines are machine specific; record one on the machine the gate runs on
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// setSize is the number of keys preloaded for the read and delete benchmarks
+const setSize = 1 << 12
+
+// benchmark is one entry of the suite
+type benchmark struct {
+	name string
+	fn   func(b *testing.B)
+}
+
+// sample is the per-operation cost of one benchmark run
+type sample struct {
+	nsPerOp     float64
+	bytesPerOp  float64
+	allocsPerOp float64
+}
+
+// suite lists the hot paths the gate protects
+var suite = []benchmark{
+	{"Insert", benchInsert},
+	{"Contains", benchContains},
+	{"Predecessor", benchPredecessor},
+	{"Delete", benchDelete},
+	{"TrieInsert", benchTrieInsert},
+	{"TriePredecessor", benchTriePredecessor},
+}
+
+// keys returns n distinct pseudo-random keys from a fixed seed
+func keys(n int) []uint32 {
+	rng := rand.New(rand.NewPCG(1, 2))
+	seen := make(map[uint32]bool, n)
+	out := make([]uint32, 0, n)
+	for len(out) < n {
+		k := rng.Uint32() >> 1
+		if k != 0 && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// preloaded returns a SkipTrie holding ks
+func preloaded(ks []uint32) *skiptrie.SkipTrie {
+	st := skiptrie.NewSkipTrie(skiptrie.WithRandSource(rand.NewPCG(3, 4)))
+	for _, k := range ks {
+		st.Insert(k)
+	}
+	return st
+}
+
+// topLevel returns a SkipTrie whose nodes all reach the top level, so that
+// every key is published in the x-fast trie
+func topLevel() *skiptrie.SkipTrie {
+	return skiptrie.NewSkipTrie(skiptrie.WithHeightFunc(func(uint32) int { return skiptrie.LogLogU }))
+}
+
+func benchInsert(b *testing.B) {
+	ks := keys(setSize)
+	st := skiptrie.NewSkipTrie(skiptrie.WithRandSource(rand.NewPCG(3, 4)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%setSize == 0 && i > 0 {
+			b.StopTimer()
+			st = skiptrie.NewSkipTrie(skiptrie.WithRandSource(rand.NewPCG(3, 4)))
+			b.StartTimer()
+		}
+		st.Insert(ks[i%setSize])
+	}
+}
+
+func benchContains(b *testing.B) {
+	ks := keys(setSize)
+	st := preloaded(ks)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st.Contains(ks[i%setSize])
+	}
+}
+
+func benchPredecessor(b *testing.B) {
+	ks := keys(setSize)
+	st := preloaded(ks)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st.Predecessor(ks[i%setSize] + 1)
+	}
+}
+
+func benchDelete(b *testing.B) {
+	ks := keys(setSize)
+	st := preloaded(ks)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%setSize == 0 && i > 0 {
+			b.StopTimer()
+			st = preloaded(ks)
+			b.StartTimer()
+		}
+		st.Delete(ks[i%setSize])
+	}
+}
+
+func benchTrieInsert(b *testing.B) {
+	ks := keys(setSize)
+	st := topLevel()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%setSize == 0 && i > 0 {
+			b.StopTimer()
+			st = topLevel()
+			b.StartTimer()
+		}
+		st.Insert(ks[i%setSize])
+	}
+}
+
+func benchTriePredecessor(b *testing.B) {
+	ks := keys(setSize)
+	st := topLevel()
+	for _, k := range ks {
+		st.Insert(k)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st.Predecessor(ks[i%setSize] + 1)
+	}
+}
+
+// run executes the suite count times, writing each result in benchmark
+// text format to w
+func run(w io.Writer, count int, filter string) map[string][]sample {
+	results := make(map[string][]sample)
+	procs := runtime.GOMAXPROCS(0)
+	for _, bm := range suite {
+		if filter != "" && !strings.Contains(bm.name, filter) {
+			continue
+		}
+		for i := 0; i < count; i++ {
+			r := testing.Benchmark(bm.fn)
+			s := sample{
+				nsPerOp:     float64(r.T.Nanoseconds()) / float64(r.N),
+				bytesPerOp:  float64(r.AllocedBytesPerOp()),
+				allocsPerOp: float64(r.AllocsPerOp()),
+			}
+			results[bm.name] = append(results[bm.name], s)
+			fmt.Fprintf(w, "Benchmark%s-%d\t%d\t%.2f ns/op\t%.0f B/op\t%.0f allocs/op\n",
+				bm.name, procs, r.N, s.nsPerOp, s.bytesPerOp, s.allocsPerOp)
+		}
+	}
+	return results
+}
+
+// parse reads benchmark text format, grouping samples by benchmark name
+// without the GOMAXPROCS suffix
+func parse(r io.Reader) (map[string][]sample, error) {
+	results := make(map[string][]sample)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		
+		name := strings.TrimPrefix(fields[0], "Benchmark")
+		if i := strings.LastIndexByte(name, '-'); i >= 0 {
+			name = name[:i]
+		}
+		var s sample
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmark %s: %w", name, err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				s.nsPerOp = v
+			case "B/op":
+				s.bytesPerOp = v
+			case "allocs/op":
+				s.allocsPerOp = v
+			}
+		}
+		results[name] = append(results[name], s)
+	}
+	return results, sc.Err()
+}
+
+// median returns the median of the values picked from samples
+func median(samples []sample, pick func(sample) float64) float64 {
+	vals := make([]float64, len(samples))
+	for i, s := range samples {
+		vals[i] = pick(s)
+	}
+	sort.Float64s(vals)
+	n := len(vals)
+	if n%2 == 1 {
+		return vals[n/2]
+	}
+	return (vals[n/2-1] + vals[n/2]) / 2
+}
+
+// delta returns the relative change from old to new
+func delta(old, new float64) float64 {
+	if old == 0 {
+		if new == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (new - old) / old
+}
+
+// compare prints a table of median changes and reports whether every
+// benchmark stayed within the thresholds
+func compare(w io.Writer, base, curr map[string][]sample, maxTime, maxAlloc float64) bool {
+	ok := true
+	fmt.Fprintf(w, "%-16s %14s %14s %8s %12s %12s %8s\n",
+		"name", "old ns/op", "new ns/op", "delta", "old allocs", "new allocs", "delta")
+	for _, bm := range suite {
+		now, ran := curr[bm.name]
+		if !ran {
+			continue
+		}
+		old, known := base[bm.name]
+		if !known {
+			fmt.Fprintf(w, "%-16s no baseline\n", bm.name)
+			continue
+		}
+		
+		oldNs := median(old, func(s sample) float64 { return s.nsPerOp })
+		newNs := median(now, func(s sample) float64 { return s.nsPerOp })
+		oldAllocs := median(old, func(s sample) float64 { return s.allocsPerOp })
+		newAllocs := median(now, func(s sample) float64 { return s.allocsPerOp })
+		dt, da := delta(oldNs, newNs), delta(oldAllocs, newAllocs)
+		
+		verdict := ""
+		if dt > maxTime || da > maxAlloc {
+			verdict = "  REGRESSION"
+			ok = false
+		}
+		fmt.Fprintf(w, "%-16s %14.2f %14.2f %+7.1f%% %12.0f %12.0f %+7.1f%%%s\n",
+			bm.name, oldNs, newNs, 100*dt, oldAllocs, newAllocs, 100*da, verdict)
+	}
+	return ok
+}
+
+func main() {
+	baseline := flag.String("baseline", "cmd/benchgate/baseline.txt", "baseline results in benchmark text format")
+	update := flag.Bool("update", false, "record the results as the new baseline instead of comparing")
+	out := flag.String("o", "", "also write the results to this file")
+	count := flag.Int("count", 5, "runs per benchmark; medians are compared")
+	filter := flag.String("run", "", "only run benchmarks whose name contains this string")
+	maxTime := flag.Float64("max-time", 0.15, "allowed relative increase in ns/op")
+	maxAlloc := flag.Float64("max-allocs", 0, "allowed relative increase in allocs/op")
+	flag.Parse()
+	
+	var base map[string][]sample
+	if !*update {
+		f, err := os.Open(*baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "benchgate: %v (record one with -update)\n", err)
+			os.Exit(2)
+		}
+		base, err = parse(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "benchgate: %s: %v\n", *baseline, err)
+			os.Exit(2)
+		}
+	}
+	
+	var sb strings.Builder
+	curr := run(io.MultiWriter(os.Stdout, &sb), *count, *filter)
+	
+	dest := *out
+	if *update {
+		dest = *baseline
+	}
+	if dest != "" {
+		if err := os.WriteFile(dest, []byte(sb.String()), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "benchgate: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *update {
+		return
+	}
+	
+	fmt.Println()
+	if !compare(os.Stdout, base, curr, *maxTime, *maxAlloc) {
+		fmt.Fprintln(os.Stderr, "benchgate: regression beyond thresholds")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,111 @@
+// Command skiptried serves a SkipTrie as a standalone ordered-set service
+// over HTTP with JSON bodies; see package server for the endpoints and
+// why there is no gRPC service
+//
+//	skiptried [-addr :8080] [-resp addr] [-changelog n] [-snapshot file]
+//
+// With -resp the same set is also served over the Redis protocol, as
+// package resp describes
+// With -snapshot the set is recovered from a Checkpoint at startup and
+// checkpointed back to the same file on SIGINT or SIGTERM
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+	"github.com/gaarutyunov/skiptrie-go/skiptrie/resp"
+	"github.com/gaarutyunov/skiptrie-go/skiptrie/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	respAddr := flag.String("resp", "", "address to serve the Redis protocol on, if any")
+	changelog := flag.Int("changelog", 1<<16, "changes retained for replicas resuming a stream")
+	snapshot := flag.String("snapshot", "", "file to recover from at startup and checkpoint to at shutdown")
+	flag.Parse()
+	
+	st, err := open(*snapshot, skiptrie.WithChangelog(*changelog))
+	if err != nil {
+		log.Fatal(err)
+	}
+	
+	srv := &http.Server{Addr: *addr, Handler: server.New(st)}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	
+	if *respAddr != "" {
+		l, err := net.Listen("tcp", *respAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rs := resp.New(st)
+		defer rs.Close()
+		go rs.Serve(l)
+		log.Printf("skiptried: serving the Redis protocol on %s", *respAddr)
+	}
+	
+	log.Printf("skiptried: serving %d keys on %s", st.Len(), *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	st.Close()
+	
+	if *snapshot != "" {
+		if err := save(st, *snapshot); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("skiptried: checkpointed %d keys to %s", st.Len(), *snapshot)
+	}
+}
+
+// open recovers the set from path, or creates an empty one if path is
+// empty or does not exist yet
+func open(path string, opts ...skiptrie.Option) (*skiptrie.SkipTrie, error) {
+	if path == "" {
+		return skiptrie.NewSkipTrie(opts...), nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return skiptrie.NewSkipTrie(opts...), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return skiptrie.Recover(f, nil, opts...)
+}
+
+// save checkpoints st to path, replacing it only once the new snapshot is
+// complete
+func save(st *skiptrie.SkipTrie, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := st.Checkpoint(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,9 @@
+//go:build stress && arm64
+
+package main
+
+import "github.com/gaarutyunov/skiptrie-go/skiptrie"
+
+func init() {
+	scenarios = append(scenarios, scenario{"Publication", skiptrie.StressPublication})
+}
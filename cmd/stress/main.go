@@ -0,0 +1,101 @@
+//go:build stress
+
+// Command stress runs the SkipTrie stress scenarios, which target the
+// windows between marking and unlinking, tower raising and deletion, trie
+// repair and concurrent inserts, trie walks and top-level deletes, and node
+// recycling and stale references, and random operation sequences checked
+// against a reference model; on arm64 it also races readers against node
+// publication. Replay runs the configurable workload of the stress
+// package, sized by the SKIPTRIE_STRESS_* variables where the flags leave
+// it open, and checks the final state against a replay of the changelog
+//
+// The scenarios are short and meant for the race detector:
+//
+//	go run -race -tags stress ./cmd/stress
+//	go run -race -tags stress ./cmd/stress -run Tower -d 2s -rounds 20
+//	go run -race -tags stress,fences ./cmd/stress    # audit memory ordering
+//	SKIPTRIE_STRESS_DURATION=1m go run -race -tags stress ./cmd/stress -run Replay -rounds 1
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+	"github.com/gaarutyunov/skiptrie-go/skiptrie/stress"
+)
+
+// scenario is a stress entry point and its name
+type scenario struct {
+	name string
+	fn   func(skiptrie.StressConfig) error
+}
+
+// scenarios lists the stress entry points by name
+var scenarios = []scenario{
+	{"MarkUnlink", skiptrie.StressMarkUnlink},
+	{"TowerVsDelete", skiptrie.StressTowerVsDelete},
+	{"TallTowers", skiptrie.StressTallTowers},
+	{"TrieRepair", skiptrie.StressTrieRepair},
+	{"TrieBack", skiptrie.StressTrieBack},
+	{"LazyRepair", skiptrie.StressLazyRepair},
+	{"NoHelp", skiptrie.StressNoHelp},
+	{"BulkDelete", skiptrie.StressBulkDelete},
+	{"Recycle", skiptrie.StressRecycle},
+	{"Operations", skiptrie.StressOperations},
+	{"Replay", replay},
+}
+
+// replay runs the stress package's workload, taking what cfg leaves open
+// from the environment
+func replay(cfg skiptrie.StressConfig) error {
+	rc, err := stress.FromEnv()
+	if err != nil {
+		return err
+	}
+	if cfg.Goroutines > 0 {
+		rc.Goroutines = cfg.Goroutines
+	}
+	if cfg.Keys > 0 {
+		rc.Keys = cfg.Keys
+	}
+	if cfg.Duration > 0 {
+		rc.Duration = cfg.Duration
+	}
+	_, err = stress.Run(rc)
+	return err
+}
+
+func main() {
+	var cfg skiptrie.StressConfig
+	flag.IntVar(&cfg.Goroutines, "g", 0, "concurrent workers (0 for the default)")
+	flag.IntVar(&cfg.Keys, "keys", 0, "size of the key space (0 for the default)")
+	flag.DurationVar(&cfg.Duration, "d", 0, "length of each round (0 for the default)")
+	rounds := flag.Int("rounds", 5, "rounds per scenario")
+	filter := flag.String("run", "", "only run scenarios whose name contains this string")
+	flag.Parse()
+	
+	failed := false
+	for _, sc := range scenarios {
+		if !strings.Contains(sc.name, *filter) {
+			continue
+		}
+		start := time.Now()
+		var err error
+		for i := 0; i < *rounds && err == nil; i++ {
+			err = sc.fn(cfg)
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", sc.name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("ok   %s\t%v\n", sc.name, time.Since(start).Round(time.Millisecond))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/gaarutyunov/skiptrie-go
+
+go 1.23
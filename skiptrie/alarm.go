@@ -0,0 +1,104 @@
+package skiptrie
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// alarm is a key-count watermark registered by OnThreshold or
+// OnLowThreshold
+type alarm struct {
+	n     int64
+	low   bool // fires when the count falls to n rather than rises
+	fn    func()
+	armed atomic.Bool // cleared when fn runs, set again past the hysteresis
+}
+
+// alarms holds the registered watermarks; the slice is copied on write so
+// that size changes read it without locking
+type alarms struct {
+	mu         sync.Mutex
+	list       atomic.Pointer[[]*alarm]
+	hysteresis int64
+}
+
+// WithHysteresis sets how far the key count must move back past a
+// watermark before its alarm can fire again; the default is 0, so an alarm
+// re-arms as soon as the count is back on the other side
+func WithHysteresis(h uint64) Option {
+	return func(st *SkipTrie) {
+		st.alarms.hysteresis = int64(h)
+	}
+}
+
+// OnThreshold calls fn each time the number of live keys rises to n from
+// below it, and returns a function that removes the alarm
+// fn runs on the goroutine whose insert crossed the watermark, so it
+// should be short or hand the work off
+func (st *SkipTrie) OnThreshold(n uint64, fn func()) (stop func()) {
+	return st.addAlarm(&alarm{n: int64(n), fn: fn})
+}
+
+// OnLowThreshold calls fn each time the number of live keys falls to n
+// from above it, and returns a function that removes the alarm
+// fn runs on the goroutine whose delete crossed the watermark
+func (st *SkipTrie) OnLowThreshold(n uint64, fn func()) (stop func()) {
+	return st.addAlarm(&alarm{n: int64(n), low: true, fn: fn})
+}
+
+// addAlarm registers a, armed unless the count is already past it
+func (st *SkipTrie) addAlarm(a *alarm) func() {
+	size := st.size.Load()
+	a.armed.Store(a.low && size > a.n || !a.low && size < a.n)
+	
+	l := &st.alarms
+	l.mu.Lock()
+	var list []*alarm
+	if old := l.list.Load(); old != nil {
+		list = append(list, *old...)
+	}
+	list = append(list, a)
+	l.list.Store(&list)
+	l.mu.Unlock()
+	
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		old := l.list.Load()
+		if old == nil {
+			return
+		}
+		var list []*alarm
+		for _, other := range *old {
+			if other != a {
+				list = append(list, other)
+			}
+		}
+		if len(list) == 0 {
+			l.list.Store(nil)
+		} else {
+			l.list.Store(&list)
+		}
+	}
+}
+
+// checkAlarms fires and re-arms the watermarks after the key count changed
+// to size
+func (st *SkipTrie) checkAlarms(size int64) {
+	list := st.alarms.list.Load()
+	if list == nil {
+		return
+	}
+	
+	h := st.alarms.hysteresis
+	for _, a := range *list {
+		switch {
+		case !a.low && size >= a.n, a.low && size <= a.n:
+			if a.armed.CompareAndSwap(true, false) {
+				a.fn()
+			}
+		case !a.low && size < a.n-h, a.low && size > a.n+h:
+			a.armed.Store(true)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package skiptrie
+
+import "sync"
+
+// Allocator supplies the nodes of a SkipTrie
+//
+// New returns a zeroed node; Free takes back a node the SkipTrie no longer
+// references. Lock-free readers may still hold a node after it is deleted,
+// so deleted nodes are left to the garbage collector: Free only receives
+// nodes that were never published (an insert that found its key present),
+// the nodes of an instance emptied by Pool.Put, which its caller has
+// promised no longer to use, and with WithNodeReuse deleted nodes no
+// reader can reach any more. Both methods may be called concurrently
+type Allocator interface {
+	New() *Node
+	Free(node *Node)
+}
+
+// WithAllocator takes nodes from a instead of the heap
+func WithAllocator(a Allocator) Option {
+	return func(st *SkipTrie) {
+		st.alloc = a
+	}
+}
+
+// HeapAllocator allocates every node with new and leaves freed nodes to
+// the garbage collector; it is the default
+type HeapAllocator struct{}
+
+// New implements Allocator
+func (HeapAllocator) New() *Node {
+	return new(Node)
+}
+
+// Free implements Allocator
+func (HeapAllocator) Free(*Node) {}
+
+// PoolAllocator recycles freed nodes through a sync.Pool, which may drop
+// them at any garbage collection
+type PoolAllocator struct {
+	pool sync.Pool
+}
+
+// NewPoolAllocator creates an empty PoolAllocator
+func NewPoolAllocator() *PoolAllocator {
+	return &PoolAllocator{pool: sync.Pool{New: func() any { return new(Node) }}}
+}
+
+// New implements Allocator
+func (a *PoolAllocator) New() *Node {
+	return a.pool.Get().(*Node)
+}
+
+// Free implements Allocator
+func (a *PoolAllocator) Free(node *Node) {
+	*node = Node{}
+	a.pool.Put(node)
+}
+
+// ArenaAllocator carves nodes out of slabs allocated together, keeping
+// nodes close in memory and reducing the number of objects the garbage
+// collector tracks; freed nodes are reused before a new slab is taken
+// Memory is only returned to the runtime once the allocator and every node
+// of every slab are unreachable
+type ArenaAllocator struct {
+	mu   sync.Mutex
+	slab []Node  // unused tail of the current slab
+	free []*Node // freed nodes
+	size int     // nodes per slab
+}
+
+// NewArenaAllocator creates an ArenaAllocator taking slabs of slabSize
+// nodes, at least 1
+func NewArenaAllocator(slabSize int) *ArenaAllocator {
+	return &ArenaAllocator{size: max(1, slabSize)}
+}
+
+// New implements Allocator
+func (a *ArenaAllocator) New() *Node {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	
+	if n := len(a.free); n > 0 {
+		node := a.free[n-1]
+		a.free = a.free[:n-1]
+		return node
+	}
+	if len(a.slab) == 0 {
+		a.slab = make([]Node, a.size)
+	}
+	node := &a.slab[0]
+	a.slab = a.slab[1:]
+	return node
+}
+
+// Free implements Allocator
+func (a *ArenaAllocator) Free(node *Node) {
+	*node = Node{}
+	a.mu.Lock()
+	a.free = append(a.free, node)
+	a.mu.Unlock()
+}
+
+// freeAll hands every node of a quiescent instance back to its allocator
+func (st *SkipTrie) freeAll() {
+	if _, ok := st.alloc.(HeapAllocator); ok {
+		return
+	}
+	for curr := st.head.next[0].Load(); curr != nil && curr != st.tail; {
+		next := curr.next[0].Load()
+		st.alloc.Free(curr)
+		curr = next
+	}
+}
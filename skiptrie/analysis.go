@@ -0,0 +1,58 @@
+package skiptrie
+
+// analysisHopFactor is the slack of the skiplist part of the cost model:
+// starting from the trie's answer a search takes an expected 2 hops per
+// level, so more than analysisHopFactor*LogLogU hops in total points at a
+// missing trie entry, a stale prev pointer or degenerate towers
+const analysisHopFactor = 4
+
+// analysisBound is the cost model's budget for a single query: one probe
+// per step of the binary search over prefix lengths plus the root lookup,
+// and the skiplist hops allowed by analysisHopFactor
+const analysisBound = (LogLogU + 1) + analysisHopFactor*LogLogU
+
+// opTrace counts the work done by one query in analysis mode
+type opTrace struct {
+	trieProbes int // prefix table lookups
+	backSteps  int // prev/back pointer steps after the trie lookup
+	listHops   int // skiplist nodes stepped over
+}
+
+// OpReport describes a query whose cost exceeded the O(log log u) model
+type OpReport struct {
+	Op         string // name of the query
+	Key        uint32 // queried key
+	TrieProbes int    // prefix table lookups
+	BackSteps  int    // prev/back pointer steps after the trie lookup
+	ListHops   int    // skiplist nodes stepped over
+	Bound      int    // budget for the sum of the three counts
+}
+
+// WithAnalysis records the work of every Predecessor-based query (and so of
+// Contains, Delete and the range helpers built on it) and calls fn for each
+// query that exceeds the cost model, typically a sign that a fallback path
+// or a structural problem is being hit
+// fn runs synchronously on the querying goroutine
+func WithAnalysis(fn func(OpReport)) Option {
+	return func(st *SkipTrie) {
+		st.analysis = fn
+	}
+}
+
+// analyze checks a traced query against the cost model
+func (st *SkipTrie) analyze(op string, key uint32, tr *opTrace) {
+	st.analyzed.Add(1)
+	if tr.trieProbes+tr.backSteps+tr.listHops <= analysisBound {
+		return
+	}
+	
+	st.flagged.Add(1)
+	st.analysis(OpReport{
+		Op:         op,
+		Key:        key,
+		TrieProbes: tr.trieProbes,
+		BackSteps:  tr.backSteps,
+		ListHops:   tr.listHops,
+		Bound:      analysisBound,
+	})
+}
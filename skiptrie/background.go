@@ -0,0 +1,89 @@
+package skiptrie
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is reported by Err once Close has stopped the background work
+var ErrClosed = errors.New("skiptrie: closed")
+
+// workers supervises the background goroutines of a SkipTrie as one group:
+// they share a context, and the first one to fail cancels the rest
+type workers struct {
+	mu     sync.Mutex
+	parent context.Context         // set by WithContext
+	ctx    context.Context         // cancelled by Close, a failure or parent
+	cancel context.CancelCauseFunc // records why ctx was cancelled
+	wg     sync.WaitGroup
+}
+
+// context returns the group context, creating it on first use
+func (w *workers) context() context.Context {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.contextLocked()
+}
+
+// contextLocked is context with w.mu held
+func (w *workers) contextLocked() context.Context {
+	if w.ctx == nil {
+		parent := w.parent
+		if parent == nil {
+			parent = context.Background()
+		}
+		w.ctx, w.cancel = context.WithCancelCause(parent)
+	}
+	return w.ctx
+}
+
+// WithContext ties the background workers to ctx: cancelling it stops them
+// as Close would, and Err reports its cause
+func WithContext(ctx context.Context) Option {
+	return func(st *SkipTrie) {
+		st.bg.parent = ctx
+	}
+}
+
+// goBackground runs f in a background goroutine; f must return promptly
+// once ctx is done. A non-nil error from f stops the other workers and is
+// reported by Err
+func (st *SkipTrie) goBackground(f func(ctx context.Context) error) {
+	st.bg.mu.Lock()
+	ctx := st.bg.contextLocked()
+	st.bg.wg.Add(1)
+	st.bg.mu.Unlock()
+	
+	go func() {
+		defer st.bg.wg.Done()
+		if err := f(ctx); err != nil {
+			st.bg.cancel(err)
+		}
+	}()
+}
+
+// Done returns a channel that is closed once the background work has been
+// told to stop, by Close, a failing worker or the WithContext context
+func (st *SkipTrie) Done() <-chan struct{} {
+	return st.bg.context().Done()
+}
+
+// Err returns nil while background work may run, and afterwards why it
+// stopped: the first worker error, ErrClosed, or the cause of the
+// WithContext context's cancellation
+func (st *SkipTrie) Err() error {
+	ctx := st.bg.context()
+	if ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(ctx)
+}
+
+// Close stops all background work and waits for it to finish
+// The SkipTrie stays usable; background work started later stops at once
+func (st *SkipTrie) Close() {
+	st.bg.context()
+	st.bg.cancel(ErrClosed)
+	st.bg.wg.Wait()
+}
@@ -0,0 +1,223 @@
+// Package bench runs parameterized workloads against SkipTrie and the
+// structures it is usually compared with, a sync.Map with a sorted key copy
+// and a mutex-protected B-tree, so that performance claims can be checked
+// on any machine
+//
+// Each run is a testing.Benchmark, so results carry ns/op, B/op and
+// allocs/op; cmd/bench prints them in the standard benchmark text format
+package bench
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// Set is the ordered set contract every implementation under test meets
+type Set interface {
+	Insert(key uint32) bool
+	Delete(key uint32) bool
+	Contains(key uint32) bool
+	PredecessorKey(key uint32) (uint32, bool)
+}
+
+// Impl names a Set implementation and constructs empty instances of it
+type Impl struct {
+	Name string
+	New  func() Set
+}
+
+// Impls lists the implementations under test
+var Impls = []Impl{
+	{"SkipTrie", func() Set { return skiptrie.NewSkipTrie() }},
+	{"SkipTrieHelpNever", func() Set { return helping(skiptrie.HelpNever) }},
+	{"SkipTrieHelpSometimes", func() Set { return helping(skiptrie.HelpProbabilistic) }},
+	{"SyncMapSort", func() Set { return NewSyncMapSort() }},
+	{"BTree", func() Set { return NewBTree() }},
+}
+
+// helping returns a SkipTrie whose queries follow the help policy p, to
+// compare the policies across the read/write mixes
+func helping(p skiptrie.HelpPolicy) Set {
+	return skiptrie.NewSkipTrie(skiptrie.WithHelpPolicy(p))
+}
+
+// Workload describes an operation mix and how its keys are drawn
+type Workload struct {
+	Name  string
+	Reads float64 // fraction of reads, split evenly between Contains and PredecessorKey
+	Zipf  bool    // draw keys from a Zipf distribution instead of uniformly
+}
+
+// Workloads lists the standard operation mixes
+var Workloads = []Workload{
+	{"read-heavy/uniform", 0.9, false},
+	{"write-heavy/uniform", 0.1, false},
+	{"read-heavy/zipfian", 0.9, true},
+	{"write-heavy/zipfian", 0.1, true},
+}
+
+// Goroutines lists the standard concurrency levels
+var Goroutines = []int{1, 2, 4, 8, 16, 32, 64}
+
+// Config sizes a run; zero fields take the defaults
+type Config struct {
+	Keys int     // size of the key space, half of it preloaded, default 1<<12
+	Skew float64 // Zipf exponent s > 1 for zipfian workloads, default 1.1
+}
+
+// withDefaults fills in the zero fields of c
+func (c Config) withDefaults() Config {
+	if c.Keys <= 0 {
+		c.Keys = 1 << 12
+	}
+	if c.Skew <= 1 {
+		c.Skew = 1.1
+	}
+	return c
+}
+
+// op is one pre-generated operation
+type op struct {
+	kind uint8
+	key  uint32
+}
+
+// Operation kinds
+const (
+	opContains uint8 = iota
+	opPredecessor
+	opInsert
+	opDelete
+)
+
+// streamLen is the number of operations pre-generated per goroutine, so
+// that drawing keys is not part of the measured cost
+const streamLen = 1 << 14
+
+// keySpace returns n distinct keys spread over the universe, skipping the
+// reserved maximum
+func keySpace(n int) []uint32 {
+	stride := uint32(0xFFFFFFFE / uint64(max(n, 1)))
+	out := make([]uint32, n)
+	for i := range out {
+		out[i] = uint32(i) * stride
+	}
+	return out
+}
+
+// stream returns the operations of goroutine g for w over keys
+func stream(w Workload, cfg Config, keys []uint32, g int) []op {
+	rng := rand.New(rand.NewPCG(uint64(g), 7))
+	draw := func() uint32 { return keys[rng.IntN(len(keys))] }
+	if w.Zipf {
+		// Hot keys are scattered over the key space rather than clustered
+		perm := rng.Perm(len(keys))
+		zipf := rand.NewZipf(rng, cfg.Skew, 1, uint64(len(keys)-1))
+		draw = func() uint32 { return keys[perm[zipf.Uint64()]] }
+	}
+	
+	ops := make([]op, streamLen)
+	for i := range ops {
+		r := rng.Float64()
+		switch {
+		case r < w.Reads/2:
+			ops[i].kind = opContains
+		case r < w.Reads:
+			ops[i].kind = opPredecessor
+		case r < (1+w.Reads)/2:
+			ops[i].kind = opInsert
+		default:
+			ops[i].kind = opDelete
+		}
+		ops[i].key = draw()
+	}
+	return ops
+}
+
+// preload inserts every other key of keys into s, through InsertBatch when
+// s offers it
+func preload(s Set, keys []uint32) {
+	half := make([]uint32, 0, len(keys)/2)
+	for i := 0; i < len(keys); i += 2 {
+		half = append(half, keys[i])
+	}
+	if b, ok := s.(interface{ InsertBatch([]uint32) []bool }); ok {
+		b.InsertBatch(half)
+		return
+	}
+	for _, k := range half {
+		s.Insert(k)
+	}
+}
+
+// Name returns the benchmark name of impl under w with g goroutines
+func Name(impl Impl, w Workload, g int) string {
+	return fmt.Sprintf("%s/%s/g=%d", impl.Name, w.Name, g)
+}
+
+// Benchmark returns the benchmark body for impl under w with g goroutines
+// b.N operations are shared out among the goroutines, so ns/op is wall time
+// per operation across all of them
+func Benchmark(impl Impl, w Workload, g int, cfg Config) func(b *testing.B) {
+	cfg = cfg.withDefaults()
+	return func(b *testing.B) {
+		keys := keySpace(cfg.Keys)
+		s := impl.New()
+		preload(s, keys)
+		streams := make([][]op, g)
+		for i := range streams {
+			streams[i] = stream(w, cfg, keys, i)
+		}
+		
+		b.ReportAllocs()
+		b.ResetTimer()
+		var wg sync.WaitGroup
+		for i := 0; i < g; i++ {
+			n := b.N / g
+			if i < b.N%g {
+				n++
+			}
+			wg.Add(1)
+			go func(ops []op) {
+				defer wg.Done()
+				for j := 0; j < n; j++ {
+					o := ops[j%len(ops)]
+					switch o.kind {
+					case opContains:
+						s.Contains(o.key)
+					case opPredecessor:
+						s.PredecessorKey(o.key)
+					case opInsert:
+						s.Insert(o.key)
+					case opDelete:
+						s.Delete(o.key)
+					}
+				}
+			}(streams[i])
+		}
+		wg.Wait()
+	}
+}
+
+// Result is the outcome of one benchmark run
+type Result struct {
+	Name string
+	testing.BenchmarkResult
+}
+
+// Run benchmarks every combination of impls, workloads and goroutine
+// counts, calling report after each
+func Run(impls []Impl, workloads []Workload, goroutines []int, cfg Config, report func(Result)) {
+	for _, w := range workloads {
+		for _, g := range goroutines {
+			for _, impl := range impls {
+				r := testing.Benchmark(Benchmark(impl, w, g, cfg))
+				report(Result{Name: Name(impl, w, g), BenchmarkResult: r})
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package bench
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// btreeDegree is the minimum degree of the BTree: nodes other than the
+// root hold between btreeDegree-1 and 2*btreeDegree-1 keys
+const btreeDegree = 16
+
+// BTree is an in-memory B-tree of keys behind a read-write mutex, the
+// usual way to share an ordered set between goroutines without a
+// concurrent data structure
+type BTree struct {
+	mu   sync.RWMutex
+	root *btreeNode
+}
+
+// btreeNode is a B-tree node; children is nil for leaves
+type btreeNode struct {
+	keys     []uint32
+	children []*btreeNode
+}
+
+// NewBTree creates an empty BTree
+func NewBTree() *BTree {
+	return &BTree{root: &btreeNode{}}
+}
+
+// Insert inserts key, reporting whether it was absent
+func (t *BTree) Insert(key uint32) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if t.root.contains(key) {
+		return false
+	}
+	if len(t.root.keys) == 2*btreeDegree-1 {
+		root := &btreeNode{children: []*btreeNode{t.root}}
+		root.splitChild(0)
+		t.root = root
+	}
+	t.root.insertNonFull(key)
+	return true
+}
+
+// Delete deletes key, reporting whether it was present
+func (t *BTree) Delete(key uint32) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	deleted := t.root.delete(key)
+	if len(t.root.keys) == 0 && t.root.children != nil {
+		t.root = t.root.children[0]
+	}
+	return deleted
+}
+
+// Contains checks if key exists
+func (t *BTree) Contains(key uint32) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.root.contains(key)
+}
+
+// PredecessorKey returns the largest key strictly less than key
+func (t *BTree) PredecessorKey(key uint32) (uint32, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	
+	var pred uint32
+	found := false
+	for x := t.root; x != nil; {
+		i := sort.Search(len(x.keys), func(j int) bool { return x.keys[j] >= key })
+		if i > 0 {
+			pred, found = x.keys[i-1], true
+		}
+		if x.children == nil {
+			break
+		}
+		x = x.children[i]
+	}
+	return pred, found
+}
+
+// contains searches the subtree of x for key
+func (x *btreeNode) contains(key uint32) bool {
+	for {
+		i, found := slices.BinarySearch(x.keys, key)
+		if found {
+			return true
+		}
+		if x.children == nil {
+			return false
+		}
+		x = x.children[i]
+	}
+}
+
+// splitChild splits the full child i of x around its median key
+func (x *btreeNode) splitChild(i int) {
+	y := x.children[i]
+	mid := btreeDegree - 1
+	z := &btreeNode{keys: slices.Clone(y.keys[mid+1:])}
+	if y.children != nil {
+		z.children = slices.Clone(y.children[mid+1:])
+		y.children = y.children[:mid+1]
+	}
+	median := y.keys[mid]
+	y.keys = y.keys[:mid]
+	x.keys = slices.Insert(x.keys, i, median)
+	x.children = slices.Insert(x.children, i+1, z)
+}
+
+// insertNonFull inserts an absent key into the subtree of x, which is not
+// full, splitting full nodes on the way down
+func (x *btreeNode) insertNonFull(key uint32) {
+	for {
+		i, _ := slices.BinarySearch(x.keys, key)
+		if x.children == nil {
+			x.keys = slices.Insert(x.keys, i, key)
+			return
+		}
+		if len(x.children[i].keys) == 2*btreeDegree-1 {
+			x.splitChild(i)
+			if key > x.keys[i] {
+				i++
+			}
+		}
+		x = x.children[i]
+	}
+}
+
+// delete removes key from the subtree of x, whose keys number at least
+// btreeDegree unless x is the root, keeping every node visited above the
+// minimum on the way down
+func (x *btreeNode) delete(key uint32) bool {
+	i, found := slices.BinarySearch(x.keys, key)
+	if found {
+		if x.children == nil {
+			x.keys = slices.Delete(x.keys, i, i+1)
+			return true
+		}
+		left, right := x.children[i], x.children[i+1]
+		switch {
+		case len(left.keys) >= btreeDegree:
+			pred := left.max()
+			x.keys[i] = pred
+			return left.delete(pred)
+		case len(right.keys) >= btreeDegree:
+			succ := right.min()
+			x.keys[i] = succ
+			return right.delete(succ)
+		}
+		x.merge(i)
+		return left.delete(key)
+	}
+	if x.children == nil {
+		return false
+	}
+	
+	if len(x.children[i].keys) < btreeDegree {
+		switch {
+		case i > 0 && len(x.children[i-1].keys) >= btreeDegree:
+			x.borrowLeft(i)
+		case i < len(x.keys) && len(x.children[i+1].keys) >= btreeDegree:
+			x.borrowRight(i)
+		case i < len(x.keys):
+			x.merge(i)
+		default:
+			x.merge(i - 1)
+			i--
+		}
+	}
+	return x.children[i].delete(key)
+}
+
+// merge joins child i+1 and the key between them into child i
+func (x *btreeNode) merge(i int) {
+	left, right := x.children[i], x.children[i+1]
+	left.keys = append(append(left.keys, x.keys[i]), right.keys...)
+	left.children = append(left.children, right.children...)
+	x.keys = slices.Delete(x.keys, i, i+1)
+	x.children = slices.Delete(x.children, i+1, i+2)
+}
+
+// borrowLeft moves a key from child i-1 through x into child i
+func (x *btreeNode) borrowLeft(i int) {
+	child, left := x.children[i], x.children[i-1]
+	child.keys = slices.Insert(child.keys, 0, x.keys[i-1])
+	x.keys[i-1] = left.keys[len(left.keys)-1]
+	left.keys = left.keys[:len(left.keys)-1]
+	if left.children != nil {
+		child.children = slices.Insert(child.children, 0, left.children[len(left.children)-1])
+		left.children = left.children[:len(left.children)-1]
+	}
+}
+
+// borrowRight moves a key from child i+1 through x into child i
+func (x *btreeNode) borrowRight(i int) {
+	child, right := x.children[i], x.children[i+1]
+	child.keys = append(child.keys, x.keys[i])
+	x.keys[i] = right.keys[0]
+	right.keys = slices.Delete(right.keys, 0, 1)
+	if right.children != nil {
+		child.children = append(child.children, right.children[0])
+		right.children = slices.Delete(right.children, 0, 1)
+	}
+}
+
+// min returns the smallest key in the subtree of x
+func (x *btreeNode) min() uint32 {
+	for x.children != nil {
+		x = x.children[0]
+	}
+	return x.keys[0]
+}
+
+// max returns the largest key in the subtree of x
+func (x *btreeNode) max() uint32 {
+	for x.children != nil {
+		x = x.children[len(x.children)-1]
+	}
+	return x.keys[len(x.keys)-1]
+}
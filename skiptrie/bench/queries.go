@@ -0,0 +1,144 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// rangeWidth is the number of keys each Range query visits
+const rangeWidth = 16
+
+// Query is a read-only SkipTrie query measured on pre-built tries, with the
+// most allocations per call it is allowed to make
+type Query struct {
+	Name      string
+	MaxAllocs float64
+	run       func(st *skiptrie.SkipTrie, keys []uint32, i int)
+}
+
+// Queries lists the query suite; every query is expected not to allocate
+// The point queries run for present keys and for the gaps between them,
+// which end their searches differently
+var Queries = []Query{
+	{"Contains", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.Contains(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"Predecessor", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.Predecessor(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"PredecessorKey", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.PredecessorKey(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"PredecessorLive", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.PredecessorLive(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"Successor", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.SuccessorKey(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"Floor", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.Floor(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"Locate", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		st.Locate(keys[i%len(keys)] + uint32(i&1))
+	}},
+	{"Range", 0, func(st *skiptrie.SkipTrie, keys []uint32, i int) {
+		j := i % (len(keys) - rangeWidth)
+		for range st.Between(keys[j], keys[j+rangeWidth-1]) {
+		}
+	}},
+}
+
+// QuerySizes lists the trie sizes the query suite runs at
+var QuerySizes = []int{1 << 8, 1 << 12, 1 << 16}
+
+// QueryConfig names a set of options the query suite builds its tries with
+type QueryConfig struct {
+	Name    string
+	Options []skiptrie.Option
+}
+
+// QueryConfigs lists the configurations the query suite runs under: the
+// options that change the read path must keep it allocation-free too
+var QueryConfigs = []QueryConfig{
+	{"default", nil},
+	{"reuse", []skiptrie.Option{skiptrie.WithNodeReuse()}},
+	{"live", []skiptrie.Option{skiptrie.WithLivePredecessor()}},
+	{"opstats", []skiptrie.Option{skiptrie.WithOpStats()}},
+	{"helpnever", []skiptrie.Option{skiptrie.WithHelpPolicy(skiptrie.HelpNever)}},
+}
+
+// queryTrie returns a SkipTrie configured by c holding size keys spread
+// over the universe, and the keys
+func queryTrie(c QueryConfig, size int) (*skiptrie.SkipTrie, []uint32) {
+	keys := keySpace(size)
+	st := skiptrie.NewSkipTrie(c.Options...)
+	st.InsertBatch(keys)
+	return st, keys
+}
+
+// QueryName returns the benchmark name of q under c at size
+func QueryName(q Query, c QueryConfig, size int) string {
+	return fmt.Sprintf("Query/%s/%s/n=%d", q.Name, c.Name, size)
+}
+
+// QueryBenchmark returns the benchmark body for q on a trie of size keys
+// configured by c
+func QueryBenchmark(q Query, c QueryConfig, size int) func(b *testing.B) {
+	return func(b *testing.B) {
+		st, keys := queryTrie(c, size)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.run(st, keys, i)
+		}
+	}
+}
+
+// RunQueries benchmarks every query under every configuration at every
+// size, calling report after each
+func RunQueries(queries []Query, configs []QueryConfig, sizes []int, report func(Result)) {
+	for _, c := range configs {
+		for _, size := range sizes {
+			for _, q := range queries {
+				r := testing.Benchmark(QueryBenchmark(q, c, size))
+				report(Result{Name: QueryName(q, c, size), BenchmarkResult: r})
+			}
+		}
+	}
+}
+
+// CheckAllocs measures the allocations per call of every query under
+// every configuration at every size with testing.AllocsPerRun, and returns
+// an error naming each query over its budget
+func CheckAllocs(queries []Query, configs []QueryConfig, sizes []int) error {
+	var over []string
+	for _, c := range configs {
+		for _, size := range sizes {
+			over = append(over, checkAllocs(queries, c, size)...)
+		}
+	}
+	if over != nil {
+		return fmt.Errorf("allocation budget exceeded:\n\t%s", strings.Join(over, "\n\t"))
+	}
+	return nil
+}
+
+// checkAllocs describes each query over its budget under c at size
+func checkAllocs(queries []Query, c QueryConfig, size int) []string {
+	var over []string
+	st, keys := queryTrie(c, size)
+	for _, q := range queries {
+		i := 0
+		allocs := testing.AllocsPerRun(1000, func() {
+			q.run(st, keys, i)
+			i++
+		})
+		if allocs > q.MaxAllocs {
+			over = append(over, fmt.Sprintf("%s: %.1f allocs/op, want at most %.0f", QueryName(q, c, size), allocs, q.MaxAllocs))
+		}
+	}
+	return over
+}
@@ -0,0 +1,27 @@
+package bench
+
+import "testing"
+
+// TestQueryAllocs fails for every query of the suite that allocates more
+// than its budget, under every configuration at every size
+func TestQueryAllocs(t *testing.T) {
+	for _, c := range QueryConfigs {
+		for _, size := range QuerySizes {
+			for _, over := range checkAllocs(Queries, c, size) {
+				t.Error(over)
+			}
+		}
+	}
+}
+
+// BenchmarkQueries runs the query suite, so it can be run with -benchmem
+// under go test
+func BenchmarkQueries(b *testing.B) {
+	for _, c := range QueryConfigs {
+		for _, size := range QuerySizes {
+			for _, q := range Queries {
+				b.Run(QueryName(q, c, size), QueryBenchmark(q, c, size))
+			}
+		}
+	}
+}
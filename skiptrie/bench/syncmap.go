@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// SyncMapSort is the common stopgap for a concurrent ordered set: a
+// sync.Map for point operations, and a sorted copy of its keys for ordered
+// queries, rebuilt under a mutex by the first query after an update
+type SyncMapSort struct {
+	m      sync.Map
+	dirty  atomic.Bool
+	mu     sync.Mutex
+	sorted atomic.Pointer[[]uint32]
+}
+
+// NewSyncMapSort creates an empty SyncMapSort
+func NewSyncMapSort() *SyncMapSort {
+	s := &SyncMapSort{}
+	s.sorted.Store(new([]uint32))
+	return s
+}
+
+// Insert inserts key, reporting whether it was absent
+func (s *SyncMapSort) Insert(key uint32) bool {
+	if _, loaded := s.m.LoadOrStore(key, struct{}{}); loaded {
+		return false
+	}
+	s.dirty.Store(true)
+	return true
+}
+
+// Delete deletes key, reporting whether it was present
+func (s *SyncMapSort) Delete(key uint32) bool {
+	if _, loaded := s.m.LoadAndDelete(key); !loaded {
+		return false
+	}
+	s.dirty.Store(true)
+	return true
+}
+
+// Contains checks if key exists
+func (s *SyncMapSort) Contains(key uint32) bool {
+	_, ok := s.m.Load(key)
+	return ok
+}
+
+// PredecessorKey returns the largest key strictly less than key, from the
+// sorted copy
+func (s *SyncMapSort) PredecessorKey(key uint32) (uint32, bool) {
+	keys := *s.keys()
+	i, _ := slices.BinarySearch(keys, key)
+	if i == 0 {
+		return 0, false
+	}
+	return keys[i-1], true
+}
+
+// keys returns the sorted copy, rebuilding it if an update made it stale
+func (s *SyncMapSort) keys() *[]uint32 {
+	if !s.dirty.Load() {
+		return s.sorted.Load()
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty.Swap(false) {
+		var keys []uint32
+		s.m.Range(func(k, _ any) bool {
+			keys = append(keys, k.(uint32))
+			return true
+		})
+		slices.Sort(keys)
+		s.sorted.Store(&keys)
+	}
+	return s.sorted.Load()
+}
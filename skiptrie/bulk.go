@@ -0,0 +1,243 @@
+package skiptrie
+
+import (
+	"math"
+	"sort"
+)
+
+// DeleteRange deletes all keys in the closed range [lo, hi] and returns how
+// many were deleted
+//
+// Nodes are marked in a single pass over the bottom level and then unlinked
+// by one sweep per level starting from the range's predecessor, instead of
+// a separate search per key
+func (st *SkipTrie) DeleteRange(lo, hi uint32) int {
+	defer st.unpin(st.pin())
+	
+	if lo > hi {
+		return 0
+	}
+	
+	var victims []*Node
+	st.ascend(lo, hi, func(node *Node) bool {
+		if st.markNode(node) {
+			victims = append(victims, node)
+		}
+		return true
+	})
+	if len(victims) == 0 {
+		return 0
+	}
+	
+	// listSearch unlinks every marked node it steps over, so searching for
+	// hi from the left edge of the range clears the whole range per level;
+	// at the top level each unlink also points the prev of the node after
+	// the range back at left
+	start := st.head
+	for level := st.levels - 1; level >= 0; level-- {
+		left, _ := st.listSearch(lo, start, level)
+		st.listSearch(hi, left, level)
+		start = left
+	}
+	
+	for _, node := range victims {
+		st.retire(node)
+	}
+	return len(victims)
+}
+
+// DeleteAll deletes every key, leaving the instance empty and ready for
+// reuse
+//
+// Unlike Pool.Put it may run concurrently with other operations, but it
+// is not atomic: it is DeleteRange over the whole key space, taking O(n)
+// steps, so readers may see the set half deleted, and keys inserted while
+// it runs may survive it. Nodes are marked in one pass and unlinked by one
+// sweep per level, and each is then retired as a delete: watchers and the
+// write-ahead log see it, and with WithNodeReuse it goes back to the
+// allocator once no reader holds it. Iterators stop with ErrInvalidated
+func (st *SkipTrie) DeleteAll() {
+	st.gen.Add(1)
+	st.DeleteRange(0, math.MaxUint32)
+}
+
+// Merge moves every key of other into st and returns how many st did not
+// already hold; keys held by both keep st's value, and other is left empty
+//
+// One merge walk reads other in key order, marks each node there and
+// inserts its key into st from a finger, as InsertBatch does, carrying
+// its value, flags, priority and expiry; the marked nodes are then
+// unlinked from other by one sweep per level, as by DeleteRange. Both
+// sides stay usable throughout: a key is briefly in neither, and keys
+// inserted into other during the walk may stay there
+func (st *SkipTrie) Merge(other *SkipTrie) int {
+	if other == st {
+		return 0
+	}
+	defer st.unpin(st.pin())
+	defer other.unpin(other.pin())
+	
+	other.gen.Add(1)
+	
+	var fing finger
+	var victims []*Node
+	added := 0
+	other.ascend(0, math.MaxUint32, func(node *Node) bool {
+		// Claim the node first, so a concurrent delete from other either
+		// wins and the key stays deleted, or loses and the key moves
+		if !other.markNode(node) {
+			return true
+		}
+		victims = append(victims, node)
+		
+		_, inserted := st.insertNodeFrom(node.key, func(dup *Node) {
+			dup.value.Store(released(node.value.Load()))
+			dup.flags.Store(node.flags.Load())
+			dup.priority = node.priority
+			dup.expires.Store(node.expires.Load())
+		}, &fing)
+		if inserted {
+			added++
+		}
+		return true
+	})
+	if len(victims) == 0 {
+		return added
+	}
+	
+	lo, hi := victims[0].key, victims[len(victims)-1].key
+	start := other.head
+	for level := other.levels - 1; level >= 0; level-- {
+		left, _ := other.listSearch(lo, start, level)
+		other.listSearch(hi, left, level)
+		start = left
+	}
+	for _, node := range victims {
+		other.retire(node)
+	}
+	return added
+}
+
+// finger remembers a start node per level for searches of ascending keys
+type finger [MaxHeight]*Node
+
+// start returns the node a level search for key should begin from
+func (f *finger) start(st *SkipTrie, level int, key uint32) *Node {
+	node := f[level]
+	if node == nil || node.marked.Load() || node.key >= key {
+		return st.head
+	}
+	return node
+}
+
+// maxFingerSteps bounds how far a batch walks the bottom level from the
+// previous key before falling back to a fresh trie-accelerated search
+const maxFingerSteps = 32
+
+// seek returns the live node holding key, walking forward from pos (a node
+// with a smaller key) and updating it
+func (st *SkipTrie) seek(pos **Node, key uint32) *Node {
+	curr := *pos
+	if curr == nil || curr.marked.Load() || curr.key >= key {
+		curr = st.head
+	}
+	
+	for steps := 0; ; steps++ {
+		next := curr.next[0].Load()
+		if next == nil || next == st.tail || next.key > key {
+			*pos = curr
+			return nil
+		}
+		if next.key == key {
+			*pos = curr
+			if next.marked.Load() {
+				return nil
+			}
+			return next
+		}
+		if steps == maxFingerSteps {
+			node := st.findNode(key)
+			*pos = node
+			return node
+		}
+		curr = next
+	}
+}
+
+// batchOrder returns the indexes of keys in ascending key order, ties in
+// input order
+func batchOrder(keys []uint32) []int {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return keys[order[a]] < keys[order[b]]
+	})
+	return order
+}
+
+// InsertBatch inserts keys and reports, in input order, which were newly
+// inserted
+// Keys are processed in sorted order so each search starts from the
+// position of the previous key rather than from head
+func (st *SkipTrie) InsertBatch(keys []uint32) []bool {
+	defer st.unpin(st.pin())
+	
+	results := make([]bool, len(keys))
+	
+	var fing finger
+	for _, i := range batchOrder(keys) {
+		_, results[i] = st.insertNodeFrom(keys[i], nil, &fing)
+	}
+	return results
+}
+
+// ContainsBatch reports, in input order, which keys are present
+func (st *SkipTrie) ContainsBatch(keys []uint32) []bool {
+	defer st.unpin(st.pin())
+	
+	results := make([]bool, len(keys))
+	
+	var pos *Node
+	for _, i := range batchOrder(keys) {
+		results[i] = st.seek(&pos, keys[i]) != nil
+	}
+	return results
+}
+
+// DeleteBatch deletes keys and reports, in input order, which were deleted
+//
+// All nodes are marked first and then unlinked by one ascending sweep per
+// level, so the batch shares its searches instead of repeating them per key
+func (st *SkipTrie) DeleteBatch(keys []uint32) []bool {
+	defer st.unpin(st.pin())
+	
+	results := make([]bool, len(keys))
+	
+	var pos *Node
+	var victims []*Node
+	for _, i := range batchOrder(keys) {
+		node := st.seek(&pos, keys[i])
+		if node != nil && st.markNode(node) {
+			victims = append(victims, node)
+			results[i] = true
+		}
+	}
+	
+	// Each search unlinks the victims it steps over; at the top level every
+	// unlink also points the prev of the victim's successor back at left
+	for level := st.levels - 1; level >= 0; level-- {
+		left := st.head
+		for _, node := range victims {
+			if node.origHeight > level {
+				left, _ = st.listSearch(node.key, left, level)
+			}
+		}
+	}
+	
+	for _, node := range victims {
+		st.retire(node)
+	}
+	return results
+}
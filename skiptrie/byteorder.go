@@ -0,0 +1,80 @@
+package skiptrie
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrKeyLength is returned by the byte-order helpers for a slice that is
+// not exactly four bytes long
+var ErrKeyLength = errors.New("skiptrie: key slice is not 4 bytes long")
+
+// keyFrom decodes a 4-byte key in the given byte order
+func keyFrom(b []byte, order binary.ByteOrder) (uint32, error) {
+	if len(b) != 4 {
+		return 0, ErrKeyLength
+	}
+	return order.Uint32(b), nil
+}
+
+// insertFrom inserts the key held in b in the given byte order, rejecting
+// the reserved 0xFFFFFFFF with ErrReservedKey as TryInsert does
+func (st *SkipTrie) insertFrom(b []byte, order binary.ByteOrder) (bool, error) {
+	key, err := keyFrom(b, order)
+	if err != nil {
+		return false, err
+	}
+	if key == math.MaxUint32 {
+		return false, ErrReservedKey
+	}
+	return st.Insert(key), nil
+}
+
+// InsertBE inserts the key held in b in big-endian (network) byte order,
+// as found in packet headers; the all-ones key is reserved (ErrReservedKey)
+func (st *SkipTrie) InsertBE(b []byte) (bool, error) {
+	return st.insertFrom(b, binary.BigEndian)
+}
+
+// ContainsBE checks if the big-endian key held in b exists
+func (st *SkipTrie) ContainsBE(b []byte) (bool, error) {
+	key, err := keyFrom(b, binary.BigEndian)
+	if err != nil {
+		return false, err
+	}
+	return st.Contains(key), nil
+}
+
+// DeleteBE deletes the big-endian key held in b
+func (st *SkipTrie) DeleteBE(b []byte) (bool, error) {
+	key, err := keyFrom(b, binary.BigEndian)
+	if err != nil {
+		return false, err
+	}
+	return st.Delete(key), nil
+}
+
+// InsertLE inserts the key held in b in little-endian byte order; the
+// all-ones key is reserved (ErrReservedKey)
+func (st *SkipTrie) InsertLE(b []byte) (bool, error) {
+	return st.insertFrom(b, binary.LittleEndian)
+}
+
+// ContainsLE checks if the little-endian key held in b exists
+func (st *SkipTrie) ContainsLE(b []byte) (bool, error) {
+	key, err := keyFrom(b, binary.LittleEndian)
+	if err != nil {
+		return false, err
+	}
+	return st.Contains(key), nil
+}
+
+// DeleteLE deletes the little-endian key held in b
+func (st *SkipTrie) DeleteLE(b []byte) (bool, error) {
+	key, err := keyFrom(b, binary.LittleEndian)
+	if err != nil {
+		return false, err
+	}
+	return st.Delete(key), nil
+}
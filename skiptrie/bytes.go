@@ -0,0 +1,135 @@
+package skiptrie
+
+import "errors"
+
+// MaxBytesLen is the longest byte string BytesKey can encode
+// Strings of up to 3 bytes number 16843009, which fits the key universe;
+// strings of up to 4 bytes would not, so longer keys need a hash or a
+// fixed-length big-endian encoding instead
+const MaxBytesLen = 3
+
+// ErrKeyTooLong is returned for byte strings longer than MaxBytesLen
+var ErrKeyTooLong = errors.New("skiptrie: key longer than MaxBytesLen bytes")
+
+// bytesBelow[m] is the number of byte strings of at most m bytes
+var bytesBelow = [MaxBytesLen + 1]uint32{1, 257, 65793, 16843009}
+
+// BytesKey maps a byte string of at most MaxBytesLen bytes to its rank in
+// lexicographic order among all such strings, so that comparing keys
+// compares the strings and every string has its own key
+func BytesKey[K ~string | ~[]byte](b K) (uint32, error) {
+	if len(b) > MaxBytesLen {
+		return 0, ErrKeyTooLong
+	}
+	
+	var key uint32
+	for i := 0; i < len(b); i++ {
+		// Skip the strings starting with a smaller byte here, and b's own
+		// prefix of length i
+		key += uint32(b[i])*bytesBelow[MaxBytesLen-1-i] + 1
+	}
+	return key, nil
+}
+
+// KeyBytes is the inverse of BytesKey
+func KeyBytes(key uint32) []byte {
+	var b []byte
+	for i := 0; i < MaxBytesLen && key > 0; i++ {
+		key--
+		below := bytesBelow[MaxBytesLen-1-i]
+		b = append(b, byte(key/below))
+		key %= below
+	}
+	return b
+}
+
+// BytesSet is an ordered set of short byte strings or strings, stored in a
+// SkipTrie under their BytesKey
+type BytesSet[K ~string | ~[]byte] struct {
+	st *SkipTrie
+}
+
+// NewBytesSet creates a new BytesSet instance
+func NewBytesSet[K ~string | ~[]byte](opts ...Option) *BytesSet[K] {
+	return &BytesSet[K]{st: NewSkipTrie(opts...)}
+}
+
+// Insert adds b to the set, reporting whether it was absent
+func (s *BytesSet[K]) Insert(b K) (bool, error) {
+	key, err := BytesKey(b)
+	if err != nil {
+		return false, err
+	}
+	return s.st.Insert(key), nil
+}
+
+// Delete removes b from the set, reporting whether it was present
+func (s *BytesSet[K]) Delete(b K) bool {
+	key, err := BytesKey(b)
+	return err == nil && s.st.Delete(key)
+}
+
+// Contains checks if b is in the set
+func (s *BytesSet[K]) Contains(b K) bool {
+	key, err := BytesKey(b)
+	return err == nil && s.st.Contains(key)
+}
+
+// Len returns the number of strings in the set
+func (s *BytesSet[K]) Len() int {
+	return s.st.Len()
+}
+
+// Floor returns the largest string in the set less than or equal to b
+// Strings longer than MaxBytesLen are compared by their prefix, which
+// sorts just before them
+func (s *BytesSet[K]) Floor(b K) (K, bool) {
+	if len(b) > MaxBytesLen {
+		b = b[:MaxBytesLen]
+	}
+	key, _ := BytesKey(b)
+	return s.result(s.st.Floor(key))
+}
+
+// Ceiling returns the smallest string in the set greater than or equal to
+// b
+func (s *BytesSet[K]) Ceiling(b K) (K, bool) {
+	if len(b) <= MaxBytesLen {
+		key, _ := BytesKey(b)
+		return s.result(s.st.Ceiling(key))
+	}
+	
+	// Every string starting with the long string's prefix sorts before
+	// it, so the answer is the successor of the prefix
+	key, _ := BytesKey(b[:MaxBytesLen])
+	return s.result(s.st.SuccessorKey(key))
+}
+
+// Ascend calls fn for each string in [lo, hi] in ascending order until fn
+// returns false
+func (s *BytesSet[K]) Ascend(lo, hi K, fn func(b K) bool) {
+	var from uint32
+	if len(lo) > MaxBytesLen {
+		// The prefix sorts before lo, so start just after it
+		from, _ = BytesKey(lo[:MaxBytesLen])
+		from++
+	} else {
+		from, _ = BytesKey(lo)
+	}
+	if len(hi) > MaxBytesLen {
+		hi = hi[:MaxBytesLen]
+	}
+	to, _ := BytesKey(hi)
+	s.st.ascend(from, to, func(node *Node) bool {
+		return fn(K(KeyBytes(node.key)))
+	})
+}
+
+// result converts a key query result back to a string
+func (s *BytesSet[K]) result(key uint32, ok bool) (K, bool) {
+	if !ok {
+		var zero K
+		return zero, false
+	}
+	return K(KeyBytes(key)), true
+}
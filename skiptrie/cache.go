@@ -0,0 +1,135 @@
+package skiptrie
+
+import (
+	"math"
+	"time"
+)
+
+// Cache is an expirable cache over uint32 keys with the usual
+// Get/Set/SetWithTTL/Delete contract, built on a SkipTrieMap so it also
+// answers ordered queries such as Floor and Ceiling
+//
+// Expiry is the nodes' own, as set by InsertWithTTL: expired entries are
+// invisible to reads at once and removed by the first lookup that finds
+// them or by the WithExpirySweep sweep; Len counts them until then.
+// Passing WithCapacity bounds the cache by evicting the smallest keys
+type Cache[V any] struct {
+	m   *SkipTrieMap[V]
+	ttl time.Duration
+}
+
+// NewCache creates a cache whose entries live for ttl by default (forever
+// if ttl <= 0), sweeping expired entries every sweep (never if sweep <= 0)
+// Close stops the sweep
+func NewCache[V any](ttl, sweep time.Duration, opts ...Option) *Cache[V] {
+	if sweep > 0 {
+		opts = append(opts, WithExpirySweep(sweep))
+	}
+	return &Cache[V]{m: NewSkipTrieMap[V](opts...), ttl: ttl}
+}
+
+// Get returns the value cached for key if it is present and unexpired
+func (c *Cache[V]) Get(key uint32) (V, bool) {
+	return c.m.Get(key)
+}
+
+// Set caches value for key with the default TTL
+func (c *Cache[V]) Set(key uint32, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL caches value for key for ttl (forever if ttl <= 0), replacing
+// any previous value
+// A live entry is updated in place, so concurrent reads of the key see
+// either the old value or the new one; only an expired entry, or one being
+// deleted, is replaced by inserting the key afresh
+func (c *Cache[V]) SetWithTTL(key uint32, value V, ttl time.Duration) {
+	st := c.m.st
+	defer st.unpin(st.pin())
+	
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+	boxed := any(value)
+	init := func(node *Node) {
+		node.value.Store(&boxed)
+		node.expires.Store(expires)
+	}
+	
+	for {
+		node, inserted := st.insertNode(key, init)
+		if inserted {
+			return
+		}
+		
+		old := node.value.Load()
+		if !node.marked.Load() && !claimed(old) && !node.expired(time.Now().UnixNano()) {
+			// The expiry goes first, so the new value is never read under
+			// the old expiry; a racing SetWithTTL that stores its expiry
+			// after this one also swaps its value after this one
+			node.expires.Store(expires)
+			if node.value.CompareAndSwap(old, &boxed) && !node.marked.Load() {
+				st.publishUpdate(node)
+				return
+			}
+			// Lost a race, or a lookup that saw the old expiry pass is
+			// deleting the node
+			continue
+		}
+		// Expired, claimed or being removed; delete it and insert afresh
+		st.deleteNode(node)
+	}
+}
+
+// Delete removes key from the cache, reporting whether it was present
+func (c *Cache[V]) Delete(key uint32) bool {
+	return c.m.Delete(key)
+}
+
+// Floor returns the largest unexpired key less than or equal to key and
+// its value
+func (c *Cache[V]) Floor(key uint32) (uint32, V, bool) {
+	st := c.m.st
+	defer st.unpin(st.pin())
+	
+	now := time.Now().UnixNano()
+	for node := st.floorNode(key); node != nil; node = st.prevNode(node) {
+		if !node.expired(now) {
+			return node.key, valueOf[V](node), true
+		}
+	}
+	
+	var zero V
+	return 0, zero, false
+}
+
+// Ceiling returns the smallest unexpired key greater than or equal to key
+// and its value
+func (c *Cache[V]) Ceiling(key uint32) (uint32, V, bool) {
+	var (
+		found uint32
+		value V
+		ok    bool
+	)
+	now := time.Now().UnixNano()
+	c.m.st.ascend(key, math.MaxUint32, func(node *Node) bool {
+		if node.expired(now) {
+			return true
+		}
+		found, value, ok = node.key, valueOf[V](node), true
+		return false
+	})
+	return found, value, ok
+}
+
+// Len returns the number of entries, counting expired entries not removed
+// yet
+func (c *Cache[V]) Len() int {
+	return c.m.st.Len()
+}
+
+// Close stops the background sweep
+func (c *Cache[V]) Close() {
+	c.m.st.Close()
+}
@@ -0,0 +1,58 @@
+package skiptrie
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCacheSetInPlace overwrites an expiring entry while readers look it
+// up: the entry is live throughout, so no read may miss it
+func TestCacheSetInPlace(t *testing.T) {
+	c := NewCache[int](time.Hour, 0)
+	defer c.Close()
+	c.Set(1, 0)
+	
+	var (
+		stop   atomic.Bool
+		misses atomic.Int64
+		wg     sync.WaitGroup
+	)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if _, ok := c.Get(1); !ok {
+					misses.Add(1)
+				}
+			}
+		}()
+	}
+	for i := range 20000 {
+		c.SetWithTTL(1, i, time.Duration(1+i%2)*time.Hour)
+	}
+	stop.Store(true)
+	wg.Wait()
+	
+	if n := misses.Load(); n != 0 {
+		t.Fatalf("Get(1) missed a live entry %d times", n)
+	}
+	if v, ok := c.Get(1); !ok || v != 19999 {
+		t.Fatalf("Get(1) = %v, %v, want 19999, true", v, ok)
+	}
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+	
+	c.SetWithTTL(1, -1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Fatal("Get(1) found an expired entry")
+	}
+	c.Set(1, 2)
+	if v, ok := c.Get(1); !ok || v != 2 {
+		t.Fatalf("Get(1) after expiry = %v, %v, want 2, true", v, ok)
+	}
+}
@@ -0,0 +1,97 @@
+package skiptrie
+
+import "math"
+
+// Priority is the eviction class of a key in capacity-bounded mode
+// Keys of lower priority are evicted first; plain Insert uses priority 0
+type Priority uint8
+
+// NumPriorities is the number of priority classes
+const NumPriorities = 4
+
+// WithCapacity bounds the number of keys; once an insert exceeds the bound,
+// keys are evicted from the lowest non-empty priority class, smallest key
+// first. The bound is hard: if only higher classes remain they are evicted
+// too, but the key just inserted never is
+func WithCapacity(n int) Option {
+	return func(st *SkipTrie) {
+		st.capacity = n
+	}
+}
+
+// InsertWithPriority inserts a key with the given eviction class
+// Priorities at or above NumPriorities are clamped to the highest class
+func (st *SkipTrie) InsertWithPriority(key uint32, priority Priority) bool {
+	if priority >= NumPriorities {
+		priority = NumPriorities - 1
+	}
+	
+	_, inserted := st.insertNode(key, func(node *Node) {
+		node.priority = priority
+	})
+	return inserted
+}
+
+// evict deletes keys until the capacity bound holds again, sparing keep
+func (st *SkipTrie) evict(keep *Node) {
+	for st.size.Load() > int64(st.capacity) {
+		victim := st.evictionVictim(keep)
+		if victim == nil {
+			return
+		}
+		if st.deleteNode(victim) {
+			st.evictions[victim.priority].Add(1)
+		}
+	}
+}
+
+// evictionVictim returns the smallest live key of the lowest populated
+// priority class other than keep
+// The walk starts from the class's lower bound in classFrom rather than the
+// head, and moves the bound up to the victim, so repeated evictions of a
+// class do not walk past the keys already evicted
+func (st *SkipTrie) evictionVictim(keep *Node) *Node {
+	for class := Priority(0); class < NumPriorities; class++ {
+		n := st.classCount[class].Load()
+		if keep != nil && keep.priority == class {
+			n-- // keep alone would send the walk to the end of the list
+		}
+		if n <= 0 {
+			continue
+		}
+		
+		from := st.classFrom[class].Load()
+		var victim *Node
+		st.ascend(from, math.MaxUint32, func(node *Node) bool {
+			if node.priority == class && node != keep {
+				victim = node
+				return false
+			}
+			return true
+		})
+		if victim == nil {
+			continue
+		}
+		
+		// keep stays in the class, so the bound must not pass it; a failed
+		// CAS means an insert lowered the bound, which must stand
+		bound := victim.key
+		if keep != nil && keep.priority == class {
+			bound = min(bound, keep.key)
+		}
+		st.classFrom[class].CompareAndSwap(from, bound)
+		return victim
+	}
+	return nil
+}
+
+// lowerClassFrom lowers the bound of node's class to node's key once node
+// is linked, so that evictionVictim finds it
+func (st *SkipTrie) lowerClassFrom(node *Node) {
+	bound := &st.classFrom[node.priority]
+	for from := bound.Load(); node.key < from; from = bound.Load() {
+		if bound.CompareAndSwap(from, node.key) {
+			return
+		}
+	}
+}
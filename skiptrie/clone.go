@@ -0,0 +1,64 @@
+package skiptrie
+
+import "math"
+
+// Clone returns an independent copy of st, configured as st is and then by
+// opts, holding its keys together with their values, flags, priorities and
+// expiries
+//
+// The copy is built in one scan of the bottom level, inserting in key order
+// from a finger as InsertBatch does; writers of st are never blocked, and
+// keys they change during the scan may or may not be copied. Changelog,
+// write-ahead log, watchers and alarms are not carried over, and tower
+// heights are drawn afresh
+func (st *SkipTrie) Clone(opts ...Option) *SkipTrie {
+	config := func(out *SkipTrie) {
+		out.levels = st.levels
+		out.keyBits, out.loglog = st.keyBits, st.loglog
+		out.reverseLinks = st.reverseLinks
+		out.help = st.help
+		out.heightFn = st.heightFn
+		out.bucketSize = st.bucketSize
+		out.prefixes.secret = st.prefixes.secret
+		out.capacity = st.capacity
+		out.sweepEvery = st.sweepEvery
+		out.alloc = st.alloc
+		out.analysis = st.analysis
+		out.fallbackFn = st.fallbackFn
+		if st.ops != nil {
+			out.ops = &opCounters{}
+		}
+		if st.reclaim != nil {
+			out.reclaim = newReclaimer()
+		}
+		if st.order != nil {
+			out.order = &orderStats{}
+		}
+		if st.repair != nil {
+			WithLazyTrieRepair()(out)
+		}
+	}
+	out := NewSkipTrie(append([]Option{config}, opts...)...)
+	
+	var fing finger
+	st.ascend(0, math.MaxUint32, func(node *Node) bool {
+		out.insertNodeFrom(node.key, func(dup *Node) {
+			dup.value.Store(released(node.value.Load()))
+			dup.flags.Store(node.flags.Load())
+			dup.priority = node.priority
+			dup.expires.Store(node.expires.Load())
+		}, &fing)
+		return true
+	})
+	return out
+}
+
+// Clone returns an independent copy of the map; values themselves are
+// copied as by assignment
+func (m *SkipTrieMap[V]) Clone(opts ...Option) *SkipTrieMap[V] {
+	c := &SkipTrieMap[V]{st: m.st.Clone(opts...)}
+	if m.codec != nil {
+		c.SetCodec(m.codec)
+	}
+	return c
+}
@@ -0,0 +1,138 @@
+package skiptrie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrNoCodec is returned when values must be encoded for a SkipTrieMap
+// that has no codec registered
+var ErrNoCodec = errors.New("skiptrie: no value codec registered")
+
+// Codec encodes values of type V to bytes and back, so that snapshots, the
+// write-ahead log and network front ends can carry SkipTrieMap values
+type Codec[V any] interface {
+	// Append appends the encoding of v to dst and returns the result
+	Append(dst []byte, v V) ([]byte, error)
+	// Decode decodes a value encoded by Append
+	Decode(data []byte) (V, error)
+}
+
+// FixedCodec encodes fixed-size values (numbers, booleans, and arrays and
+// structs of them) big-endian with encoding/binary
+type FixedCodec[V any] struct{}
+
+// Append implements Codec
+func (FixedCodec[V]) Append(dst []byte, v V) ([]byte, error) {
+	return binary.Append(dst, binary.BigEndian, v)
+}
+
+// Decode implements Codec
+func (FixedCodec[V]) Decode(data []byte) (V, error) {
+	var v V
+	n, err := binary.Decode(data, binary.BigEndian, &v)
+	if err == nil && n != len(data) {
+		err = fmt.Errorf("skiptrie: %d trailing bytes after fixed-size value", len(data)-n)
+	}
+	return v, err
+}
+
+// GobCodec encodes values with encoding/gob; each value carries its own
+// type description, so the encoding is self-contained but larger than a
+// shared stream's
+type GobCodec[V any] struct{}
+
+// Append implements Codec
+func (GobCodec[V]) Append(dst []byte, v V) ([]byte, error) {
+	b := bytes.NewBuffer(dst)
+	err := gob.NewEncoder(b).Encode(&v)
+	return b.Bytes(), err
+}
+
+// Decode implements Codec
+func (GobCodec[V]) Decode(data []byte) (V, error) {
+	var v V
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec[V any] struct{}
+
+// Append implements Codec
+func (JSONCodec[V]) Append(dst []byte, v V) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return append(dst, data...), err
+}
+
+// Decode implements Codec
+func (JSONCodec[V]) Decode(data []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// SetCodec registers the codec that encodes values for Checkpoint and for
+// the write-ahead log, whose insert and update records then carry the
+// value; call it before the map is shared
+func (m *SkipTrieMap[V]) SetCodec(c Codec[V]) {
+	m.codec = c
+	l := &m.st.changes
+	l.mu.Lock()
+	l.encode = func(node *Node) ([]byte, error) {
+		return c.Append(nil, valueOf[V](node))
+	}
+	l.mu.Unlock()
+}
+
+// Codec returns the registered codec, or nil
+func (m *SkipTrieMap[V]) Codec() Codec[V] {
+	return m.codec
+}
+
+// Checkpoint writes a snapshot of the keys and their values, encoded with
+// the registered codec, to w; Recover and RecoverMap read it back as for
+// SkipTrie.Checkpoint
+func (m *SkipTrieMap[V]) Checkpoint(w io.Writer) error {
+	if m.codec == nil {
+		return ErrNoCodec
+	}
+	
+	var buf []byte
+	return writeSnapshot(w, m.st.Seq(), true, func(fn func(uint32, []byte)) error {
+		var err error
+		m.st.ascend(0, math.MaxUint32, func(node *Node) bool {
+			buf, err = m.codec.Append(buf[:0], valueOf[V](node))
+			if err != nil {
+				err = fmt.Errorf("skiptrie: encoding value of key %d: %w", node.key, err)
+				return false
+			}
+			fn(node.key, buf)
+			return true
+		})
+		return err
+	})
+}
+
+// RecoverMap rebuilds a SkipTrieMap from a snapshot written by Checkpoint
+// and the log written by WithWAL, as Recover does for a SkipTrie, decoding
+// values with c, which is registered on the new map
+// Keys recovered from records without a value get the zero value
+func RecoverMap[V any](snapshot, wal io.Reader, c Codec[V], opts ...Option) (*SkipTrieMap[V], error) {
+	m := NewSkipTrieMap[V](opts...)
+	m.SetCodec(c)
+	dec := func(data []byte) (any, error) {
+		v, err := c.Decode(data)
+		return v, err
+	}
+	if err := m.st.recover(snapshot, wal, &SnapshotInfo{}, dec); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,81 @@
+package skiptrie
+
+// deleting wraps the value box of a map node DeleteIf has claimed, so that
+// Update stops replacing the value it checked while the node is removed
+// Readers see the wrapped value until the node is marked
+type deleting struct {
+	boxed *any
+}
+
+// claimed checks if a value box is a DeleteIf claim
+func claimed(boxed *any) bool {
+	if boxed == nil {
+		return false
+	}
+	_, ok := (*boxed).(deleting)
+	return ok
+}
+
+// released returns the value box under a DeleteIf claim, for copying the
+// value to another node
+func released(boxed *any) *any {
+	if claimed(boxed) {
+		return (*boxed).(deleting).boxed
+	}
+	return boxed
+}
+
+// DeleteIf deletes key if pred accepts it, reporting whether it did
+// The node pred was asked about is the one deleted; if a concurrent delete
+// removes it first, DeleteIf reports false without calling pred again
+func (st *SkipTrie) DeleteIf(key uint32, pred func(key uint32) bool) bool {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil || !pred(key) {
+		return false
+	}
+	return st.deleteNode(node)
+}
+
+// DeleteIf deletes key if pred accepts it and its current value, reporting
+// whether it did
+//
+// The check and the removal are atomic with respect to other changes to
+// key: the value is claimed with a CAS before the node is marked, so an
+// Update either lands before the claim, and pred is asked again about the
+// new value, or finds the claim and sees the key deleted. pred may run
+// more than once and should have no side effects
+func (m *SkipTrieMap[V]) DeleteIf(key uint32, pred func(key uint32, value V) bool) bool {
+	defer m.st.unpin(m.st.pin())
+	
+	for {
+		node := m.st.findNode(key)
+		if node == nil {
+			return false
+		}
+		
+		old := node.value.Load()
+		if claimed(old) {
+			// Another DeleteIf got there first; help it and look again
+			m.st.deleteNode(node)
+			continue
+		}
+		if !pred(key, unbox[V](old)) {
+			return false
+		}
+		claim := any(deleting{old})
+		if node.value.CompareAndSwap(old, &claim) {
+			// A plain Delete may still mark the node first
+			return m.st.deleteNode(node)
+		}
+	}
+}
+
+// DeleteIfValue deletes key from m if it holds expected, reporting whether
+// it did, atomically as for SkipTrieMap.DeleteIf
+func DeleteIfValue[V comparable](m *SkipTrieMap[V], key uint32, expected V) bool {
+	return m.DeleteIf(key, func(_ uint32, value V) bool {
+		return value == expected
+	})
+}
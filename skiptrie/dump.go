@@ -0,0 +1,118 @@
+package skiptrie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Dump writes the skiplist levels, top first, and the x-fast trie prefix
+// table in a readable form
+// Deleted nodes still linked are suffixed with "x" and indexed nodes with
+// "^"; the trie lists each entry as its prefix bits and the keys of its
+// two pointers. The output reflects one pass over the structure and is
+// only exact when nothing runs concurrently
+func (st *SkipTrie) Dump(w io.Writer) error {
+	defer st.unpin(st.pin())
+	
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "skiptrie: %d keys, %d levels\n", st.Len(), st.levels)
+	for level := st.levels - 1; level >= 0; level-- {
+		fmt.Fprintf(bw, "level %d:", level)
+		for curr := st.head.next[level].Load(); curr != nil && curr != st.tail; curr = curr.next[level].Load() {
+			fmt.Fprintf(bw, " %d%s", curr.key, nodeMarks(curr))
+		}
+		bw.WriteString("\n")
+	}
+	
+	entries := st.prefixEntries()
+	fmt.Fprintf(bw, "trie: %d prefixes\n", len(entries))
+	for _, e := range entries {
+		fmt.Fprintf(bw, "  %v\n", e)
+	}
+	return bw.Flush()
+}
+
+// WriteDOT writes the structure as a Graphviz digraph: one row per level
+// with the next pointers as edges, tower levels of the same node stacked,
+// and trie entries as boxes with dashed edges to the nodes they point at
+// Render it with, for example, dot -Tsvg
+func (st *SkipTrie) WriteDOT(w io.Writer) error {
+	defer st.unpin(st.pin())
+	
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph skiptrie {\n\trankdir=LR;\n\tnode [shape=record, fontname=monospace];\n")
+	
+	name := func(node *Node, level int) string {
+		switch node {
+		case st.head:
+			return fmt.Sprintf("head_%d", level)
+		case st.tail:
+			return fmt.Sprintf("tail_%d", level)
+		}
+		return fmt.Sprintf("n%d_%d", node.key, level)
+	}
+	for level := st.levels - 1; level >= 0; level-- {
+		fmt.Fprintf(bw, "\tsubgraph level%d {\n\t\trank=same;\n", level)
+		fmt.Fprintf(bw, "\t\t%s [label=\"head|%d\"];\n", name(st.head, level), level)
+		prev := st.head
+		for curr := st.head.next[level].Load(); curr != nil; curr = curr.next[level].Load() {
+			if curr != st.tail {
+				fmt.Fprintf(bw, "\t\t%s [label=\"%d%s\"];\n", name(curr, level), curr.key, nodeMarks(curr))
+			} else {
+				fmt.Fprintf(bw, "\t\t%s [label=\"tail|%d\"];\n", name(st.tail, level), level)
+			}
+			fmt.Fprintf(bw, "\t\t%s -> %s;\n", name(prev, level), name(curr, level))
+			if curr == st.tail {
+				break
+			}
+			prev = curr
+		}
+		bw.WriteString("\t}\n")
+		if level > 0 {
+			fmt.Fprintf(bw, "\t%s -> %s [style=invis];\n", name(st.head, level), name(st.head, level-1))
+		}
+	}
+	
+	top := st.loglog - 1
+	for i, e := range st.prefixEntries() {
+		fmt.Fprintf(bw, "\tp%d [shape=box, label=\"%v\"];\n", i, e)
+		for _, key := range e.Pointers {
+			if key >= 0 {
+				fmt.Fprintf(bw, "\tp%d -> n%d_%d [style=dashed];\n", i, key, top)
+			}
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// prefixEntries returns the trie entries ordered by length and bits
+func (st *SkipTrie) prefixEntries() []PrefixEntry {
+	var entries []PrefixEntry
+	st.prefixes.rangeEntries(func(p prefix, _ *TreeNode) bool {
+		entries = append(entries, st.prefixEntry(p))
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Len != entries[j].Len {
+			return entries[i].Len < entries[j].Len
+		}
+		return entries[i].Bits < entries[j].Bits
+	})
+	return entries
+}
+
+// nodeMarks returns the Dump suffix of node: "x" if deleted, "^" if
+// indexed
+func nodeMarks(node *Node) string {
+	marks := ""
+	if node.marked.Load() {
+		marks += "x"
+	}
+	if node.indexed {
+		marks += "^"
+	}
+	return marks
+}
@@ -0,0 +1,47 @@
+package skiptrie
+
+import (
+	"errors"
+	"math"
+)
+
+// Errors returned by the error-reporting variants of the update methods
+// ErrKeyExists and ErrNotFound are ordinary outcomes; ErrContention means
+// a concurrent operation on the same key got in the way, and the caller
+// may retry; ErrReservedKey rejects math.MaxUint32, the key of the tail
+// sentinel, which can never be stored
+var (
+	ErrKeyExists   = errors.New("skiptrie: key already exists")
+	ErrNotFound    = errors.New("skiptrie: key not found")
+	ErrContention  = errors.New("skiptrie: lost a race with a concurrent operation")
+	ErrReservedKey = errors.New("skiptrie: key is reserved for the tail sentinel")
+)
+
+// TryInsert inserts key like Insert, but reports why it did not: the bool
+// is true exactly when the error is nil
+func (st *SkipTrie) TryInsert(key uint32) (bool, error) {
+	if key == math.MaxUint32 {
+		return false, ErrReservedKey
+	}
+	if _, inserted := st.insertNode(key, nil); !inserted {
+		return false, ErrKeyExists
+	}
+	return true, nil
+}
+
+// TryDelete deletes key like Delete, but reports why it did not: the bool
+// is true exactly when the error is nil
+// ErrContention is returned when key was found but a concurrent deleter
+// removed it first
+func (st *SkipTrie) TryDelete(key uint32) (bool, error) {
+	if err := st.deleteKey(key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TryDelete deletes key and its value like Delete, with the errors of
+// SkipTrie.TryDelete
+func (m *SkipTrieMap[V]) TryDelete(key uint32) (bool, error) {
+	return m.st.TryDelete(key)
+}
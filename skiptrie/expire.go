@@ -0,0 +1,80 @@
+package skiptrie
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// expireCounters tracks the progress of ExpireWhere passes
+type expireCounters struct {
+	running atomic.Int64  // passes still in progress
+	scanned atomic.Uint64 // keys tested against a predicate
+	deleted atomic.Uint64 // keys deleted by a pass
+}
+
+// ExpireWhere starts a background pass over the keys in ascending order
+// that deletes every key for which fn returns true, at no more than
+// maxPerSecond deletions per second (unlimited if maxPerSecond <= 0)
+//
+// Keys inserted behind the pass's position are not revisited. Progress is
+// reported by Stats, and Close stops the pass early
+func (st *SkipTrie) ExpireWhere(fn func(key uint32) bool, maxPerSecond int) {
+	st.expire.running.Add(1)
+	st.goBackground(func(ctx context.Context) error {
+		defer st.expire.running.Add(-1)
+		st.expireWhere(ctx, fn, maxPerSecond)
+		return nil
+	})
+}
+
+// expireWhere runs one ExpireWhere pass
+func (st *SkipTrie) expireWhere(ctx context.Context, fn func(key uint32) bool, maxPerSecond int) {
+	var tick <-chan time.Time
+	if maxPerSecond > 0 {
+		if interval := time.Second / time.Duration(maxPerSecond); interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+	}
+	
+	for from := uint32(0); ; {
+		key, ok := st.nextExpired(ctx, from, fn)
+		if !ok {
+			return
+		}
+		
+		// Nodes are not held across the wait, which WithNodeReuse may
+		// recycle them during
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if st.Delete(key) {
+			st.expire.deleted.Add(1)
+		}
+		from = key + 1
+	}
+}
+
+// nextExpired returns the first key from from on for which fn returns
+// true, or false once the keys run out or ctx is done
+func (st *SkipTrie) nextExpired(ctx context.Context, from uint32, fn func(key uint32) bool) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	for node := st.ceilingNode(from); node != nil; node = st.ceilingNode(node.key + 1) {
+		if ctx.Err() != nil {
+			return 0, false
+		}
+		
+		st.expire.scanned.Add(1)
+		if fn(node.key) {
+			return node.key, true
+		}
+	}
+	return 0, false
+}
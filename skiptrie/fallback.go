@@ -0,0 +1,63 @@
+package skiptrie
+
+// FallbackKind names a recovery path taken when a traversal loses a race
+type FallbackKind uint8
+
+const (
+	// FallbackSearchRestart: listSearch restarted from head because its
+	// start node was deleted under it
+	FallbackSearchRestart FallbackKind = iota
+	// FallbackPrevRetry: fixPrev redid a prev repair because the left
+	// neighbour it linked was deleted meanwhile
+	FallbackPrevRetry
+	// FallbackTrieDeadEnd: the trie predecessor walk reached a deleted node
+	// with no back pointer and fell back to a search from head
+	FallbackTrieDeadEnd
+)
+
+// String returns the name of the fallback path
+func (k FallbackKind) String() string {
+	switch k {
+	case FallbackSearchRestart:
+		return "search-restart"
+	case FallbackPrevRetry:
+		return "prev-retry"
+	case FallbackTrieDeadEnd:
+		return "trie-dead-end"
+	}
+	return "unknown"
+}
+
+// FallbackEvent describes one activation of a fallback path
+type FallbackEvent struct {
+	Kind    FallbackKind // path taken
+	Key     uint32       // key being searched for or repaired
+	Level   int          // skiplist level, -1 for the trie
+	Retries int          // attempts made by the loop so far, including this one
+}
+
+// WithFallbackEvents calls fn every time an operation takes a fallback path
+// These paths are correct but slow, so a steady stream of events points at
+// heavy contention on a few keys
+// fn runs synchronously on the goroutine that took the path and must not
+// call back into the SkipTrie
+func WithFallbackEvents(fn func(FallbackEvent)) Option {
+	return func(st *SkipTrie) {
+		st.fallbackFn = fn
+	}
+}
+
+// fallback counts a fallback activation and reports it if requested
+func (st *SkipTrie) fallback(kind FallbackKind, key uint32, level, retries int) {
+	st.fallbacks.Add(1)
+	if st.fallbackFn == nil {
+		return
+	}
+	
+	st.fallbackFn(FallbackEvent{
+		Kind:    kind,
+		Key:     key,
+		Level:   level,
+		Retries: retries,
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !fences
+
+package skiptrie
+
+// Without the fences build tag the publication-point audit is empty, so
+// every call below compiles away
+
+// fencePublish runs before node is published at point
+func (st *SkipTrie) fencePublish(point string, node *Node) {}
+
+// fenceLinked runs after node is linked at level
+func (st *SkipTrie) fenceLinked(node *Node, level int) {}
+
+// fenceObserve runs on a node reached through point
+func (st *SkipTrie) fenceObserve(point string, node *Node) {}
+
+// fenceReady runs where the prev pointer of node is relied on
+func (st *SkipTrie) fenceReady(node *Node) {}
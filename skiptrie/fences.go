@@ -0,0 +1,113 @@
+//go:build fences
+
+package skiptrie
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// The fences build tag turns on a memory-ordering audit for diagnosing
+// suspected ordering bugs on weakly ordered architectures such as arm64:
+//
+//	go test -tags fences ./...
+//	go run -race -tags stress,fences ./cmd/stress
+//
+// A full fence is issued before every publication point (linking a node
+// into a level, setting a prev pointer and its ready flag, storing a trie
+// pointer) and again before every node reached through one is checked with
+// extra loads for the fields its publisher initialized first. A node seen
+// through a publication point without them panics with a *FenceViolation
+
+// fenceWord is the target of the read-modify-write used as a fence
+var fenceWord atomic.Uint64
+
+// Fences returns the number of fences issued so far (fences build tag
+// only), to confirm a binary runs the audit
+func Fences() uint64 {
+	return fenceWord.Load()
+}
+
+// fence is a full memory barrier: Go's atomic read-modify-write operations
+// are sequentially consistent, which on arm64 compiles to LDADDAL or to an
+// LDAXR/STLXR pair
+func fence() {
+	fenceWord.Add(1)
+}
+
+// FenceViolation describes a node observed through a publication point
+// before the fields written ahead of its publication were visible
+type FenceViolation struct {
+	Point   string // publication point: link, prev or trie
+	Key     uint32 // key of the node
+	Problem string
+}
+
+// Error implements error
+func (v *FenceViolation) Error() string {
+	return fmt.Sprintf("skiptrie: fences: %s of key %d: %s", v.Point, v.Key, v.Problem)
+}
+
+// fenceCheck panics with a *FenceViolation if node, reached through point,
+// is not fully initialized
+func (st *SkipTrie) fenceCheck(point string, node *Node) {
+	problem := ""
+	sentinel := node == st.head || node == st.tail
+	switch {
+	case node.origHeight < 1 || node.origHeight > st.levels:
+		problem = fmt.Sprintf("height %d not visible", node.origHeight)
+	case len(node.next) != node.origHeight:
+		problem = fmt.Sprintf("%d of %d next pointers visible", len(node.next), node.origHeight)
+	case node.origHeight >= st.loglog && node.prev == nil:
+		problem = "prev pointer not allocated"
+	case node.origHeight >= st.loglog && node.back == nil && !sentinel:
+		problem = "back pointer not allocated"
+	case st.reverseLinks && node.prevBottom == nil:
+		problem = "bottom-level hint not allocated"
+	case point == "trie" && !node.indexed:
+		problem = "indexed flag not visible"
+	}
+	if problem != "" {
+		panic(&FenceViolation{Point: point, Key: node.key, Problem: problem})
+	}
+}
+
+// fencePublish checks node and fences before it is published at point
+func (st *SkipTrie) fencePublish(point string, node *Node) {
+	st.fenceCheck(point, node)
+	fence()
+}
+
+// fenceLinked fences after node is linked at level and reloads its next
+// pointer, which was set before the link and must still be in place
+func (st *SkipTrie) fenceLinked(node *Node, level int) {
+	fence()
+	next := node.next[level].Load()
+	switch {
+	case next == nil:
+		panic(&FenceViolation{Point: "link", Key: node.key, Problem: fmt.Sprintf("level %d next pointer lost", level)})
+	case next != st.tail && next.key <= node.key:
+		panic(&FenceViolation{Point: "link", Key: node.key, Problem: fmt.Sprintf("level %d next key %d out of order", level, next.key)})
+	}
+}
+
+// fenceObserve fences and checks a node reached through point
+func (st *SkipTrie) fenceObserve(point string, node *Node) {
+	if node == nil || node.dcss != nil {
+		return
+	}
+	fence()
+	st.fenceCheck(point, node)
+}
+
+// fenceReady fences and checks that a node whose ready flag is set has its
+// prev pointer visible
+func (st *SkipTrie) fenceReady(node *Node) {
+	if node.prev == nil {
+		return
+	}
+	fence()
+	if node.ready.Load() && node.prev.Load() == nil {
+		panic(&FenceViolation{Point: "prev", Key: node.key, Problem: "ready flag visible before the prev pointer"})
+	}
+}
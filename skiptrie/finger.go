@@ -0,0 +1,103 @@
+package skiptrie
+
+// Finger remembers where its last operation ended, so that an operation on
+// a nearby larger key walks forward from there instead of searching from
+// the top. Keys further than maxFingerSteps ahead, or behind the finger,
+// fall back to an ordinary trie-accelerated search, so a Finger is never
+// much slower than the plain methods
+//
+// A Finger is not safe for concurrent use; give each goroutine its own
+type Finger struct {
+	st     *SkipTrie
+	pos    *Node  // bottom-level position of the last operation
+	levels finger // per-level insert positions
+}
+
+// NewFinger returns a Finger positioned at the start of the SkipTrie
+func (st *SkipTrie) NewFinger() *Finger {
+	return &Finger{st: st}
+}
+
+// locate returns the last live node before key at the bottom level, or
+// head, and moves the finger there
+func (f *Finger) locate(key uint32) *Node {
+	st := f.st
+	if st.reclaim != nil {
+		// The node may have been recycled since the last operation
+		f.pos = nil
+	}
+	if curr := f.pos; curr != nil && !curr.marked.Load() && curr.key < key {
+		last := curr
+		for steps := 0; steps < maxFingerSteps; steps++ {
+			next := curr.next[0].Load()
+			if next == nil || next == st.tail || next.key >= key {
+				f.pos = last
+				return last
+			}
+			curr = next
+			if !next.marked.Load() {
+				last = next
+			}
+		}
+	}
+	
+	// Too far ahead or behind: jump through the trie
+	pred := st.head
+	if key > 0 {
+		if node := st.floorNode(key - 1); node != nil {
+			pred = node
+		}
+	}
+	f.pos = pred
+	return pred
+}
+
+// Insert inserts key, starting the search from the finger
+func (f *Finger) Insert(key uint32) bool {
+	defer f.st.unpin(f.st.pin())
+	
+	if f.st.reclaim != nil {
+		f.levels = finger{}
+	}
+	pred := f.locate(key)
+	if pred != f.st.head {
+		f.levels[0] = pred
+	}
+	
+	node, inserted := f.st.insertNodeFrom(key, nil, &f.levels)
+	if inserted {
+		f.pos = node
+	}
+	return inserted
+}
+
+// Contains checks if key exists, starting the search from the finger
+func (f *Finger) Contains(key uint32) bool {
+	defer f.st.unpin(f.st.pin())
+	
+	node := f.st.nextLive(f.locate(key))
+	return node != nil && node.key == key
+}
+
+// Delete deletes key, starting the search from the finger
+func (f *Finger) Delete(key uint32) bool {
+	defer f.st.unpin(f.st.pin())
+	
+	node := f.st.nextLive(f.locate(key))
+	if node == nil || node.key != key {
+		return false
+	}
+	return f.st.deleteNode(node)
+}
+
+// PredecessorKey returns the largest key strictly less than key, starting
+// the search from the finger
+func (f *Finger) PredecessorKey(key uint32) (uint32, bool) {
+	defer f.st.unpin(f.st.pin())
+	
+	pred := f.locate(key)
+	if pred == f.st.head {
+		return 0, false
+	}
+	return pred.key, true
+}
@@ -0,0 +1,99 @@
+package skiptrie
+
+// Flags is a small user-defined bitmask attached to each key, e.g. to track
+// per-ID states such as "seen" or "processed" without a value map
+// Flags start out zero when a key is inserted
+type Flags uint16
+
+// updateFlags atomically replaces the flags of node with f(old) and returns old
+func (node *Node) updateFlags(f func(old Flags) Flags) Flags {
+	for {
+		old := node.flags.Load()
+		if node.flags.CompareAndSwap(old, uint32(f(Flags(old)))) {
+			return Flags(old)
+		}
+	}
+}
+
+// GetFlags returns the flags attached to key
+func (st *SkipTrie) GetFlags(key uint32) (Flags, bool) {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil {
+		return 0, false
+	}
+	return Flags(node.flags.Load()), true
+}
+
+// SetFlags sets the bits of mask on key and returns the previous flags
+func (st *SkipTrie) SetFlags(key uint32, mask Flags) (old Flags, ok bool) {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil {
+		return 0, false
+	}
+	return node.updateFlags(func(old Flags) Flags { return old | mask }), true
+}
+
+// ClearFlags clears the bits of mask on key and returns the previous flags
+func (st *SkipTrie) ClearFlags(key uint32, mask Flags) (old Flags, ok bool) {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil {
+		return 0, false
+	}
+	return node.updateFlags(func(old Flags) Flags { return old &^ mask }), true
+}
+
+// TestAndSetFlags sets the bits of mask on key only if none of them were
+// set yet, reporting whether this call set them
+func (st *SkipTrie) TestAndSetFlags(key uint32, mask Flags) bool {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil {
+		return false
+	}
+	
+	for {
+		old := node.flags.Load()
+		if Flags(old)&mask != 0 {
+			return false
+		}
+		if node.flags.CompareAndSwap(old, old|uint32(mask)) {
+			return true
+		}
+	}
+}
+
+// CompareAndSwapFlags replaces the flags of key with new if they equal old
+func (st *SkipTrie) CompareAndSwapFlags(key uint32, old, new Flags) bool {
+	defer st.unpin(st.pin())
+	
+	node := st.findNode(key)
+	if node == nil {
+		return false
+	}
+	return node.flags.CompareAndSwap(uint32(old), uint32(new))
+}
+
+// RangeUpdate visits each key in [lo, hi] in ascending order and lets fn
+// rewrite its flags in place, stopping after fn returns false
+//
+// Each rewrite is applied with a CAS; if another update to the same key
+// intervenes, fn is called again for that key with the fresh flags
+func (st *SkipTrie) RangeUpdate(lo, hi uint32, fn func(key uint32, flags *Flags) bool) {
+	st.ascend(lo, hi, func(node *Node) bool {
+		for {
+			old := node.flags.Load()
+			flags := Flags(old)
+			more := fn(node.key, &flags)
+			if node.flags.CompareAndSwap(old, uint32(flags)) {
+				return more
+			}
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package skiptrie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// ErrFrozenFormat is returned for data that is not a well-formed frozen
+// SkipTrie
+var ErrFrozenFormat = errors.New("skiptrie: malformed frozen SkipTrie")
+
+// frozenMagic starts every frozen SkipTrie, followed by the format version
+const frozenMagic = "SKTF"
+
+// frozenVersion is the format written by Freeze
+const frozenVersion = 1
+
+// The frozen format is little-endian and read in place, so a mapped file
+// is queried without decoding it first:
+//
+//	header  magic, version, 3 bytes padding, key count (8 bytes), prefix
+//	        count (4 bytes), CRC-32 of everything after the header
+//	        (4 bytes), 8 reserved bytes
+//	bitmap  65536 bits, one per high half of a key, set if any key has it
+//	ranks   1024 counts of the bits set before each 64-bit bitmap word
+//	highs   the high halves present, ascending (2 bytes each)
+//	starts  for each present high half, the position of its first key,
+//	        then the key count (8 bytes each)
+//	lows    the low halves of the keys, ascending within each high half
+//	        (2 bytes each)
+//
+// The bitmap and its ranks are a one-level succinct trie over the high
+// halves: the position of a high half among those present is a rank
+// query, which selects its stretch of lows for a binary search
+const (
+	frozenHeaderSize = 32
+	frozenBitmapSize = 1 << 16 / 8
+	frozenRanksSize  = 1 << 16 / 64 * 4
+)
+
+// Freeze writes the keys of st in the frozen format, which OpenFrozen and
+// NewFrozen serve read-only without rebuilding a SkipTrie
+// The keys take 2 bytes each plus about 12KB and 10 bytes per distinct
+// high half. Concurrent updates may or may not be captured
+func (st *SkipTrie) Freeze(w io.Writer) error {
+	var highs []uint16
+	var starts []uint64
+	var lows []uint16
+	st.ascend(0, math.MaxUint32, func(node *Node) bool {
+		high := uint16(node.key >> 16)
+		if len(highs) == 0 || highs[len(highs)-1] != high {
+			highs = append(highs, high)
+			starts = append(starts, uint64(len(lows)))
+		}
+		lows = append(lows, uint16(node.key))
+		return true
+	})
+	starts = append(starts, uint64(len(lows)))
+	return writeFrozen(w, highs, starts, lows)
+}
+
+// writeFrozen writes the sections of a frozen SkipTrie
+func writeFrozen(w io.Writer, highs []uint16, starts []uint64, lows []uint16) error {
+	var bitmap [1 << 16 / 64]uint64
+	for _, high := range highs {
+		bitmap[high/64] |= 1 << (high % 64)
+	}
+	
+	body := make([]byte, 0, frozenBitmapSize+frozenRanksSize+10*len(highs)+8)
+	rank := uint32(0)
+	for _, word := range bitmap {
+		body = binary.LittleEndian.AppendUint64(body, word)
+	}
+	for _, word := range bitmap {
+		body = binary.LittleEndian.AppendUint32(body, rank)
+		rank += uint32(bits.OnesCount64(word))
+	}
+	for _, high := range highs {
+		body = binary.LittleEndian.AppendUint16(body, high)
+	}
+	for _, start := range starts {
+		body = binary.LittleEndian.AppendUint64(body, start)
+	}
+	
+	// The lows are streamed, so the checksum covers them on the way out
+	crc := crc32.NewIEEE()
+	crc.Write(body)
+	var pair [2]byte
+	for _, low := range lows {
+		binary.LittleEndian.PutUint16(pair[:], low)
+		crc.Write(pair[:])
+	}
+	
+	header := make([]byte, frozenHeaderSize)
+	copy(header, frozenMagic)
+	header[4] = frozenVersion
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(lows)))
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(highs)))
+	binary.LittleEndian.PutUint32(header[20:], crc.Sum32())
+	
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+	bw.Write(body)
+	for _, low := range lows {
+		binary.LittleEndian.PutUint16(pair[:], low)
+		bw.Write(pair[:])
+	}
+	return bw.Flush()
+}
+
+// FrozenSkipTrie is an immutable set of keys queried in place from the
+// bytes Freeze wrote, typically a mapped file
+// Queries cost a rank over the high halves and a binary search among the
+// keys sharing one, and are safe for concurrent use
+type FrozenSkipTrie struct {
+	data   []byte       // the whole frozen image
+	count  int          // number of keys
+	bitmap []byte       // high halves present
+	ranks  []byte       // set bits before each bitmap word
+	highs  []byte       // present high halves, ascending
+	starts []byte       // first position of each high half, then count
+	lows   []byte       // low halves of the keys
+	crc    uint32       // checksum from the header
+	unmap  func() error // releases data (OpenFrozen only)
+}
+
+// NewFrozen serves the frozen SkipTrie in data, which must not change
+// while it is in use
+// The layout is checked, but not the checksum over the keys, which would
+// read all of them; Verify does that
+func NewFrozen(data []byte) (*FrozenSkipTrie, error) {
+	if len(data) < frozenHeaderSize || string(data[:4]) != frozenMagic || data[4] != frozenVersion {
+		return nil, ErrFrozenFormat
+	}
+	count64 := binary.LittleEndian.Uint64(data[8:])
+	prefixes := uint64(binary.LittleEndian.Uint32(data[16:]))
+	if prefixes > 1<<16 || count64 > 1<<32 || count64 > uint64(math.MaxInt/2) {
+		return nil, ErrFrozenFormat
+	}
+	size := frozenHeaderSize + frozenBitmapSize + frozenRanksSize + 10*prefixes + 8 + 2*count64
+	if uint64(len(data)) != size {
+		return nil, ErrFrozenFormat
+	}
+	
+	f := &FrozenSkipTrie{data: data, count: int(count64), crc: binary.LittleEndian.Uint32(data[20:])}
+	rest := data[frozenHeaderSize:]
+	f.bitmap, rest = rest[:frozenBitmapSize], rest[frozenBitmapSize:]
+	f.ranks, rest = rest[:frozenRanksSize], rest[frozenRanksSize:]
+	f.highs, rest = rest[:2*prefixes], rest[2*prefixes:]
+	f.starts, f.lows = rest[:8*(prefixes+1)], rest[8*(prefixes+1):]
+	
+	if err := f.checkIndex(int(prefixes)); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// checkIndex checks that the bitmap, ranks, highs and starts agree, so
+// that no query can index out of range
+func (f *FrozenSkipTrie) checkIndex(prefixes int) error {
+	rank := 0
+	for w := 0; w < 1<<16/64; w++ {
+		if int(f.rankWord(w)) != rank {
+			return ErrFrozenFormat
+		}
+		rank += bits.OnesCount64(f.word(w))
+	}
+	if rank != prefixes {
+		return ErrFrozenFormat
+	}
+	
+	for j := 0; j < prefixes; j++ {
+		high := f.high(j)
+		if (j > 0 && high <= f.high(j-1)) || !f.present(high) {
+			return ErrFrozenFormat
+		}
+		if f.start(j) >= f.start(j+1) {
+			return ErrFrozenFormat
+		}
+	}
+	if f.start(0) != 0 || f.start(prefixes) != f.count {
+		return ErrFrozenFormat
+	}
+	return nil
+}
+
+// Verify checks the checksum over the whole image and the order of the
+// keys, reading all of them
+func (f *FrozenSkipTrie) Verify() error {
+	if crc32.ChecksumIEEE(f.data[frozenHeaderSize:]) != f.crc {
+		return ErrFrozenFormat
+	}
+	for j := 0; j < f.prefixes(); j++ {
+		for i := f.start(j) + 1; i < f.start(j+1); i++ {
+			if f.low(i) <= f.low(i-1) {
+				return ErrFrozenFormat
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the mapping of a FrozenSkipTrie from OpenFrozen; it must
+// not be queried afterwards
+func (f *FrozenSkipTrie) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	unmap := f.unmap
+	f.unmap = nil
+	return unmap()
+}
+
+// word returns bitmap word w
+func (f *FrozenSkipTrie) word(w int) uint64 {
+	return binary.LittleEndian.Uint64(f.bitmap[8*w:])
+}
+
+// rankWord returns the number of bits set before bitmap word w
+func (f *FrozenSkipTrie) rankWord(w int) uint32 {
+	return binary.LittleEndian.Uint32(f.ranks[4*w:])
+}
+
+// prefixes returns the number of high halves present
+func (f *FrozenSkipTrie) prefixes() int {
+	return len(f.highs) / 2
+}
+
+// high returns the j-th high half present
+func (f *FrozenSkipTrie) high(j int) uint16 {
+	return binary.LittleEndian.Uint16(f.highs[2*j:])
+}
+
+// start returns the position of the first key of the j-th high half
+func (f *FrozenSkipTrie) start(j int) int {
+	return int(binary.LittleEndian.Uint64(f.starts[8*j:]))
+}
+
+// low returns the low half of the key at position i
+func (f *FrozenSkipTrie) low(i int) uint16 {
+	return binary.LittleEndian.Uint16(f.lows[2*i:])
+}
+
+// present checks if some key has the high half high
+func (f *FrozenSkipTrie) present(high uint16) bool {
+	return f.word(int(high/64))&(1<<(high%64)) != 0
+}
+
+// rank returns the number of high halves present below high
+func (f *FrozenSkipTrie) rank(high uint16) int {
+	w := int(high / 64)
+	below := f.word(w) & (1<<(high%64) - 1)
+	return int(f.rankWord(w)) + bits.OnesCount64(below)
+}
+
+// position returns the number of keys less than key
+func (f *FrozenSkipTrie) position(key uint32) int {
+	high, low := uint16(key>>16), uint16(key)
+	j := f.rank(high)
+	if !f.present(high) {
+		return f.start(j)
+	}
+	lo, hi := f.start(j), f.start(j+1)
+	return lo + sort.Search(hi-lo, func(i int) bool { return f.low(lo+i) >= low })
+}
+
+// at returns the key at position i
+func (f *FrozenSkipTrie) at(i int) uint32 {
+	// The last high half starting at or before i holds it
+	j := sort.Search(f.prefixes(), func(j int) bool { return f.start(j) > i }) - 1
+	return uint32(f.high(j))<<16 | uint32(f.low(i))
+}
+
+// Len returns the number of keys
+func (f *FrozenSkipTrie) Len() int {
+	return f.count
+}
+
+// Contains checks if a key exists
+func (f *FrozenSkipTrie) Contains(key uint32) bool {
+	i := f.position(key)
+	return i < f.count && f.at(i) == key
+}
+
+// PredecessorKey returns the largest key strictly less than key
+func (f *FrozenSkipTrie) PredecessorKey(key uint32) (uint32, bool) {
+	i := f.position(key)
+	if i == 0 {
+		return 0, false
+	}
+	return f.at(i - 1), true
+}
+
+// SuccessorKey returns the smallest key strictly greater than key
+func (f *FrozenSkipTrie) SuccessorKey(key uint32) (uint32, bool) {
+	if key == math.MaxUint32 {
+		return 0, false
+	}
+	i := f.position(key + 1)
+	if i == f.count {
+		return 0, false
+	}
+	return f.at(i), true
+}
+
+// Range calls fn for each key in [lo, hi] in ascending order until fn
+// returns false
+func (f *FrozenSkipTrie) Range(lo, hi uint32, fn func(key uint32) bool) {
+	if lo > hi {
+		return
+	}
+	i := f.position(lo)
+	if i == f.count {
+		return
+	}
+	j := sort.Search(f.prefixes(), func(j int) bool { return f.start(j) > i }) - 1
+	for ; j < f.prefixes(); j++ {
+		high := uint32(f.high(j)) << 16
+		for end := f.start(j + 1); i < end; i++ {
+			key := high | uint32(f.low(i))
+			if key > hi || !fn(key) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package skiptrie
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenFrozen maps the frozen SkipTrie in the file at path read-only and
+// serves it in place; pages are read from the file as queries touch them
+// Close unmaps it
+func OpenFrozen(path string) (*FrozenSkipTrie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < frozenHeaderSize || size != int64(int(size)) {
+		return nil, ErrFrozenFormat
+	}
+	
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	f, err := NewFrozen(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	f.unmap = func() error { return syscall.Munmap(data) }
+	return f, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package skiptrie
+
+import "os"
+
+// OpenFrozen reads the frozen SkipTrie in the file at path and serves it
+// from memory; on unix systems the file is mapped instead of read
+func OpenFrozen(path string) (*FrozenSkipTrie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrozen(data)
+}
@@ -0,0 +1,165 @@
+//go:build stress
+
+package skiptrie
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// fuzzOpSize is the number of input bytes decoded into one operation: an
+// op selector and two key bytes
+const fuzzOpSize = 3
+
+// fuzzGoroutines is the number of goroutines the concurrent replay of
+// CheckOperations splits the operations across
+const fuzzGoroutines = 4
+
+// fuzzOp is one decoded operation
+type fuzzOp struct {
+	kind byte // 0 Insert, 1 Delete, 2 Contains, 3 Predecessor
+	key  uint32
+}
+
+// decodeOps turns data into operations; the key bytes become the top and
+// bottom byte of the key, so keys collide often and differ in both the
+// prefixes the trie indexes and the bottom-level order
+func decodeOps(data []byte) []fuzzOp {
+	ops := make([]fuzzOp, 0, len(data)/fuzzOpSize)
+	for ; len(data) >= fuzzOpSize; data = data[fuzzOpSize:] {
+		ops = append(ops, fuzzOp{
+			kind: data[0] % 4,
+			key:  uint32(data[1])<<24 | uint32(data[2]),
+		})
+	}
+	return ops
+}
+
+// CheckOperations decodes data into Insert, Delete, Contains and
+// Predecessor calls and checks them twice: run in order against a map
+// model, where every result must agree, and split by key across goroutines,
+// where each goroutine's results must agree with its own keys' history and
+// the final set must match the model. Both runs end with the structural
+// checks
+// It is the body of the FuzzOperations fuzz target
+func CheckOperations(data []byte) error {
+	ops := decodeOps(data)
+	
+	// Sequential run against the model
+	st := NewSkipTrie()
+	model := map[uint32]bool{}
+	for i, op := range ops {
+		if err := checkOp(st, model, op); err != nil {
+			return fmt.Errorf("sequential op %d: %w", i, err)
+		}
+	}
+	if err := st.Validate(); err != nil {
+		return fmt.Errorf("sequential: %w", err)
+	}
+	
+	// Concurrent run: each key belongs to one goroutine, so the results for
+	// it are as deterministic as in the sequential run
+	st = NewSkipTrie()
+	models := make([]map[uint32]bool, fuzzGoroutines)
+	errs := make([]error, fuzzGoroutines)
+	var wg sync.WaitGroup
+	for g := range models {
+		models[g] = map[uint32]bool{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, op := range ops {
+				if int(op.key%fuzzGoroutines) != g {
+					continue
+				}
+				if op.kind == 3 {
+					// Other goroutines change the answer; check its shape
+					if pred := st.Predecessor(op.key); pred != nil && pred.key >= op.key {
+						errs[g] = fmt.Errorf("concurrent op %d: Predecessor(%d) = %d", i, op.key, pred.key)
+						return
+					}
+					continue
+				}
+				if err := checkOp(st, models[g], op); err != nil {
+					errs[g] = fmt.Errorf("concurrent op %d: %w", i, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if err := st.Validate(); err != nil {
+		return fmt.Errorf("concurrent: %w", err)
+	}
+	for key := range model {
+		if !st.Contains(key) {
+			return fmt.Errorf("concurrent: key %d missing at the end", key)
+		}
+	}
+	if st.Len() != len(model) {
+		return fmt.Errorf("concurrent: Len is %d, want %d", st.Len(), len(model))
+	}
+	return nil
+}
+
+// checkOp applies op to st and model and compares the results
+func checkOp(st *SkipTrie, model map[uint32]bool, op fuzzOp) error {
+	key := op.key
+	switch op.kind {
+	case 0:
+		if got, want := st.Insert(key), !model[key]; got != want {
+			return fmt.Errorf("Insert(%d) = %v, want %v", key, got, want)
+		}
+		model[key] = true
+	case 1:
+		if got, want := st.Delete(key), model[key]; got != want {
+			return fmt.Errorf("Delete(%d) = %v, want %v", key, got, want)
+		}
+		delete(model, key)
+	case 2:
+		if got, want := st.Contains(key), model[key]; got != want {
+			return fmt.Errorf("Contains(%d) = %v, want %v", key, got, want)
+		}
+	case 3:
+		var want *uint32
+		for k := range model {
+			if k < key && (want == nil || k > *want) {
+				want = &k
+			}
+		}
+		pred := st.Predecessor(key)
+		switch {
+		case want == nil && pred != nil:
+			return fmt.Errorf("Predecessor(%d) = %d, want none", key, pred.key)
+		case want != nil && (pred == nil || pred.key != *want):
+			return fmt.Errorf("Predecessor(%d) = %v, want %d", key, pred, *want)
+		}
+	}
+	return nil
+}
+
+// StressOperations runs CheckOperations on random inputs of up to
+// 16*Goroutines operations until the duration has passed, reporting the
+// first failing input in hex so it can be replayed or added to a fuzz
+// corpus
+func StressOperations(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+	for deadline := time.Now().Add(cfg.Duration); time.Now().Before(deadline); {
+		data := make([]byte, fuzzOpSize*(1+rng.IntN(16*cfg.Goroutines)))
+		for i := range data {
+			data[i] = byte(rng.Uint32())
+		}
+		if err := CheckOperations(data); err != nil {
+			return fmt.Errorf("input %x: %w", data, err)
+		}
+	}
+	return nil
+}
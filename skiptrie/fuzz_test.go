@@ -0,0 +1,28 @@
+//go:build stress
+
+package skiptrie
+
+import "testing"
+
+// FuzzOperations checks random sequences of Insert, Delete, Contains and
+// Predecessor against a map model with CheckOperations; the seeds run
+// under go test -tags stress, and go test -tags stress -fuzz
+// FuzzOperations explores further
+func FuzzOperations(f *testing.F) {
+	// Each operation is an op selector (mod 4: Insert, Delete, Contains,
+	// Predecessor) and the top and bottom byte of its key
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 7, 2, 0, 7, 1, 0, 7, 2, 0, 7})
+	f.Add([]byte{0, 0, 1, 0, 0, 3, 3, 0, 2, 1, 0, 1, 3, 0, 2, 3, 0, 0})
+	f.Add([]byte{0, 0x80, 5, 0, 0x7f, 5, 3, 0xff, 0xff, 1, 0x80, 5, 3, 0xff, 0xff, 3, 0x80, 0})
+	f.Add([]byte{
+		0, 1, 1, 0, 2, 2, 0, 3, 3, 0, 4, 4, 0, 5, 5, 0, 6, 6,
+		1, 3, 3, 1, 4, 4, 3, 4, 4, 3, 5, 0, 0, 4, 4, 2, 4, 4,
+		1, 1, 1, 1, 2, 2, 1, 5, 5, 1, 6, 6, 3, 0xff, 0xff, 2, 3, 3,
+	})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := CheckOperations(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
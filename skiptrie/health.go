@@ -0,0 +1,157 @@
+package skiptrie
+
+import (
+	"fmt"
+	"math"
+)
+
+// healthSamples caps the anomalies a Health report lists in detail; the
+// counts keep covering all of them
+const healthSamples = 16
+
+// LevelStat describes the nodes linked at one skiplist level
+type LevelStat struct {
+	Level     int     // skiplist level, 0 for the bottom
+	Nodes     int     // nodes linked at the level, sentinels excluded
+	Marked    int     // linked nodes already marked deleted
+	Promotion float64 // Nodes over the Nodes of the level below, 0 for the bottom or an empty level below
+}
+
+// LevelStats walks every level of the skiplist and returns its occupancy,
+// bottom first, with the measured fraction of nodes promoted from the
+// level below, which random heights keep near 1/2
+// The walk runs alongside writers, so the figures are only consistent
+// while st is quiescent
+func (st *SkipTrie) LevelStats() []LevelStat {
+	defer st.unpin(st.pin())
+	
+	stats := make([]LevelStat, st.levels)
+	for level := range stats {
+		stats[level].Level = level
+		for node := st.head.next[level].Load(); node != nil && node != st.tail; node = node.next[level].Load() {
+			stats[level].Nodes++
+			if node.marked.Load() {
+				stats[level].Marked++
+			}
+		}
+		if level > 0 && stats[level-1].Nodes > 0 {
+			stats[level].Promotion = float64(stats[level].Nodes) / float64(stats[level-1].Nodes)
+		}
+	}
+	return stats
+}
+
+// Anomaly is one suspicious state found by Health
+type Anomaly struct {
+	Problem string // what is wrong
+	Level   int    // skiplist level involved; -1 stands for the trie
+	Key     uint32 // key of the node involved, if any
+}
+
+// String formats the anomaly on one line
+func (a Anomaly) String() string {
+	where := "trie"
+	if a.Level >= 0 {
+		where = fmt.Sprintf("level %d", a.Level)
+	}
+	return fmt.Sprintf("%s: %s (key %d)", where, a.Problem, a.Key)
+}
+
+// HealthReport summarizes the shape of a SkipTrie and the anomalies found
+// in it
+type HealthReport struct {
+	Levels []LevelStat // occupancy per level, bottom first
+	
+	MarkedEntries int // trie pointers to nodes marked deleted
+	MissingBelow  int // nodes linked at a level but not at the one below
+	LinkedMarked  int // marked nodes still linked at some level
+	SkewedLevels  int // levels whose promotion ratio is implausible for random heights
+	TrieEntries   int // prefixes visited in the x-fast trie
+	
+	Anomalies []Anomaly // the first anomalies found, at most 16
+}
+
+// Healthy checks if the report found no anomaly
+func (h *HealthReport) Healthy() bool {
+	return h.MarkedEntries == 0 && h.MissingBelow == 0 && h.LinkedMarked == 0 && h.SkewedLevels == 0
+}
+
+// note counts an anomaly in total and keeps it if there is room
+func (h *HealthReport) note(total *int, problem string, level int, key uint32) {
+	*total++
+	if len(h.Anomalies) < healthSamples {
+		h.Anomalies = append(h.Anomalies, Anomaly{Problem: problem, Level: level, Key: key})
+	}
+}
+
+// Health walks every level and the x-fast trie and reports anomalies for
+// production diagnostics: trie entries pointing at deleted nodes, towers
+// linked at a level but missing from the one below, deleted nodes still
+// linked, and levels whose occupancy strays far from what random heights
+// give (only checked without WithHeightFunc)
+// Unlike Validate it never fails and tolerates writers: under load it
+// counts states that are transient. The trie is only a hint, so a few
+// entries left pointing at deleted nodes by racing deletes are harmless,
+// while many of them point at lost repairs
+func (st *SkipTrie) Health() HealthReport {
+	defer st.unpin(st.pin())
+	
+	h := HealthReport{Levels: make([]LevelStat, st.levels)}
+	var below []*Node
+	for level := range h.Levels {
+		stat := &h.Levels[level]
+		stat.Level = level
+		
+		// Both levels are sorted, so a cursor over the level below finds
+		// each node of this one
+		var nodes []*Node
+		i := 0
+		for node := st.head.next[level].Load(); node != nil && node != st.tail; node = node.next[level].Load() {
+			nodes = append(nodes, node)
+			stat.Nodes++
+			if node.marked.Load() {
+				stat.Marked++
+				h.note(&h.LinkedMarked, "deleted node still linked", level, node.key)
+			}
+			if level == 0 {
+				continue
+			}
+			for i < len(below) && below[i].key < node.key {
+				i++
+			}
+			if i == len(below) || below[i] != node {
+				h.note(&h.MissingBelow, "node missing from the level below", level, node.key)
+			}
+		}
+		
+		if n := h.Levels[max(level-1, 0)].Nodes; level > 0 && n > 0 {
+			stat.Promotion = float64(stat.Nodes) / float64(n)
+			if st.heightFn == nil && skewed(stat.Nodes, n) {
+				h.note(&h.SkewedLevels, fmt.Sprintf("promotion ratio %.3f from %d nodes", stat.Promotion, n), level, 0)
+			}
+		}
+		below = nodes
+	}
+	
+	st.prefixes.rangeEntries(func(p prefix, tn *TreeNode) bool {
+		h.TrieEntries++
+		for dir := range tn.pointers {
+			if node := tn.pointers[dir].Load(); node != nil && node.marked.Load() {
+				h.note(&h.MarkedEntries, fmt.Sprintf("entry %v points at a deleted node", st.prefixEntry(p)), -1, node.key)
+			}
+		}
+		return true
+	})
+	return h
+}
+
+// skewed checks if promoting promoted of n nodes is more than six standard
+// deviations from the fair coin flips of randomHeight; small levels are
+// never skewed
+func skewed(promoted, n int) bool {
+	if n < 64 {
+		return false
+	}
+	dev := math.Abs(float64(promoted) - float64(n)/2)
+	return dev > 6*math.Sqrt(float64(n))/2
+}
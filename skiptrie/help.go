@@ -0,0 +1,69 @@
+package skiptrie
+
+import "math/rand/v2"
+
+// HelpPolicy decides whether queries unlink the deleted nodes they pass
+type HelpPolicy uint8
+
+const (
+	// HelpAlways: every query unlinks the deleted nodes it passes, as
+	// deleters and inserters do (the default)
+	HelpAlways HelpPolicy = iota
+	// HelpNever: queries step over deleted nodes through their frozen
+	// pointers and leave the unlinking to writers
+	HelpNever
+	// HelpProbabilistic: queries unlink about one deleted node in
+	// helpOneIn they pass and step over the rest
+	HelpProbabilistic
+)
+
+// helpOneIn is the odds with which HelpProbabilistic queries help
+const helpOneIn = 8
+
+// String returns the name of the policy
+func (p HelpPolicy) String() string {
+	switch p {
+	case HelpAlways:
+		return "always"
+	case HelpNever:
+		return "never"
+	case HelpProbabilistic:
+		return "probabilistic"
+	}
+	return "unknown"
+}
+
+// WithHelpPolicy sets whether Predecessor and the queries built on it
+// (Contains, ranges, Floor...) unlink the deleted nodes they pass
+//
+// Unlinking is a CAS on the predecessor's pointer, so in read-mostly
+// workloads helping readers pull the cache lines of hot nodes away from
+// each other. A deleted node is unlinked by its deleter anyway, and every
+// writer's search still helps, so skipping costs readers at most the
+// extra hops over nodes a deleter has yet to unlink. PredecessorLive
+// always helps, since it needs a confirmed link
+func WithHelpPolicy(p HelpPolicy) Option {
+	return func(st *SkipTrie) {
+		st.help = p
+	}
+}
+
+// readerHelps decides whether a query unlinks the deleted node it is at
+func (st *SkipTrie) readerHelps() bool {
+	switch st.help {
+	case HelpNever:
+		return false
+	case HelpProbabilistic:
+		return rand.Uint32N(helpOneIn) == 0
+	}
+	return true
+}
+
+// pastMarked returns the first unmarked node from node on at level,
+// following the frozen pointers of deleted nodes
+func pastMarked(node *Node, level int) *Node {
+	for node != nil && node.marked.Load() {
+		node = node.next[level].Load()
+	}
+	return node
+}
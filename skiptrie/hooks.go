@@ -0,0 +1,19 @@
+//go:build !testhooks
+
+package skiptrie
+
+// testHooks is empty without the testhooks build tag, so that every hook
+// check below compiles to a constant
+type testHooks struct{}
+
+// failCAS reports whether to fail the next link CAS
+func (*testHooks) failCAS() bool { return false }
+
+// forcedHeight returns the next forced tower height, if any
+func (*testHooks) forcedHeight() (int, bool) { return 0, false }
+
+// missTrie reports whether the next trie lookup should miss
+func (*testHooks) missTrie() bool { return false }
+
+// pause is called at point by an operation on key
+func (*testHooks) pause(point PausePoint, key uint32) {}
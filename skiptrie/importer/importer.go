@@ -0,0 +1,75 @@
+// Package importer loads keys into a SkipTrie from the serialized forms of
+// other ordered stores, so migrations don't need one-off conversion tools
+//
+// Keys reads any key stream exposed through a KeyIterator, such as an
+// iterator over a LevelDB or RocksDB SST file; RDB reads the sorted sets of
+// a Redis RDB dump
+package importer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrKeyRange is returned by the key decoders for input that doesn't fit
+// in a uint32
+var ErrKeyRange = errors.New("importer: key out of range")
+
+// Inserter is the part of a SkipTrie the importers need; *skiptrie.SkipTrie
+// satisfies it
+type Inserter interface {
+	Insert(key uint32) bool
+}
+
+// KeyIterator is a forward iterator over raw keys
+// Its method set matches the iterators of goleveldb and similar stores, so
+// those can be passed in directly or through a thin adapter
+type KeyIterator interface {
+	Next() bool   // advances to the next key, reporting whether there is one
+	Key() []byte  // returns the current key, valid until the next call to Next
+	Error() error // returns the error that stopped the iteration, if any
+}
+
+// KeyDecoder converts a raw key into a SkipTrie key
+type KeyDecoder func(raw []byte) (uint32, error)
+
+// BigEndianKey decodes a 4-byte big-endian key, the layout that keeps byte
+// order and numeric order the same
+func BigEndianKey(raw []byte) (uint32, error) {
+	if len(raw) != 4 {
+		return 0, fmt.Errorf("%w: %d-byte key", ErrKeyRange, len(raw))
+	}
+	return binary.BigEndian.Uint32(raw), nil
+}
+
+// DecimalKey decodes a key written as a decimal number
+func DecimalKey(raw []byte) (uint32, error) {
+	key, err := strconv.ParseUint(string(raw), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrKeyRange, raw)
+	}
+	return uint32(key), nil
+}
+
+// Keys inserts every key produced by it into dst, decoding each with
+// decode (BigEndianKey if nil), and returns the number of keys inserted
+// Keys already present are not counted; a decoding error stops the import
+func Keys(dst Inserter, it KeyIterator, decode KeyDecoder) (int, error) {
+	if decode == nil {
+		decode = BigEndianKey
+	}
+	
+	n := 0
+	for it.Next() {
+		key, err := decode(it.Key())
+		if err != nil {
+			return n, err
+		}
+		if dst.Insert(key) {
+			n++
+		}
+	}
+	return n, it.Error()
+}
@@ -0,0 +1,635 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ErrRDB is wrapped by every error RDB returns for malformed or unsupported
+// input
+var ErrRDB = errors.New("importer: bad RDB stream")
+
+// RDB opcodes
+const (
+	rdbOpSlotInfo  = 244
+	rdbOpFunction2 = 245
+	rdbOpModuleAux = 247
+	rdbOpIdle      = 248
+	rdbOpFreq      = 249
+	rdbOpAux       = 250
+	rdbOpResizeDB  = 251
+	rdbOpExpireMs  = 252
+	rdbOpExpire    = 253
+	rdbOpSelectDB  = 254
+	rdbOpEOF       = 255
+)
+
+// RDB value types
+const (
+	rdbString         = 0
+	rdbList           = 1
+	rdbSet            = 2
+	rdbZSet           = 3
+	rdbHash           = 4
+	rdbZSet2          = 5
+	rdbHashZipmap     = 9
+	rdbListZiplist    = 10
+	rdbSetIntset      = 11
+	rdbZSetZiplist    = 12
+	rdbHashZiplist    = 13
+	rdbListQuicklist  = 14
+	rdbHashListpack   = 16
+	rdbZSetListpack   = 17
+	rdbListQuicklist2 = 18
+	rdbSetListpack    = 20
+)
+
+// RDBOptions selects the sorted sets RDB imports and maps their members
+// to keys
+type RDBOptions struct {
+	// Sets reports whether the sorted set stored under name is imported;
+	// nil imports every sorted set
+	Sets func(name string) bool
+	
+	// Member maps a member and its score to a key; nil parses the member
+	// with DecimalKey
+	Member func(member []byte, score float64) (uint32, error)
+}
+
+// ScoreKey is an RDBOptions.Member that uses the score as the key, for sets
+// whose scores are ids or timestamps
+func ScoreKey(member []byte, score float64) (uint32, error) {
+	if score < 0 || score > math.MaxUint32 || score != math.Trunc(score) {
+		return 0, fmt.Errorf("%w: score %v of %q", ErrKeyRange, score, member)
+	}
+	return uint32(score), nil
+}
+
+// RDB inserts the members of the sorted sets in a Redis RDB dump into dst
+// and returns the number of keys inserted
+//
+// Sorted sets are read in all of their encodings (skiplist, ziplist and
+// listpack). Strings, lists, sets and hashes are skipped; streams and
+// module types are not supported and stop the import with an error.
+// Expiry times are ignored and the trailing checksum is not verified
+func RDB(dst Inserter, r io.Reader, opts RDBOptions) (int, error) {
+	if opts.Member == nil {
+		opts.Member = func(member []byte, _ float64) (uint32, error) {
+			return DecimalKey(member)
+		}
+	}
+	
+	rd := &rdbReader{r: bufio.NewReader(r)}
+	var magic [9]byte
+	if _, err := io.ReadFull(rd.r, magic[:]); err != nil {
+		return 0, fmt.Errorf("%w: header: %v", ErrRDB, err)
+	}
+	if string(magic[:5]) != "REDIS" {
+		return 0, fmt.Errorf("%w: missing REDIS magic", ErrRDB)
+	}
+	
+	n := 0
+	add := func(member []byte, score float64) error {
+		key, err := opts.Member(member, score)
+		if err != nil {
+			return err
+		}
+		if dst.Insert(key) {
+			n++
+		}
+		return nil
+	}
+	
+	for {
+		op, err := rd.r.ReadByte()
+		if err != nil {
+			return n, fmt.Errorf("%w: %v", ErrRDB, err)
+		}
+		
+		switch op {
+		case rdbOpEOF:
+			return n, nil
+		case rdbOpSelectDB:
+			_, err = rd.length()
+		case rdbOpResizeDB:
+			err = rd.skipLengths(2)
+		case rdbOpSlotInfo:
+			err = rd.skipLengths(3)
+		case rdbOpAux:
+			err = rd.skipStrings(2)
+		case rdbOpFunction2:
+			err = rd.skipStrings(1)
+		case rdbOpExpireMs:
+			err = rd.skip(8)
+		case rdbOpExpire:
+			err = rd.skip(4)
+		case rdbOpFreq:
+			err = rd.skip(1)
+		case rdbOpIdle:
+			_, err = rd.length()
+		case rdbOpModuleAux:
+			err = fmt.Errorf("%w: module data is not supported", ErrRDB)
+		default:
+			var name []byte
+			if name, err = rd.str(); err != nil {
+				break
+			}
+			if opts.Sets == nil || opts.Sets(string(name)) {
+				err = rd.value(op, add)
+			} else {
+				err = rd.value(op, nil)
+			}
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// rdbReader decodes the primitives of the RDB format
+type rdbReader struct {
+	r *bufio.Reader
+}
+
+// skip discards n bytes
+func (rd *rdbReader) skip(n int) error {
+	if _, err := rd.r.Discard(n); err != nil {
+		return fmt.Errorf("%w: %v", ErrRDB, err)
+	}
+	return nil
+}
+
+// skipLengths discards n length fields
+func (rd *rdbReader) skipLengths(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := rd.length(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipStrings discards n strings
+func (rd *rdbReader) skipStrings(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := rd.str(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lengthOrEncoding reads a length field; encoded reports that the field
+// instead names a special string encoding, returned as the length
+func (rd *rdbReader) lengthOrEncoding() (length uint64, encoded bool, err error) {
+	b, err := rd.r.ReadByte()
+	if err != nil {
+		return 0, false, fmt.Errorf("%w: %v", ErrRDB, err)
+	}
+	
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		lo, err := rd.r.ReadByte()
+		if err != nil {
+			return 0, false, fmt.Errorf("%w: %v", ErrRDB, err)
+		}
+		return uint64(b&0x3f)<<8 | uint64(lo), false, nil
+	case 2:
+		var buf [8]byte
+		switch b {
+		case 0x80:
+			if _, err := io.ReadFull(rd.r, buf[:4]); err != nil {
+				return 0, false, fmt.Errorf("%w: %v", ErrRDB, err)
+			}
+			return uint64(binary.BigEndian.Uint32(buf[:4])), false, nil
+		case 0x81:
+			if _, err := io.ReadFull(rd.r, buf[:]); err != nil {
+				return 0, false, fmt.Errorf("%w: %v", ErrRDB, err)
+			}
+			return binary.BigEndian.Uint64(buf[:]), false, nil
+		}
+		return 0, false, fmt.Errorf("%w: length prefix %#x", ErrRDB, b)
+	}
+	return uint64(b & 0x3f), true, nil
+}
+
+// length reads a plain length field
+func (rd *rdbReader) length() (uint64, error) {
+	length, encoded, err := rd.lengthOrEncoding()
+	if err == nil && encoded {
+		err = fmt.Errorf("%w: encoded string where a length was expected", ErrRDB)
+	}
+	return length, err
+}
+
+// bytes reads n raw bytes
+func (rd *rdbReader) bytes(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: %d-byte string", ErrRDB, n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(rd.r, buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRDB, err)
+	}
+	return buf, nil
+}
+
+// str reads a string in any of its encodings; integers are returned in
+// decimal, as Redis presents them
+func (rd *rdbReader) str() ([]byte, error) {
+	length, encoded, err := rd.lengthOrEncoding()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return rd.bytes(length)
+	}
+	
+	switch length {
+	case 0, 1, 2:
+		buf, err := rd.bytes(1 << length)
+		if err != nil {
+			return nil, err
+		}
+		var v int64
+		switch length {
+		case 0:
+			v = int64(int8(buf[0]))
+		case 1:
+			v = int64(int16(binary.LittleEndian.Uint16(buf)))
+		case 2:
+			v = int64(int32(binary.LittleEndian.Uint32(buf)))
+		}
+		return strconv.AppendInt(nil, v, 10), nil
+	case 3:
+		clen, err := rd.length()
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := rd.length()
+		if err != nil {
+			return nil, err
+		}
+		compressed, err := rd.bytes(clen)
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(compressed, ulen)
+	}
+	return nil, fmt.Errorf("%w: string encoding %d", ErrRDB, length)
+}
+
+// score reads a ZSET score stored as a length-prefixed decimal string
+func (rd *rdbReader) score() (float64, error) {
+	n, err := rd.r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRDB, err)
+	}
+	
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	buf, err := rd.bytes(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return parseScore(buf)
+}
+
+// value reads a value of type typ, passing sorted-set members to add; a
+// nil add skips the value
+func (rd *rdbReader) value(typ byte, add func([]byte, float64) error) error {
+	switch typ {
+	case rdbZSet, rdbZSet2:
+		count, err := rd.length()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < count; i++ {
+			member, err := rd.str()
+			if err != nil {
+				return err
+			}
+			var score float64
+			if typ == rdbZSet2 {
+				var buf [8]byte
+				if _, err := io.ReadFull(rd.r, buf[:]); err != nil {
+					return fmt.Errorf("%w: %v", ErrRDB, err)
+				}
+				score = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+			} else if score, err = rd.score(); err != nil {
+				return err
+			}
+			if add != nil {
+				if err := add(member, score); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case rdbZSetZiplist, rdbZSetListpack:
+		blob, err := rd.str()
+		if err != nil || add == nil {
+			return err
+		}
+		entries := ziplistEntries
+		if typ == rdbZSetListpack {
+			entries = listpackEntries
+		}
+		// Entries alternate between a member and its score
+		var member []byte
+		isScore := false
+		return entries(blob, func(entry []byte) error {
+			isScore = !isScore
+			if isScore {
+				member = entry
+				return nil
+			}
+			score, err := parseScore(entry)
+			if err != nil {
+				return err
+			}
+			return add(member, score)
+		})
+	case rdbString, rdbHashZipmap, rdbListZiplist, rdbSetIntset, rdbHashZiplist, rdbHashListpack, rdbSetListpack:
+		return rd.skipStrings(1)
+	case rdbList, rdbSet, rdbHash, rdbListQuicklist:
+		count, err := rd.length()
+		if err != nil {
+			return err
+		}
+		if typ == rdbHash {
+			count *= 2
+		}
+		for i := uint64(0); i < count; i++ {
+			if _, err := rd.str(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case rdbListQuicklist2:
+		count, err := rd.length()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < count; i++ {
+			if _, err := rd.length(); err != nil {
+				return err
+			}
+			if _, err := rd.str(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: value type %d is not supported", ErrRDB, typ)
+}
+
+// parseScore parses a score written as text
+func parseScore(buf []byte) (float64, error) {
+	score, err := strconv.ParseFloat(string(buf), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: score %q", ErrRDB, buf)
+	}
+	return score, nil
+}
+
+// lzfDecompress expands an LZF-compressed string of ulen bytes
+func lzfDecompress(in []byte, ulen uint64) ([]byte, error) {
+	if ulen > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: %d-byte string", ErrRDB, ulen)
+	}
+	
+	out := make([]byte, 0, ulen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 {
+			// Literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, fmt.Errorf("%w: truncated LZF literal", ErrRDB)
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+		
+		// Back reference of n bytes
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, fmt.Errorf("%w: truncated LZF reference", ErrRDB)
+			}
+			n += int(in[i])
+			i++
+		}
+		n += 2
+		if i >= len(in) {
+			return nil, fmt.Errorf("%w: truncated LZF reference", ErrRDB)
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, fmt.Errorf("%w: LZF reference before start", ErrRDB)
+		}
+		for j := 0; j < n; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if uint64(len(out)) != ulen {
+		return nil, fmt.Errorf("%w: LZF expanded to %d bytes, want %d", ErrRDB, len(out), ulen)
+	}
+	return out, nil
+}
+
+// errPacked reports a malformed ziplist or listpack
+var errPacked = fmt.Errorf("%w: truncated ziplist or listpack", ErrRDB)
+
+// ziplistEntries calls fn with each entry of a ziplist, integers in decimal
+func ziplistEntries(zl []byte, fn func([]byte) error) error {
+	pos := 10 // zlbytes, zltail, zllen
+	for {
+		if pos >= len(zl) {
+			return errPacked
+		}
+		if zl[pos] == 0xff {
+			return nil
+		}
+		
+		// Skip the previous entry's length
+		if zl[pos] == 0xfe {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(zl) {
+			return errPacked
+		}
+		
+		e := zl[pos]
+		var entry []byte
+		var intLen int
+		switch {
+		case e>>6 == 0:
+			entry, pos = packedString(zl, pos+1, int(e&0x3f))
+		case e>>6 == 1:
+			if pos+1 >= len(zl) {
+				return errPacked
+			}
+			entry, pos = packedString(zl, pos+2, int(e&0x3f)<<8|int(zl[pos+1]))
+		case e == 0x80:
+			if pos+5 > len(zl) {
+				return errPacked
+			}
+			entry, pos = packedString(zl, pos+5, int(binary.BigEndian.Uint32(zl[pos+1:])))
+		case e == 0xc0:
+			intLen = 2
+		case e == 0xd0:
+			intLen = 4
+		case e == 0xe0:
+			intLen = 8
+		case e == 0xf0:
+			intLen = 3
+		case e == 0xfe:
+			intLen = 1
+		case e >= 0xf1 && e <= 0xfd:
+			entry = strconv.AppendInt(nil, int64(e&0x0f)-1, 10)
+			pos++
+		default:
+			return fmt.Errorf("%w: ziplist encoding %#x", ErrRDB, e)
+		}
+		if intLen > 0 {
+			if pos+1+intLen > len(zl) {
+				return errPacked
+			}
+			entry = strconv.AppendInt(nil, littleEndianInt(zl[pos+1:pos+1+intLen]), 10)
+			pos += 1 + intLen
+		}
+		if pos < 0 {
+			return errPacked
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// listpackEntries calls fn with each entry of a listpack, integers in
+// decimal
+func listpackEntries(lp []byte, fn func([]byte) error) error {
+	pos := 6 // total bytes, element count
+	for {
+		if pos >= len(lp) {
+			return errPacked
+		}
+		e := lp[pos]
+		if e == 0xff {
+			return nil
+		}
+		
+		start := pos
+		var entry []byte
+		var intLen int
+		switch {
+		case e&0x80 == 0:
+			entry = strconv.AppendInt(nil, int64(e&0x7f), 10)
+			pos++
+		case e&0xc0 == 0x80:
+			entry, pos = packedString(lp, pos+1, int(e&0x3f))
+		case e&0xe0 == 0xc0:
+			if pos+1 >= len(lp) {
+				return errPacked
+			}
+			v := int64(e&0x1f)<<8 | int64(lp[pos+1])
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+			entry = strconv.AppendInt(nil, v, 10)
+			pos += 2
+		case e&0xf0 == 0xe0:
+			if pos+1 >= len(lp) {
+				return errPacked
+			}
+			entry, pos = packedString(lp, pos+2, int(e&0x0f)<<8|int(lp[pos+1]))
+		case e == 0xf0:
+			if pos+5 > len(lp) {
+				return errPacked
+			}
+			entry, pos = packedString(lp, pos+5, int(binary.LittleEndian.Uint32(lp[pos+1:])))
+		case e == 0xf1:
+			intLen = 2
+		case e == 0xf2:
+			intLen = 3
+		case e == 0xf3:
+			intLen = 4
+		case e == 0xf4:
+			intLen = 8
+		default:
+			return fmt.Errorf("%w: listpack encoding %#x", ErrRDB, e)
+		}
+		if intLen > 0 {
+			if pos+1+intLen > len(lp) {
+				return errPacked
+			}
+			entry = strconv.AppendInt(nil, littleEndianInt(lp[pos+1:pos+1+intLen]), 10)
+			pos += 1 + intLen
+		}
+		if pos < 0 {
+			return errPacked
+		}
+		
+		// Skip the entry's back length
+		pos += backlenSize(pos - start)
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// packedString returns the n bytes at pos and the position after them, or
+// a negative position if they run past the end of buf
+func packedString(buf []byte, pos, n int) ([]byte, int) {
+	if pos+n > len(buf) {
+		return nil, -1
+	}
+	return buf[pos : pos+n], pos + n
+}
+
+// littleEndianInt decodes a signed little-endian integer of 1 to 8 bytes
+func littleEndianInt(buf []byte) int64 {
+	var v uint64
+	for i := len(buf) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(buf[i])
+	}
+	shift := 64 - 8*len(buf)
+	return int64(v<<shift) >> shift
+}
+
+// backlenSize returns the size of the back length listpack stores after an
+// entry of n bytes
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	}
+	return 5
+}
@@ -0,0 +1,118 @@
+package skiptrie
+
+import (
+	"errors"
+	"iter"
+	"math"
+)
+
+// ErrInvalidated is returned by an Iterator whose SkipTrie had its
+// contents replaced wholesale after the Iterator was created
+var ErrInvalidated = errors.New("skiptrie: iterator invalidated by a concurrent reset")
+
+// Iterator walks the keys of a range in ascending order
+// Keys inserted or deleted during the walk may or may not be seen, as with
+// the callback scans, but an iterator never mixes two generations of the
+// structure: once the contents are replaced wholesale (DeleteAll, or a reset
+// by Pool.Put) Next returns false and Err returns ErrInvalidated
+//
+// An Iterator is not safe for concurrent use
+type Iterator struct {
+	st   *SkipTrie
+	gen  uint64 // generation the iterator belongs to
+	lo   uint32
+	hi   uint32
+	pos  *Node // last node visited, or the start of the range
+	key  uint32
+	seen bool // Next has moved to a key
+	done bool
+	err  error
+}
+
+// Iter returns an Iterator over the keys in the closed range [lo, hi],
+// positioned before the first key
+func (st *SkipTrie) Iter(lo, hi uint32) *Iterator {
+	it := &Iterator{st: st, gen: st.gen.Load(), lo: lo, hi: hi, done: lo > hi}
+	it.pos = st.Predecessor(lo)
+	if it.pos == nil {
+		it.pos = st.head
+	}
+	return it
+}
+
+// Next advances to the next key and reports whether there is one
+func (it *Iterator) Next() bool {
+	defer it.st.unpin(it.st.pin())
+	
+	if it.done {
+		return false
+	}
+	
+	st := it.st
+	for curr := it.from().next[0].Load(); ; curr = curr.next[0].Load() {
+		if st.gen.Load() != it.gen {
+			it.err = ErrInvalidated
+			break
+		}
+		if curr == nil || curr == st.tail || curr.key > it.hi {
+			break
+		}
+		if curr.key < it.lo || curr.marked.Load() {
+			continue
+		}
+		it.pos, it.key, it.seen = curr, curr.key, true
+		return true
+	}
+	it.done = true
+	return false
+}
+
+// from returns the node the walk continues after: the last node visited,
+// or with WithNodeReuse, which may have recycled it since, the predecessor
+// of the next key looked up afresh
+func (it *Iterator) from() *Node {
+	st := it.st
+	if st.reclaim == nil {
+		return it.pos
+	}
+	
+	key := it.lo
+	if it.seen {
+		key = it.key + 1
+	}
+	if pred := st.Predecessor(key); pred != nil {
+		return pred
+	}
+	return st.head
+}
+
+// Key returns the key Next moved to
+func (it *Iterator) Key() uint32 {
+	return it.key
+}
+
+// Err returns ErrInvalidated if the walk stopped because the structure was
+// reset, and nil otherwise
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// All returns a sequence of all keys in ascending order, for use with
+// range; each range statement takes a fresh pass over the bottom level
+func (st *SkipTrie) All() iter.Seq[uint32] {
+	return st.Between(0, math.MaxUint32)
+}
+
+// Between returns a sequence of the keys in the closed range [lo, hi] in
+// ascending order
+// Keys changed while the loop runs may or may not be seen
+func (st *SkipTrie) Between(lo, hi uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		if lo > hi {
+			return
+		}
+		st.ascend(lo, hi, func(node *Node) bool {
+			return yield(node.key)
+		})
+	}
+}
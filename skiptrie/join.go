@@ -0,0 +1,89 @@
+package skiptrie
+
+// nextLive returns the first live node after node at the bottom level, or
+// nil at the end of the list
+func (st *SkipTrie) nextLive(node *Node) *Node {
+	for curr := node.next[0].Load(); curr != nil && curr != st.tail; curr = curr.next[0].Load() {
+		if !curr.marked.Load() {
+			return curr
+		}
+	}
+	return nil
+}
+
+// mergeJoin walks the bottom levels of a and b together in one pass and
+// calls emit for each key of either, passing nil for the side lacking it,
+// until emit returns false
+func mergeJoin(a, b *SkipTrie, emit func(key uint32, an, bn *Node) bool) {
+	defer a.unpin(a.pin())
+	defer b.unpin(b.pin())
+	
+	an, bn := a.nextLive(a.head), b.nextLive(b.head)
+	more := true
+	for more && (an != nil || bn != nil) {
+		switch {
+		case bn == nil || an != nil && an.key < bn.key:
+			more = emit(an.key, an, nil)
+			an = a.nextLive(an)
+		case an == nil || bn.key < an.key:
+			more = emit(bn.key, nil, bn)
+			bn = b.nextLive(bn)
+		default:
+			more = emit(an.key, an, bn)
+			an, bn = a.nextLive(an), b.nextLive(bn)
+		}
+	}
+}
+
+// Join calls fn in ascending key order for each key present in both a and
+// b, with the value each map holds for it
+// Both maps are read in a single merge pass; keys changed during the pass
+// may or may not be seen
+func Join[V any](a, b *SkipTrieMap[V], fn func(key uint32, av, bv V)) {
+	mergeJoin(a.st, b.st, func(key uint32, an, bn *Node) bool {
+		if an != nil && bn != nil {
+			fn(key, valueOf[V](an), valueOf[V](bn))
+		}
+		return true
+	})
+}
+
+// LeftJoin calls fn in ascending key order for each key of a; inB reports
+// whether b holds the key too, and bv is its value there or the zero value
+func LeftJoin[V any](a, b *SkipTrieMap[V], fn func(key uint32, av V, bv V, inB bool)) {
+	mergeJoin(a.st, b.st, func(key uint32, an, bn *Node) bool {
+		if an != nil {
+			fn(key, valueOf[V](an), valueOfOrZero[V](bn), bn != nil)
+		}
+		return true
+	})
+}
+
+// RightJoin calls fn in ascending key order for each key of b; inA reports
+// whether a holds the key too, and av is its value there or the zero value
+func RightJoin[V any](a, b *SkipTrieMap[V], fn func(key uint32, av V, inA bool, bv V)) {
+	mergeJoin(a.st, b.st, func(key uint32, an, bn *Node) bool {
+		if bn != nil {
+			fn(key, valueOfOrZero[V](an), an != nil, valueOf[V](bn))
+		}
+		return true
+	})
+}
+
+// OuterJoin calls fn in ascending key order for each key of a or b, with
+// the value of each map that holds it and the zero value otherwise
+func OuterJoin[V any](a, b *SkipTrieMap[V], fn func(key uint32, av V, inA bool, bv V, inB bool)) {
+	mergeJoin(a.st, b.st, func(key uint32, an, bn *Node) bool {
+		fn(key, valueOfOrZero[V](an), an != nil, valueOfOrZero[V](bn), bn != nil)
+		return true
+	})
+}
+
+// valueOfOrZero is valueOf that accepts a nil node
+func valueOfOrZero[V any](node *Node) V {
+	if node == nil {
+		var zero V
+		return zero
+	}
+	return valueOf[V](node)
+}
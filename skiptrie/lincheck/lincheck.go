@@ -0,0 +1,277 @@
+// Package lincheck records concurrent operation histories against an
+// ordered set and checks them for linearizability
+//
+// The checker is a Wing-Gong search with Lowe's memoization over a model of
+// a small ordered set: keys must be below MaxKey so that a model state fits
+// in a single uint64. Small key spaces are also what make conflicting
+// operations, and therefore races, likely
+package lincheck
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MaxKey bounds the keys the model can represent
+const MaxKey = 64
+
+// ErrNotLinearizable is returned by Check for a history that no sequential
+// execution of the model can explain
+var ErrNotLinearizable = errors.New("lincheck: history is not linearizable")
+
+// OpKind identifies a set operation
+type OpKind uint8
+
+const (
+	Insert OpKind = iota
+	Delete
+	Contains
+	Predecessor
+)
+
+// String returns the name of the operation
+func (k OpKind) String() string {
+	switch k {
+	case Insert:
+		return "Insert"
+	case Delete:
+		return "Delete"
+	case Contains:
+		return "Contains"
+	case Predecessor:
+		return "Predecessor"
+	}
+	return fmt.Sprintf("OpKind(%d)", uint8(k))
+}
+
+// Operation is one completed call in a history
+type Operation struct {
+	Kind   OpKind
+	Key    uint32
+	Ok     bool   // result of Insert/Delete/Contains, or whether a predecessor exists
+	Result uint32 // predecessor key when Kind is Predecessor and Ok is set
+	Call   int64  // logical time of invocation
+	Return int64  // logical time of response
+}
+
+// String formats the operation for failure reports
+func (op Operation) String() string {
+	if op.Kind == Predecessor && op.Ok {
+		return fmt.Sprintf("%v(%d) = %d [%d, %d]", op.Kind, op.Key, op.Result, op.Call, op.Return)
+	}
+	return fmt.Sprintf("%v(%d) = %v [%d, %d]", op.Kind, op.Key, op.Ok, op.Call, op.Return)
+}
+
+// Set is the interface under test; *skiptrie.SkipTrie satisfies it
+type Set interface {
+	Insert(key uint32) bool
+	Delete(key uint32) bool
+	Contains(key uint32) bool
+	PredecessorKey(key uint32) (uint32, bool)
+}
+
+// Recorder wraps a Set and records every call made through it
+// It is safe for concurrent use
+type Recorder struct {
+	set   Set
+	clock atomic.Int64
+	
+	mu  sync.Mutex
+	ops []Operation
+}
+
+// NewRecorder returns a Recorder forwarding to set
+func NewRecorder(set Set) *Recorder {
+	return &Recorder{set: set}
+}
+
+// record stamps and stores a completed operation
+func (r *Recorder) record(op Operation) {
+	op.Return = r.clock.Add(1)
+	
+	r.mu.Lock()
+	r.ops = append(r.ops, op)
+	r.mu.Unlock()
+}
+
+// Insert calls Insert on the underlying set and records it
+func (r *Recorder) Insert(key uint32) bool {
+	op := Operation{Kind: Insert, Key: key, Call: r.clock.Add(1)}
+	op.Ok = r.set.Insert(key)
+	r.record(op)
+	return op.Ok
+}
+
+// Delete calls Delete on the underlying set and records it
+func (r *Recorder) Delete(key uint32) bool {
+	op := Operation{Kind: Delete, Key: key, Call: r.clock.Add(1)}
+	op.Ok = r.set.Delete(key)
+	r.record(op)
+	return op.Ok
+}
+
+// Contains calls Contains on the underlying set and records it
+func (r *Recorder) Contains(key uint32) bool {
+	op := Operation{Kind: Contains, Key: key, Call: r.clock.Add(1)}
+	op.Ok = r.set.Contains(key)
+	r.record(op)
+	return op.Ok
+}
+
+// PredecessorKey calls PredecessorKey on the underlying set and records it
+func (r *Recorder) PredecessorKey(key uint32) (uint32, bool) {
+	op := Operation{Kind: Predecessor, Key: key, Call: r.clock.Add(1)}
+	op.Result, op.Ok = r.set.PredecessorKey(key)
+	r.record(op)
+	return op.Result, op.Ok
+}
+
+// History returns a copy of the operations recorded so far
+func (r *Recorder) History() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	return append([]Operation(nil), r.ops...)
+}
+
+// step applies op to the model state, reporting whether op's recorded
+// result is consistent with it
+func step(state uint64, op Operation) (uint64, bool) {
+	bit := uint64(1) << op.Key
+	present := state&bit != 0
+	
+	switch op.Kind {
+	case Insert:
+		return state | bit, op.Ok == !present
+	case Delete:
+		return state &^ bit, op.Ok == present
+	case Contains:
+		return state, op.Ok == present
+	case Predecessor:
+		below := state & (bit - 1)
+		if below == 0 {
+			return state, !op.Ok
+		}
+		return state, op.Ok && op.Result == uint32(63-bits.LeadingZeros64(below))
+	}
+	return state, false
+}
+
+// event is a call or return in the time-ordered event list
+type event struct {
+	id         int
+	call       bool
+	time       int64
+	match      *event // the return of a call
+	prev, next *event
+}
+
+// lift removes a call and its return from the list
+func lift(e *event) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+// unlift restores a call and its return removed by lift
+func unlift(e *event) {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+// Check reports whether history is linearizable with respect to an ordered
+// set that starts out empty, returning ErrNotLinearizable if it is not
+// The search is exponential in the worst case, so keep histories to a few
+// thousand operations over a handful of goroutines
+func Check(history []Operation) error {
+	for _, op := range history {
+		if op.Key >= MaxKey || op.Kind == Predecessor && op.Ok && op.Result >= MaxKey {
+			return fmt.Errorf("lincheck: key %d out of model range [0, %d)", op.Key, MaxKey)
+		}
+	}
+	
+	events := make([]*event, 0, 2*len(history))
+	for i, op := range history {
+		call := &event{id: i, call: true, time: op.Call}
+		ret := &event{id: i, time: op.Return}
+		call.match = ret
+		events = append(events, call, ret)
+	}
+	sort.Slice(events, func(a, b int) bool {
+		return events[a].time < events[b].time
+	})
+	
+	head := &event{}
+	prev := head
+	for _, e := range events {
+		prev.next = e
+		e.prev = prev
+		prev = e
+	}
+	
+	type frame struct {
+		entry *event
+		state uint64
+	}
+	var stack []frame
+	var state uint64
+	linearized := make([]byte, (len(history)+7)/8)
+	seen := make(map[string]struct{})
+	
+	entry := head.next
+	for head.next != nil {
+		if entry.call {
+			next, ok := step(state, history[entry.id])
+			if ok {
+				linearized[entry.id/8] |= 1 << (entry.id % 8)
+				key := cacheKey(linearized, next)
+				if _, dup := seen[key]; !dup {
+					seen[key] = struct{}{}
+					stack = append(stack, frame{entry: entry, state: state})
+					state = next
+					lift(entry)
+					entry = head.next
+					continue
+				}
+				linearized[entry.id/8] &^= 1 << (entry.id % 8)
+			}
+			entry = entry.next
+			continue
+		}
+		
+		// Reached a return whose call could not be linearized: backtrack
+		if len(stack) == 0 {
+			return fmt.Errorf("%w: no valid order for %v", ErrNotLinearizable, history[entry.id])
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized[top.entry.id/8] &^= 1 << (top.entry.id % 8)
+		unlift(top.entry)
+		entry = top.entry.next
+	}
+	return nil
+}
+
+// cacheKey identifies a (linearized set, model state) configuration
+func cacheKey(linearized []byte, state uint64) string {
+	key := make([]byte, len(linearized)+8)
+	copy(key, linearized)
+	for i := 0; i < 8; i++ {
+		key[len(linearized)+i] = byte(state >> (8 * i))
+	}
+	return string(key)
+}
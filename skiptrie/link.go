@@ -0,0 +1,111 @@
+package skiptrie
+
+import "sync/atomic"
+
+// link is a successor pointer packed with the mark of the node owning it
+// Links are immutable and replaced as a whole, so the mark and the pointer
+// are always read and swapped together, as in Harris's linked list
+type link struct {
+	ref
+	marked bool
+}
+
+// ref is a node pointer tagged with the stamp of the node's current use
+// With WithNodeReuse a node freed and allocated again comes back with a new
+// stamp, so a CAS expecting a ref read before the reuse fails rather than
+// succeeding on the same address in its next life (ABA)
+type ref struct {
+	node  *Node
+	stamp uint64
+}
+
+// refOf returns a ref to node as it is now; the caller must hold node, so
+// that it can't be recycled under the read
+func refOf(node *Node) ref {
+	if node == nil {
+		return ref{}
+	}
+	return ref{node: node, stamp: node.stamp}
+}
+
+// nextPtr is a node's successor at one level
+// Once marked it never changes again, so an insertion can't be linked
+// behind a node that is being unlinked and lost with it
+type nextPtr struct {
+	p atomic.Pointer[link]
+}
+
+// Load returns the successor, marked or not
+func (np *nextPtr) Load() *Node {
+	if l := np.p.Load(); l != nil {
+		return l.node
+	}
+	return nil
+}
+
+// LoadRef returns the successor with the stamp it was linked under
+func (np *nextPtr) LoadRef() ref {
+	if l := np.p.Load(); l != nil {
+		return l.ref
+	}
+	return ref{}
+}
+
+// LoadMarked returns the successor and whether the pointer is marked
+func (np *nextPtr) LoadMarked() (*Node, bool) {
+	if l := np.p.Load(); l != nil {
+		return l.node, l.marked
+	}
+	return nil, false
+}
+
+// Store sets an unmarked successor
+// It is only used on pointers no other thread can reach yet
+func (np *nextPtr) Store(node *Node) {
+	np.p.Store(&link{ref: refOf(node)})
+}
+
+// CompareAndSwap swaps the successor from old to new, failing if the
+// pointer is marked or holds another use of old's node
+func (np *nextPtr) CompareAndSwap(old, new ref) bool {
+	for {
+		l := np.p.Load()
+		if l == nil && old.node != nil || l != nil && (l.marked || l.ref != old) {
+			return false
+		}
+		if np.p.CompareAndSwap(l, &link{ref: new}) {
+			return true
+		}
+	}
+}
+
+// Set replaces the successor unless the pointer is marked
+func (np *nextPtr) Set(r ref) bool {
+	for {
+		l := np.p.Load()
+		if l != nil && l.marked {
+			return false
+		}
+		if np.p.CompareAndSwap(l, &link{ref: r}) {
+			return true
+		}
+	}
+}
+
+// Mark freezes the pointer and returns the successor it holds
+func (np *nextPtr) Mark() ref {
+	for {
+		l := np.p.Load()
+		if l != nil && l.marked {
+			return l.ref
+		}
+		
+		var r ref
+		if l != nil {
+			r = l.ref
+		}
+		if np.p.CompareAndSwap(l, &link{ref: r, marked: true}) {
+			return r
+		}
+	}
+}
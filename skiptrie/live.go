@@ -0,0 +1,42 @@
+package skiptrie
+
+// WithLivePredecessor makes Predecessor behave as PredecessorLive, at the
+// cost of a bottom-level search after every trie query
+func WithLivePredecessor() Option {
+	return func(st *SkipTrie) {
+		st.livePred = true
+	}
+}
+
+// PredecessorLive returns the node with the largest key strictly less
+// than key, or nil if there is none, like Predecessor, but only a node
+// that was live at the query's linearization point
+//
+// That point is the last load of the returned node's bottom-level
+// pointer, which found the node unmarked and linked to a successor with a
+// key of at least key: the node was then in the set and no key between it
+// and key was. Marked nodes met on the way are unlinked, and the search
+// retries if the returned node is marked before the check completes. The
+// node may still be deleted once the call returns, and with WithNodeReuse
+// recycled, as with Predecessor
+func (st *SkipTrie) PredecessorLive(key uint32) *Node {
+	defer st.unpin(st.pin())
+	
+	return st.predecessorLive(key)
+}
+
+// predecessorLive implements PredecessorLive
+func (st *SkipTrie) predecessorLive(key uint32) *Node {
+	start := st.predecessor(key, nil)
+	if start == nil || start.marked.Load() {
+		start = st.head
+	}
+	
+	// The search only returns a left node it found unmarked after
+	// confirming its link to right, which is that linearization point
+	left, _ := st.listSearch(key, start, 0)
+	if left == st.head {
+		return nil
+	}
+	return left
+}
@@ -0,0 +1,121 @@
+package skiptrie
+
+// SkipTrieMap is an ordered map from uint32 keys to values of type V
+// Values live on the skiplist nodes and are published together with them,
+// so a key is never observed without its value
+type SkipTrieMap[V any] struct {
+	st    *SkipTrie
+	codec Codec[V] // value encoding for persistence (SetCodec)
+}
+
+// NewSkipTrieMap creates a new SkipTrieMap instance
+func NewSkipTrieMap[V any](opts ...Option) *SkipTrieMap[V] {
+	return &SkipTrieMap[V]{st: NewSkipTrie(opts...)}
+}
+
+// valueOf returns the value stored on node
+func valueOf[V any](node *Node) V {
+	return unbox[V](node.value.Load())
+}
+
+// unbox returns the value in a node's value box
+func unbox[V any](boxed *any) V {
+	if boxed == nil {
+		var zero V
+		return zero
+	}
+	if d, ok := (*boxed).(deleting); ok {
+		return unbox[V](d.boxed)
+	}
+	// A nil stored for an interface V boxes as a nil any, which fails the
+	// assertion and yields the zero V, nil itself
+	v, _ := (*boxed).(V)
+	return v
+}
+
+// Get returns the value stored for key
+func (m *SkipTrieMap[V]) Get(key uint32) (V, bool) {
+	defer m.st.unpin(m.st.pin())
+	
+	node := m.st.findNode(key)
+	if node == nil {
+		var zero V
+		return zero, false
+	}
+	return valueOf[V](node), true
+}
+
+// GetOrInsert returns the existing value for key if present; otherwise it
+// inserts value. The loaded result is true if the value was already there
+func (m *SkipTrieMap[V]) GetOrInsert(key uint32, value V) (actual V, loaded bool) {
+	defer m.st.unpin(m.st.pin())
+	
+	boxed := any(value)
+	init := func(node *Node) {
+		node.value.Store(&boxed)
+	}
+	for {
+		node, inserted := m.st.insertNode(key, init)
+		if inserted {
+			return value, false
+		}
+		if !node.marked.Load() {
+			return valueOf[V](node), true
+		}
+		// The existing node is being deleted; retry
+	}
+}
+
+// Contains checks if a key exists in the map
+func (m *SkipTrieMap[V]) Contains(key uint32) bool {
+	return m.st.Contains(key)
+}
+
+// Delete deletes a key and its value from the map
+func (m *SkipTrieMap[V]) Delete(key uint32) bool {
+	return m.st.Delete(key)
+}
+
+// DeleteAll deletes every key and value from the map, as
+// SkipTrie.DeleteAll, which is not atomic
+func (m *SkipTrieMap[V]) DeleteAll() {
+	m.st.DeleteAll()
+}
+
+// Merge moves every key and value of other into m, as SkipTrie.Merge
+func (m *SkipTrieMap[V]) Merge(other *SkipTrieMap[V]) int {
+	return m.st.Merge(other.st)
+}
+
+// Update replaces the value stored for key with f applied to it and
+// returns the new value, or reports false if key is absent
+// The replacement is a CAS on the node's value, retried with a fresh call
+// to f if another Update got there first, so f may run more than once and
+// should have no side effects
+func (m *SkipTrieMap[V]) Update(key uint32, f func(old V) V) (V, bool) {
+	defer m.st.unpin(m.st.pin())
+	
+	for {
+		node := m.st.findNode(key)
+		if node == nil {
+			var zero V
+			return zero, false
+		}
+		
+		for !node.marked.Load() {
+			old := node.value.Load()
+			if claimed(old) {
+				// DeleteIf is removing the node; help it along
+				m.st.deleteNode(node)
+				break
+			}
+			value := f(unbox[V](old))
+			boxed := any(value)
+			if node.value.CompareAndSwap(old, &boxed) {
+				m.st.publishUpdate(node)
+				return value, true
+			}
+		}
+		// The node was deleted; the key may have been inserted afresh
+	}
+}
@@ -0,0 +1,31 @@
+package skiptrie
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMapNilInterface stores nil values in maps of interface types, which
+// box as a nil any
+func TestMapNilInterface(t *testing.T) {
+	m := NewSkipTrieMap[any]()
+	m.GetOrInsert(1, nil)
+	if v, ok := m.Get(1); !ok || v != nil {
+		t.Fatalf("Get(1) = %v, %v, want nil, true", v, ok)
+	}
+	if v, loaded := m.GetOrInsert(1, 5); !loaded || v != nil {
+		t.Fatalf("GetOrInsert(1, 5) = %v, %v, want nil, true", v, loaded)
+	}
+	
+	errs := NewSkipTrieMap[error]()
+	errs.GetOrInsert(2, errors.New("boom"))
+	if v, ok := errs.Update(2, func(error) error { return nil }); !ok || v != nil {
+		t.Fatalf("Update(2) = %v, %v, want nil, true", v, ok)
+	}
+	if v, ok := errs.Update(2, func(old error) error { return old }); !ok || v != nil {
+		t.Fatalf("second Update(2) = %v, %v, want nil, true", v, ok)
+	}
+	if v, ok := errs.Get(2); !ok || v != nil {
+		t.Fatalf("Get(2) = %v, %v, want nil, true", v, ok)
+	}
+}
@@ -0,0 +1,79 @@
+package skiptrie
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// MemoryStats estimates the memory held by a SkipTrie, counting the
+// structures it allocates but not the values of a SkipTrieMap, nor the
+// rounding of each allocation up to the runtime's size classes
+type MemoryStats struct {
+	Nodes         int     // nodes on the bottom level, unlinked or not
+	LevelNodes    []int   // nodes reaching each level, bottom first
+	AvgHeight     float64 // mean tower height
+	NodeBytes     uint64  // node structs
+	TowerBytes    uint64  // per-level pointers of the towers
+	PrefixSlots   int     // slots of the prefix table, used or free
+	PrefixEntries int     // trie nodes held by the prefix table
+	PrefixBytes   uint64  // prefix table slots and trie nodes
+	TotalBytes    uint64  // sum of the above, sentinels included
+}
+
+// Sizes of the structures MemoryStats counts
+const (
+	nodeSize     = uint64(unsafe.Sizeof(Node{}))
+	atomicSize   = uint64(unsafe.Sizeof(atomic.Pointer[Node]{}))
+	treeNodeSize = uint64(unsafe.Sizeof(TreeNode{}))
+	slotSize     = uint64(unsafe.Sizeof(prefixSlot{}))
+)
+
+// towerBytes returns the bytes allocated for the tower of node: per
+// level the nextPtr and its current link, and the backward pointers of
+// full-height nodes
+func (st *SkipTrie) towerBytes(node *Node) uint64 {
+	perLevel := uint64(unsafe.Sizeof(nextPtr{})) + uint64(unsafe.Sizeof(link{}))
+	n := uint64(len(node.next)) * perLevel
+	if node.prev != nil {
+		n += 2 * atomicSize
+	}
+	if node.prevBottom != nil {
+		n += atomicSize
+	}
+	return n
+}
+
+// MemoryStats walks the bottom level and the prefix table to estimate the
+// memory st holds, for capacity planning without a heap profile
+// The walk runs alongside writers, so the figures are only consistent
+// while st is quiescent
+func (st *SkipTrie) MemoryStats() MemoryStats {
+	defer st.unpin(st.pin())
+	
+	ms := MemoryStats{LevelNodes: make([]int, st.levels)}
+	
+	heights := 0
+	for node := st.head.next[0].Load(); node != nil && node != st.tail; node = node.next[0].Load() {
+		height := len(node.next)
+		ms.Nodes++
+		heights += height
+		for level := range min(height, st.levels) {
+			ms.LevelNodes[level]++
+		}
+		ms.NodeBytes += nodeSize
+		ms.TowerBytes += st.towerBytes(node)
+	}
+	if ms.Nodes > 0 {
+		ms.AvgHeight = float64(heights) / float64(ms.Nodes)
+	}
+	
+	if s := st.prefixes.slots.Load(); s != nil {
+		ms.PrefixSlots = len(s.slot)
+	}
+	ms.PrefixEntries = int(st.entries.Load())
+	ms.PrefixBytes = uint64(ms.PrefixSlots)*slotSize + uint64(ms.PrefixEntries)*treeNodeSize
+	
+	sentinels := 2*nodeSize + st.towerBytes(st.head) + st.towerBytes(st.tail)
+	ms.TotalBytes = ms.NodeBytes + ms.TowerBytes + ms.PrefixBytes + sentinels
+	return ms
+}
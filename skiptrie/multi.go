@@ -0,0 +1,135 @@
+package skiptrie
+
+import "sync/atomic"
+
+// SkipTrieMulti is an ordered multiset of uint32 keys: inserting a present
+// key increments its count and deleting one decrements it, the key leaving
+// the set when its count reaches zero
+// Each key is stored once, with its count on the node, so ordered queries
+// on SkipTrie see distinct keys
+type SkipTrieMulti struct {
+	st    *SkipTrie
+	total atomic.Int64 // keys counted with multiplicity
+}
+
+// NewSkipTrieMulti creates a new SkipTrieMulti instance
+func NewSkipTrieMulti(opts ...Option) *SkipTrieMulti {
+	return &SkipTrieMulti{st: NewSkipTrie(opts...)}
+}
+
+// SkipTrie returns the underlying set of distinct keys, for ordered
+// queries; updating it directly bypasses the counts
+func (m *SkipTrieMulti) SkipTrie() *SkipTrie {
+	return m.st
+}
+
+// countOf returns the counter stored on node
+// A count of zero marks a node whose last copy was deleted and which is
+// being removed, so that increments cannot revive it
+func countOf(node *Node) *atomic.Int64 {
+	return (*node.value.Load()).(*atomic.Int64)
+}
+
+// Insert adds a copy of key and returns its new count
+func (m *SkipTrieMulti) Insert(key uint32) int {
+	defer m.st.unpin(m.st.pin())
+	
+	init := func(node *Node) {
+		boxed := any(new(atomic.Int64))
+		boxed.(*atomic.Int64).Store(1)
+		node.value.Store(&boxed)
+	}
+	for {
+		node, inserted := m.st.insertNode(key, init)
+		if inserted {
+			m.total.Add(1)
+			return 1
+		}
+		
+		c := countOf(node)
+		for {
+			n := c.Load()
+			if n == 0 {
+				// Being removed; help the removal along and insert afresh
+				m.st.deleteNode(node)
+				break
+			}
+			if c.CompareAndSwap(n, n+1) {
+				m.total.Add(1)
+				return int(n + 1)
+			}
+		}
+	}
+}
+
+// Delete removes one copy of key, reporting whether there was one
+func (m *SkipTrieMulti) Delete(key uint32) bool {
+	defer m.st.unpin(m.st.pin())
+	
+	return m.remove(key, false) > 0
+}
+
+// DeleteAll removes every copy of key and returns how many there were
+func (m *SkipTrieMulti) DeleteAll(key uint32) int {
+	defer m.st.unpin(m.st.pin())
+	
+	return m.remove(key, true)
+}
+
+// remove takes one copy of key, or all of them, and returns the number
+// taken
+func (m *SkipTrieMulti) remove(key uint32, all bool) int {
+	for {
+		node := m.st.findNode(key)
+		if node == nil {
+			return 0
+		}
+		
+		c := countOf(node)
+		for {
+			n := c.Load()
+			if n == 0 {
+				// Another caller took the last copy; a new node may follow
+				m.st.deleteNode(node)
+				break
+			}
+			taken := int64(1)
+			if all {
+				taken = n
+			}
+			if !c.CompareAndSwap(n, n-taken) {
+				continue
+			}
+			if n == taken {
+				m.st.deleteNode(node)
+			}
+			m.total.Add(-taken)
+			return int(taken)
+		}
+	}
+}
+
+// Count returns the number of copies of key
+func (m *SkipTrieMulti) Count(key uint32) int {
+	defer m.st.unpin(m.st.pin())
+	
+	if node := m.st.findNode(key); node != nil {
+		return int(countOf(node).Load())
+	}
+	return 0
+}
+
+// Contains checks if at least one copy of key is present
+func (m *SkipTrieMulti) Contains(key uint32) bool {
+	return m.Count(key) > 0
+}
+
+// Len returns the number of keys counted with multiplicity
+func (m *SkipTrieMulti) Len() int {
+	return int(m.total.Load())
+}
+
+// Distinct returns the number of distinct keys
+func (m *SkipTrieMulti) Distinct() int {
+	return m.st.Len()
+}
@@ -0,0 +1,14 @@
+package skiptrie
+
+// Key returns the key the node holds
+// With WithNodeReuse a node returned by Predecessor may already hold
+// another key; PredecessorKey reads the key safely
+func (n *Node) Key() uint32 {
+	return n.key
+}
+
+// Valid checks if the node is non-nil and its key has not been deleted
+// The answer can change as soon as it is returned
+func (n *Node) Valid() bool {
+	return n != nil && !n.marked.Load()
+}
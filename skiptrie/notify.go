@@ -0,0 +1,38 @@
+package skiptrie
+
+import "context"
+
+// OnInsert calls fn with each key in [lo, hi] that is successfully
+// inserted, and returns a function that ends the subscription
+// fn runs on a background goroutine, one call at a time and in changelog
+// order, so it may lag the insert that triggered it; a call in progress
+// may still finish after stop returns
+func (st *SkipTrie) OnInsert(lo, hi uint32, fn func(key uint32)) (stop func()) {
+	return st.onEvent(lo, hi, EventInsert, fn)
+}
+
+// OnDelete calls fn with each key in [lo, hi] that is successfully
+// deleted, and returns a function that ends the subscription
+// fn runs as it does for OnInsert
+func (st *SkipTrie) OnDelete(lo, hi uint32, fn func(key uint32)) (stop func()) {
+	return st.onEvent(lo, hi, EventDelete, fn)
+}
+
+// onEvent runs fn for the events of kind op on a Watch of [lo, hi]
+func (st *SkipTrie) onEvent(lo, hi uint32, op EventOp, fn func(key uint32)) func() {
+	w, err := st.Watch(lo, hi)
+	if err != nil {
+		// Only WithSince makes Watch fail
+		panic(err)
+	}
+	
+	st.goBackground(func(ctx context.Context) error {
+		for ev := range w.C {
+			if ev.Op == op {
+				fn(ev.Key)
+			}
+		}
+		return nil
+	})
+	return w.Close
+}
@@ -0,0 +1,131 @@
+package skiptrie
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// opCounters counts the work of individual operations for WithOpStats
+type opCounters struct {
+	inserts          atomic.Uint64
+	deletes          atomic.Uint64
+	casRetries       atomic.Uint64
+	searchIterations atomic.Uint64
+	trieHits         atomic.Uint64
+	trieMisses       atomic.Uint64
+}
+
+// OpStats holds the per-operation counters kept with WithOpStats
+type OpStats struct {
+	Inserts          uint64 // keys inserted
+	Deletes          uint64 // keys deleted, including evictions and expiry
+	CASRetries       uint64 // failed pointer CASes that forced a retry
+	SearchIterations uint64 // listSearch passes, restarts included
+	TrieHits         uint64 // prefix table lookups that found an entry
+	TrieMisses       uint64 // prefix table lookups that found none
+}
+
+// WithOpStats counts inserts, deletes, CAS retries, search passes and
+// prefix table hits and misses, reported in Stats().Ops
+// The counters are shared atomics, so they add some contention of their
+// own on many-core machines; CASRetries and SearchIterations growing
+// faster than Inserts and Deletes is the sign of contention to watch for
+func WithOpStats() Option {
+	return func(st *SkipTrie) {
+		st.ops = &opCounters{}
+	}
+}
+
+// countCASRetry records a failed CAS that sends an operation round again
+func (st *SkipTrie) countCASRetry() {
+	if st.ops != nil {
+		st.ops.casRetries.Add(1)
+	}
+}
+
+// opStats returns a snapshot of the per-operation counters
+func (st *SkipTrie) opStats() OpStats {
+	if st.ops == nil {
+		return OpStats{}
+	}
+	return OpStats{
+		Inserts:          st.ops.inserts.Load(),
+		Deletes:          st.ops.deletes.Load(),
+		CASRetries:       st.ops.casRetries.Load(),
+		SearchIterations: st.ops.searchIterations.Load(),
+		TrieHits:         st.ops.trieHits.Load(),
+		TrieMisses:       st.ops.trieMisses.Load(),
+	}
+}
+
+// Expvar returns an expvar.Var rendering Stats as JSON, for publishing
+// with expvar.Publish
+func (st *SkipTrie) Expvar() expvar.Var {
+	return expvar.Func(func() any {
+		return st.Stats()
+	})
+}
+
+// WritePrometheus writes Stats in the Prometheus text exposition format,
+// naming each metric prefix_<name>, so it can be served from a metrics
+// handler or appended to the output of a collector
+func (st *SkipTrie) WritePrometheus(w io.Writer, prefix string) error {
+	stats := st.Stats()
+	pw := &promWriter{w: w, prefix: prefix}
+	
+	pw.metric("len", "gauge", "Live keys.", float64(stats.Len))
+	pw.metric("capacity", "gauge", "Key limit, 0 if unbounded.", float64(stats.Capacity))
+	pw.metric("evictions_total", "counter", "Keys removed to respect the capacity.", float64(stats.Evictions))
+	pw.header("class_len", "gauge", "Live keys per priority class.")
+	for class, ps := range stats.ByPriority {
+		pw.sample("class_len", fmt.Sprintf(`{class="%d"}`, class), float64(ps.Len))
+	}
+	pw.header("class_evictions_total", "counter", "Evictions per priority class.")
+	for class, ps := range stats.ByPriority {
+		pw.sample("class_evictions_total", fmt.Sprintf(`{class="%d"}`, class), float64(ps.Evictions))
+	}
+	
+	pw.metric("expire_running", "gauge", "ExpireWhere passes in progress.", float64(stats.ExpireRunning))
+	pw.metric("expire_scanned_total", "counter", "Keys tested by ExpireWhere predicates.", float64(stats.ExpireScanned))
+	pw.metric("expire_deleted_total", "counter", "Keys deleted by ExpireWhere.", float64(stats.ExpireDeleted))
+	pw.metric("analyzed_ops_total", "counter", "Queries checked against the cost model.", float64(stats.AnalyzedOps))
+	pw.metric("flagged_ops_total", "counter", "Queries that exceeded the cost model.", float64(stats.FlaggedOps))
+	pw.metric("fallbacks_total", "counter", "Fallback paths taken.", float64(stats.Fallbacks))
+	
+	pw.metric("inserts_total", "counter", "Keys inserted.", float64(stats.Ops.Inserts))
+	pw.metric("deletes_total", "counter", "Keys deleted.", float64(stats.Ops.Deletes))
+	pw.metric("cas_retries_total", "counter", "Failed pointer CASes that forced a retry.", float64(stats.Ops.CASRetries))
+	pw.metric("search_iterations_total", "counter", "Skiplist search passes.", float64(stats.Ops.SearchIterations))
+	pw.metric("trie_hits_total", "counter", "Prefix table lookups that found an entry.", float64(stats.Ops.TrieHits))
+	pw.metric("trie_misses_total", "counter", "Prefix table lookups that found none.", float64(stats.Ops.TrieMisses))
+	return pw.err
+}
+
+// promWriter writes Prometheus text format, keeping the first write error
+type promWriter struct {
+	w      io.Writer
+	prefix string
+	err    error
+}
+
+// header writes the HELP and TYPE lines of a metric
+func (pw *promWriter) header(name, typ, help string) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", pw.prefix, name, help, pw.prefix, name, typ)
+	}
+}
+
+// sample writes one sample line; labels is empty or a {...} label set
+func (pw *promWriter) sample(name, labels string, v float64) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, "%s_%s%s %v\n", pw.prefix, name, labels, v)
+	}
+}
+
+// metric writes an unlabelled metric
+func (pw *promWriter) metric(name, typ, help string, v float64) {
+	pw.header(name, typ, help)
+	pw.sample(name, "", v)
+}
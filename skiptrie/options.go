@@ -0,0 +1,109 @@
+package skiptrie
+
+import (
+	cryptorand "crypto/rand"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Option configures a SkipTrie at construction time
+type Option func(*SkipTrie)
+
+// WithReverseLinks maintains backward hints at the bottom level so that
+// reverse scans step to the previous key in O(1) expected time instead of
+// issuing a full predecessor query per key
+//
+// The cost is one extra atomic pointer per node (a pointer field plus an
+// 8-byte allocation, 16 bytes in total) and one CAS on the successor's hint
+// for every insert and delete
+func WithReverseLinks() Option {
+	return func(st *SkipTrie) {
+		st.reverseLinks = true
+	}
+}
+
+// WithRandSource draws tower heights from src instead of a randomly seeded
+// source, so that a run can be reproduced exactly from its seed
+// Access to src is serialized by the SkipTrie
+func WithRandSource(src rand.Source) Option {
+	return func(st *SkipTrie) {
+		st.rng = rand.New(src)
+	}
+}
+
+// WithCryptoSeed draws tower heights from a ChaCha8 generator keyed from
+// crypto/rand, for deployments where an adversary able to predict tower
+// heights could degrade the structure
+// By default heights come from a PCG seeded by the runtime-seeded global
+// generator, which is fast but not designed to resist prediction
+func WithCryptoSeed() Option {
+	return func(st *SkipTrie) {
+		var seed [32]byte
+		if _, err := cryptorand.Read(seed[:]); err != nil {
+			panic("skiptrie: reading crypto seed: " + err.Error())
+		}
+		st.rng = rand.New(rand.NewChaCha8(seed))
+	}
+}
+
+// WithHeightFunc replaces random tower heights with f, whose result is
+// clamped to [1, LogLogU] or the WithMaxHeight bound; returning LogLogU (or
+// the log log u of WithUniverse) or more forces a top-level node that is
+// published in the x-fast trie, unless WithBuckets leaves it out
+// f may be called concurrently from inserting goroutines
+func WithHeightFunc(f func(key uint32) int) Option {
+	return func(st *SkipTrie) {
+		st.heightFn = f
+	}
+}
+
+// WithMaxHeight lets towers grow to h levels, clamped to [LogLogU,
+// MaxHeight], instead of stopping at LogLogU (or the log log u of
+// WithUniverse, which is then the lower bound)
+// With many millions of keys the five levels indexed by the trie leave
+// long walks between top-level nodes; the extra levels act as express lanes
+// above them. Only the bottom LogLogU levels take part in the trie: every
+// node at least LogLogU tall is eligible for publication, whatever its
+// height, and searches starting from a trie node use the rest of its tower
+func WithMaxHeight(h int) Option {
+	return func(st *SkipTrie) {
+		st.levels = min(max(h, 1), MaxHeight) // raised to the trie levels by NewSkipTrie
+	}
+}
+
+// WithHardenedHeights derives each key's tower height from a SipHash of the
+// key keyed with secret, instead of from a random generator
+//
+// Threat model: an adversary who chooses the inserted keys and can predict
+// tower heights (e.g. by reconstructing a weakly seeded generator) can feed
+// keys that only ever get short towers, degrading searches to linear scans.
+// With keyed heights the height of a key is a pseudo-random function of a
+// secret the adversary does not know, and is independent of insertion
+// order. Because a key's height is fixed, keep secret private and rotate it
+// (by rebuilding the instance) if per-key timing can leak heights
+func WithHardenedHeights(secret [16]byte) Option {
+	return func(st *SkipTrie) {
+		st.heightFn = func(key uint32) int {
+			// Trailing zeros are geometric with p = 1/2, like the coin flips
+			return 1 + bits.TrailingZeros64(sipHash32(&secret, key))
+		}
+	}
+}
+
+// WithSeededPrefixes hashes the prefixes of the x-fast trie into their
+// table with a SipHash keyed with secret, instead of a fixed
+// multiplicative hash
+//
+// Threat model: an adversary who chooses the inserted keys can pick them
+// so that their prefixes land in one run of slots of the unkeyed table,
+// turning every trie lookup into a long probe sequence. Keyed, the slot of
+// a prefix is a pseudo-random function of a secret the adversary does not
+// know, different for each instance given its own secret (e.g. from
+// crypto/rand). Only the table layout changes: keys keep their order and
+// queries their results. Combine with WithHardenedHeights to also keep
+// towers out of the adversary's reach
+func WithSeededPrefixes(secret [16]byte) Option {
+	return func(st *SkipTrie) {
+		st.prefixes.secret = &secret
+	}
+}
@@ -0,0 +1,163 @@
+package skiptrie
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// orderStats counts the live keys in each 1/65536th of the key space and
+// in each 1/256th, for Select
+// Counts kept on the skiplist links themselves, as in an indexable
+// skiplist, would have to change together with the links they describe,
+// which a single-word CAS cannot do; counts by key range only change with
+// the key set and are exact whenever no update is in flight
+type orderStats struct {
+	coarse [1 << 8]atomic.Int64  // keys per top byte
+	fine   [1 << 16]atomic.Int32 // keys per high half
+}
+
+// WithOrderStatistics keeps counts of the keys by key range, so that
+// Select, Rank and Count skip to the stretch of keys holding the answer
+// instead of walking from the smallest key
+// The counts take about 260KB and two atomic adds per insert and delete
+func WithOrderStatistics() Option {
+	return func(st *SkipTrie) {
+		st.order = &orderStats{}
+	}
+}
+
+// add counts a change of delta keys at key
+func (o *orderStats) add(key uint32, delta int32) {
+	o.fine[key>>16].Add(delta)
+	o.coarse[key>>24].Add(int64(delta))
+}
+
+// reset zeroes the counts
+func (o *orderStats) reset() {
+	for i := range o.coarse {
+		o.coarse[i].Store(0)
+	}
+	for i := range o.fine {
+		o.fine[i].Store(0)
+	}
+}
+
+// locate returns the smallest key of the high half holding the k-th
+// smallest key, and how many keys before it to skip there; ok is false
+// if the counts hold k keys or fewer
+func (o *orderStats) locate(k int64) (from uint32, skip int64, ok bool) {
+	top := 0
+	for ; top < len(o.coarse); top++ {
+		n := o.coarse[top].Load()
+		if k < n {
+			break
+		}
+		k -= n
+	}
+	if top == len(o.coarse) {
+		return 0, 0, false
+	}
+	
+	high := top << 8
+	for end := high + 1<<8 - 1; high < end; high++ {
+		n := int64(o.fine[high].Load())
+		if k < n {
+			break
+		}
+		k -= n
+	}
+	return uint32(high) << 16, k, true
+}
+
+// before returns the number of keys below the high half starting at from,
+// whose low 16 bits must be zero, summing at most 256 coarse and 255 fine
+// counts
+func (o *orderStats) before(from uint32) int64 {
+	var n int64
+	top := int(from >> 24)
+	for i := 0; i < top; i++ {
+		n += o.coarse[i].Load()
+	}
+	for high := top << 8; high < int(from>>16); high++ {
+		n += int64(o.fine[high].Load())
+	}
+	return n
+}
+
+// Select returns the k-th smallest key, counting from 0, or false if there
+// are k keys or fewer
+//
+// With WithOrderStatistics the counts lead to the stretch of 65536 key
+// values holding the answer, which is then walked; otherwise the walk
+// starts from the smallest key, so it takes O(k) steps. Under concurrent
+// updates the answer reflects the counts and the keys as they were read
+func (st *SkipTrie) Select(k int) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	if k < 0 {
+		return 0, false
+	}
+	
+	from, skip := uint32(0), int64(k)
+	if st.order != nil {
+		var ok bool
+		if from, skip, ok = st.order.locate(int64(k)); !ok {
+			return 0, false
+		}
+	}
+	
+	var found uint32
+	ok := false
+	st.ascend(from, math.MaxUint32, func(node *Node) bool {
+		if skip == 0 {
+			found, ok = node.key, true
+			return false
+		}
+		skip--
+		return true
+	})
+	return found, ok
+}
+
+// splitSamples is the number of samples per partition SplitPoints looks
+// for before it settles on a level
+const splitSamples = 8
+
+// SplitPoints returns up to n-1 ascending keys that cut the key set into
+// n partitions of about equal size, for spreading a scan over workers:
+// the partitions are [0, p[0]), [p[0], p[1]), ..., [p[n-2], MaxUint32]
+//
+// The keys are sampled from the highest skiplist level holding at least
+// splitSamples keys per partition, where each key stands for about 2^level
+// keys below it; with the default heights the top level is enough for n up
+// to Len()/128, so the cost is a walk of about Len()/16 nodes. Fewer points
+// are returned when there are too few keys to cut, and none for n < 2
+func (st *SkipTrie) SplitPoints(n int) []uint32 {
+	defer st.unpin(st.pin())
+	
+	if n < 2 {
+		return nil
+	}
+	
+	var samples []uint32
+	for level := st.levels - 1; level >= 0; level-- {
+		samples = samples[:0]
+		for curr := st.head.next[level].Load(); curr != nil && curr != st.tail; curr = curr.next[level].Load() {
+			if !curr.marked.Load() {
+				samples = append(samples, curr.key)
+			}
+		}
+		if len(samples) >= splitSamples*n {
+			break
+		}
+	}
+	
+	var points []uint32
+	for i := 1; i < n && len(samples) > 0; i++ {
+		key := samples[i*len(samples)/n]
+		if len(points) == 0 || key > points[len(points)-1] {
+			points = append(points, key)
+		}
+	}
+	return points
+}
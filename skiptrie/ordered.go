@@ -0,0 +1,187 @@
+package skiptrie
+
+import "math"
+
+// ceilingNode returns the live node with the smallest key greater than or
+// equal to key, or nil if there is none
+func (st *SkipTrie) ceilingNode(key uint32) *Node {
+	var found *Node
+	st.ascend(key, math.MaxUint32, func(node *Node) bool {
+		found = node
+		return false
+	})
+	return found
+}
+
+// PredecessorKey returns the largest key strictly less than key
+func (st *SkipTrie) PredecessorKey(key uint32) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	if key == 0 {
+		return 0, false
+	}
+	
+	node := st.floorNode(key - 1)
+	if node == nil {
+		return 0, false
+	}
+	return node.key, true
+}
+
+// SuccessorKey returns the smallest key strictly greater than key
+func (st *SkipTrie) SuccessorKey(key uint32) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	if key == math.MaxUint32 {
+		return 0, false
+	}
+	
+	node := st.ceilingNode(key + 1)
+	if node == nil {
+		return 0, false
+	}
+	return node.key, true
+}
+
+// Floor returns the largest key less than or equal to key
+func (st *SkipTrie) Floor(key uint32) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	node := st.floorNode(key)
+	if node == nil {
+		return 0, false
+	}
+	return node.key, true
+}
+
+// Ceiling returns the smallest key greater than or equal to key
+func (st *SkipTrie) Ceiling(key uint32) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	node := st.ceilingNode(key)
+	if node == nil {
+		return 0, false
+	}
+	return node.key, true
+}
+
+// Nearest returns the key numerically closest to key, which is key itself
+// if present; on a tie between the keys either side the smaller one wins
+func (st *SkipTrie) Nearest(key uint32) (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	floor, ceil := st.floorNode(key), st.ceilingNode(key)
+	switch {
+	case floor == nil && ceil == nil:
+		return 0, false
+	case ceil == nil:
+		return floor.key, true
+	case floor == nil:
+		return ceil.key, true
+	}
+	
+	if st.distance(key, ceil.key) < st.distance(key, floor.key) {
+		return ceil.key, true
+	}
+	return floor.key, true
+}
+
+// LocateFlags reports which results of Locate are present
+type LocateFlags uint8
+
+const (
+	LocatePred  LocateFlags = 1 << iota // a key below the query exists
+	LocateExact                         // the query key itself exists
+	LocateSucc                          // a key above the query exists
+)
+
+// Locate returns the keys bracketing key, the largest below it and the
+// smallest above it, and whether key itself is present, in one traversal
+// instead of separate Contains, PredecessorKey and SuccessorKey calls
+// pred, exact and succ are only meaningful if flags has LocatePred,
+// LocateExact and LocateSucc set; exact is then key. As for the other
+// ordered queries, an expired key not yet deleted still counts
+func (st *SkipTrie) Locate(key uint32) (pred, exact, succ uint32, flags LocateFlags) {
+	defer st.unpin(st.pin())
+	
+	from := st.head
+	if key > 0 {
+		if node := st.floorNode(key - 1); node != nil {
+			pred, flags = node.key, LocatePred
+			from = node
+		}
+	}
+	
+	// The frozen pointers of a node deleted meanwhile still lead forward
+	next := st.nextLive(from)
+	if next != nil && next.key == key {
+		exact, flags = key, flags|LocateExact
+		next = st.nextLive(next)
+	}
+	if next != nil {
+		succ, flags = next.key, flags|LocateSucc
+	}
+	return pred, exact, succ, flags
+}
+
+// First returns the smallest key in the SkipTrie
+func (st *SkipTrie) First() (uint32, bool) {
+	return st.Ceiling(0)
+}
+
+// Last returns the largest key in the SkipTrie
+func (st *SkipTrie) Last() (uint32, bool) {
+	return st.Floor(math.MaxUint32)
+}
+
+// PopMin deletes and returns the smallest key, so that the SkipTrie can
+// serve as a concurrent priority queue
+// Each key is returned by exactly one of any concurrent PopMin, PopMax and
+// Delete calls; a caller that loses the race for the minimum moves on to
+// the next live key
+func (st *SkipTrie) PopMin() (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	for {
+		node := st.ceilingNode(0)
+		if node == nil {
+			return 0, false
+		}
+		if st.deleteNode(node) {
+			return node.key, true
+		}
+	}
+}
+
+// PopMax deletes and returns the largest key, finding it through the
+// x-fast trie in O(log log u) expected time
+// Concurrent calls behave as for PopMin
+func (st *SkipTrie) PopMax() (uint32, bool) {
+	defer st.unpin(st.pin())
+	
+	for {
+		node := st.floorNode(math.MaxUint32)
+		if node == nil {
+			return 0, false
+		}
+		if st.deleteNode(node) {
+			return node.key, true
+		}
+	}
+}
+
+// Keys returns the keys in ascending order
+func (st *SkipTrie) Keys() []uint32 {
+	return st.AppendKeys(make([]uint32, 0, st.Len()))
+}
+
+// AppendKeys appends the keys in ascending order to dst, reading the bottom
+// level in a single pass, and returns the extended slice
+// Keys changed during the pass may or may not be included
+func (st *SkipTrie) AppendKeys(dst []uint32) []uint32 {
+	st.ascend(0, math.MaxUint32, func(node *Node) bool {
+		dst = append(dst, node.key)
+		return true
+	})
+	return dst
+}
@@ -0,0 +1,143 @@
+package skiptrie
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// OrderedSet is the contract of a set of keys kept in order
+// SkipTrie and ShardedSkipTrie implement OrderedSet[uint32]; LockedSet
+// implements it for any ordered key type
+type OrderedSet[K cmp.Ordered] interface {
+	Insert(key K) bool                   // adds key, reporting whether it was absent
+	Delete(key K) bool                   // removes key, reporting whether it was present
+	Contains(key K) bool                 // reports whether key is present
+	PredecessorKey(key K) (K, bool)      // largest key strictly less than key
+	SuccessorKey(key K) (K, bool)        // smallest key strictly greater than key
+	Range(lo, hi K, fn func(key K) bool) // ascending over [lo, hi] until fn returns false
+	Len() int                            // number of keys
+}
+
+var (
+	_ OrderedSet[uint32] = (*SkipTrie)(nil)
+	_ OrderedSet[uint32] = (*ShardedSkipTrie)(nil)
+	_ OrderedSet[uint32] = (*LockedSet[uint32])(nil)
+)
+
+// Range calls fn for each key in [lo, hi] in ascending order until fn
+// returns false
+// Keys changed during the walk may or may not be seen
+func (st *SkipTrie) Range(lo, hi uint32, fn func(key uint32) bool) {
+	if lo > hi {
+		return
+	}
+	st.ascend(lo, hi, func(node *Node) bool {
+		return fn(node.key)
+	})
+}
+
+// Range is Ascend, for OrderedSet
+func (s *ShardedSkipTrie) Range(lo, hi uint32, fn func(key uint32) bool) {
+	s.Ascend(lo, hi, fn)
+}
+
+// LockedSet is a sorted slice behind a read-write mutex: the reference an
+// OrderedSet can be checked against in differential tests, and a simple
+// drop-in for sets of a few thousand keys, where it is hard to beat
+// Inserts and deletes move the tail of the slice, so they cost O(n)
+type LockedSet[K cmp.Ordered] struct {
+	mu   sync.RWMutex
+	keys []K
+}
+
+// NewLockedSet creates an empty LockedSet
+func NewLockedSet[K cmp.Ordered]() *LockedSet[K] {
+	return &LockedSet[K]{}
+}
+
+// Insert implements OrderedSet
+func (s *LockedSet[K]) Insert(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	i, found := slices.BinarySearch(s.keys, key)
+	if found {
+		return false
+	}
+	s.keys = slices.Insert(s.keys, i, key)
+	return true
+}
+
+// Delete implements OrderedSet
+func (s *LockedSet[K]) Delete(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	i, found := slices.BinarySearch(s.keys, key)
+	if !found {
+		return false
+	}
+	s.keys = slices.Delete(s.keys, i, i+1)
+	return true
+}
+
+// Contains implements OrderedSet
+func (s *LockedSet[K]) Contains(key K) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	_, found := slices.BinarySearch(s.keys, key)
+	return found
+}
+
+// PredecessorKey implements OrderedSet
+func (s *LockedSet[K]) PredecessorKey(key K) (K, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	i, _ := slices.BinarySearch(s.keys, key)
+	if i == 0 {
+		var zero K
+		return zero, false
+	}
+	return s.keys[i-1], true
+}
+
+// SuccessorKey implements OrderedSet
+func (s *LockedSet[K]) SuccessorKey(key K) (K, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	i, found := slices.BinarySearch(s.keys, key)
+	if found {
+		i++
+	}
+	if i == len(s.keys) {
+		var zero K
+		return zero, false
+	}
+	return s.keys[i], true
+}
+
+// Range implements OrderedSet
+// fn runs under the read lock, so it must not modify the set
+func (s *LockedSet[K]) Range(lo, hi K, fn func(key K) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	i, _ := slices.BinarySearch(s.keys, lo)
+	for ; i < len(s.keys) && s.keys[i] <= hi; i++ {
+		if !fn(s.keys[i]) {
+			return
+		}
+	}
+}
+
+// Len implements OrderedSet
+func (s *LockedSet[K]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	return len(s.keys)
+}
@@ -0,0 +1,35 @@
+package skiptrie
+
+// PausePoint names a step inside an operation where, with the testhooks
+// build tag, TestHooks.Pause is called so that a scheduler can hold the
+// operation there and force an interleaving with others
+// Without the tag the points compile away
+type PausePoint uint8
+
+const (
+	PauseInsertSearched PausePoint = iota + 1 // insert found a level's neighbours and is about to link there
+	PauseInsertLinked                         // insert linked the bottom level and has not raised the tower
+	PauseTrieInsert                           // insert checked its top-level node is live and is about to store it in the trie
+	PauseFixPrev                              // fixPrev read a prev pointer and is about to replace it
+	PauseDeleteMarked                         // delete marked the node and has not unlinked any level
+	PauseTrieDelete                           // delete unlinked the node and is about to remove it from the trie
+)
+
+// String returns the name of the point
+func (p PausePoint) String() string {
+	switch p {
+	case PauseInsertSearched:
+		return "insert-searched"
+	case PauseInsertLinked:
+		return "insert-linked"
+	case PauseTrieInsert:
+		return "trie-insert"
+	case PauseFixPrev:
+		return "fix-prev"
+	case PauseDeleteMarked:
+		return "delete-marked"
+	case PauseTrieDelete:
+		return "trie-delete"
+	}
+	return "unknown"
+}
@@ -0,0 +1,383 @@
+package skiptrie
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// ErrCorrupt is returned by Recover for a snapshot or log that fails its
+// checksums or does not parse
+var ErrCorrupt = errors.New("skiptrie: corrupt snapshot or log")
+
+// snapshotMagic starts every snapshot, followed by the format version
+const snapshotMagic = "SKTS"
+
+// Snapshot format versions
+const (
+	snapshotKeys   byte = 1 // keys only
+	snapshotValues byte = 2 // each key followed by its encoded value
+)
+
+// walRecordSize is the size of a log record: op, sequence number, key and
+// CRC-32 of the preceding bytes
+const walRecordSize = 1 + 8 + 4 + 4
+
+// walValue flags the op of a record that carries an encoded value, as a
+// uvarint length and the bytes, between the key and the CRC-32
+const walValue = 0x80
+
+// valueDecoder turns an encoded value back into the boxed value of a node
+type valueDecoder func(data []byte) (any, error)
+
+// WithWAL appends every successful insert and delete to w as a fixed-size
+// record, so that Recover can replay the changes made after a Checkpoint
+// Records are written in changelog order under the changelog mutex, so w
+// should be buffered; flushing and syncing it is up to the caller and
+// decides how much a crash can lose. The first write error stops the log
+// and is reported by WALErr
+func WithWAL(w io.Writer) Option {
+	return func(st *SkipTrie) {
+		st.changes.wal = w
+		st.changes.active.Store(true)
+	}
+}
+
+// WALErr returns the error that stopped the write-ahead log, if any
+func (st *SkipTrie) WALErr() error {
+	st.changes.mu.Lock()
+	defer st.changes.mu.Unlock()
+	return st.changes.walErr
+}
+
+// logRecord writes ev, which changed node, to the write-ahead log, with
+// the node's value if a codec is registered; l.mu must be held
+func (l *changeLog) logRecord(ev Event, node *Node) {
+	if l.wal == nil || l.walErr != nil {
+		return
+	}
+	
+	if l.encode == nil || ev.Op == EventDelete {
+		rec := walRecord(ev)
+		_, l.walErr = l.wal.Write(rec[:])
+		return
+	}
+	value, err := l.encode(node)
+	if err == nil {
+		_, err = l.wal.Write(walValueRecord(ev, value))
+	}
+	l.walErr = err
+}
+
+// walRecord encodes ev as a log record
+func walRecord(ev Event) [walRecordSize]byte {
+	var rec [walRecordSize]byte
+	rec[0] = byte(ev.Op)
+	binary.BigEndian.PutUint64(rec[1:], ev.Seq)
+	binary.BigEndian.PutUint32(rec[9:], ev.Key)
+	binary.BigEndian.PutUint32(rec[13:], crc32.ChecksumIEEE(rec[:13]))
+	return rec
+}
+
+// walValueRecord encodes ev as a log record carrying value
+func walValueRecord(ev Event, value []byte) []byte {
+	rec := make([]byte, 0, walRecordSize+binary.MaxVarintLen64+len(value))
+	rec = append(rec, byte(ev.Op)|walValue)
+	rec = binary.BigEndian.AppendUint64(rec, ev.Seq)
+	rec = binary.BigEndian.AppendUint32(rec, ev.Key)
+	rec = binary.AppendUvarint(rec, uint64(len(value)))
+	rec = append(rec, value...)
+	return binary.BigEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec))
+}
+
+// Checkpoint writes a snapshot of the keys to w
+//
+// The snapshot records the changelog sequence number at which it started
+// and then the keys as ascending varint deltas, followed by a checksum.
+// Concurrent updates may or may not be captured; Recover replays the log
+// records after the recorded sequence number, which restores any key they
+// touched, so a snapshot taken under load is still exact after replay
+func (st *SkipTrie) Checkpoint(w io.Writer) error {
+	return writeSnapshot(w, st.Seq(), false, func(fn func(key uint32, value []byte)) error {
+		st.ascend(0, math.MaxUint32, func(node *Node) bool {
+			fn(node.key, nil)
+			return true
+		})
+		return nil
+	})
+}
+
+// writeSnapshot writes a snapshot at sequence number seq of the ascending
+// keys that each passes to fn, with their encoded values if values is set
+func writeSnapshot(w io.Writer, seq uint64, values bool, each func(fn func(key uint32, value []byte)) error) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	
+	var buf [binary.MaxVarintLen64]byte
+	bw.WriteString(snapshotMagic)
+	if values {
+		bw.WriteByte(snapshotValues)
+	} else {
+		bw.WriteByte(snapshotKeys)
+	}
+	binary.BigEndian.PutUint64(buf[:8], seq)
+	bw.Write(buf[:8])
+	
+	// Deltas are at least 1, so 0 terminates the list; the first key is
+	// stored plus one
+	prev := int64(-1)
+	err := each(func(key uint32, value []byte) {
+		n := binary.PutUvarint(buf[:], uint64(int64(key)-prev))
+		bw.Write(buf[:n])
+		if values {
+			n = binary.PutUvarint(buf[:], uint64(len(value)))
+			bw.Write(buf[:n])
+			bw.Write(value)
+		}
+		prev = int64(key)
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteByte(0)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	
+	binary.BigEndian.PutUint32(buf[:4], crc.Sum32())
+	_, err = w.Write(buf[:4])
+	return err
+}
+
+// Recover rebuilds a SkipTrie from a snapshot written by Checkpoint and
+// the log written by WithWAL, either of which may be nil; opts configure
+// the new instance as for NewSkipTrie
+// Log records already covered by the snapshot are skipped, and a record
+// torn by a crash at the end of the log is ignored. The changelog
+// continues from the last recovered sequence number, so a log opened with
+// WithWAL in opts carries on where the old one stopped; the replayed
+// changes themselves are not logged again
+func Recover(snapshot, wal io.Reader, opts ...Option) (*SkipTrie, error) {
+	st := NewSkipTrie(opts...)
+	if err := st.recover(snapshot, wal, &SnapshotInfo{}, nil); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// recover loads snapshot and replays wal into st, either of which may be
+// nil, describing what it read in info; values are restored with dec, and
+// skipped if it is nil
+func (st *SkipTrie) recover(snapshot, wal io.Reader, info *SnapshotInfo, dec valueDecoder) error {
+	l := &st.changes
+	l.mu.Lock()
+	out := l.wal
+	l.wal = nil
+	l.mu.Unlock()
+	
+	if snapshot != nil {
+		if err := st.loadSnapshot(snapshot, info, dec); err != nil {
+			return err
+		}
+	}
+	info.LastSeq = info.Seq
+	if wal != nil {
+		if err := st.replayWAL(wal, info, dec); err != nil {
+			return err
+		}
+	}
+	
+	// Drop the events of the replay itself and continue the old numbering
+	l.mu.Lock()
+	l.seq = info.LastSeq
+	l.ring = l.ring[:0]
+	l.start = 0
+	l.wal = out
+	l.mu.Unlock()
+	return nil
+}
+
+// checksumReader reads from r, feeding everything read to a CRC-32
+type checksumReader struct {
+	r   *bufio.Reader
+	sum uint32
+}
+
+// Read implements io.Reader
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.sum = crc32.Update(c.sum, crc32.IEEETable, p[:n])
+	return n, err
+}
+
+// ReadByte implements io.ByteReader
+func (c *checksumReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.sum = crc32.Update(c.sum, crc32.IEEETable, []byte{b})
+	}
+	return b, err
+}
+
+// loadSnapshot inserts the keys of a snapshot, and their values decoded
+// with dec unless it is nil, recording its sequence number, key count and
+// checksum in info
+func (st *SkipTrie) loadSnapshot(r io.Reader, info *SnapshotInfo, dec valueDecoder) error {
+	br := bufio.NewReader(r)
+	tr := &checksumReader{r: br}
+	
+	head := make([]byte, len(snapshotMagic)+1+8)
+	if _, err := io.ReadFull(tr, head); err != nil {
+		return fmt.Errorf("%w: snapshot header: %v", ErrCorrupt, err)
+	}
+	if string(head[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: not a snapshot", ErrCorrupt)
+	}
+	version := head[len(snapshotMagic)]
+	if version != snapshotKeys && version != snapshotValues {
+		return fmt.Errorf("%w: unknown snapshot version %d", ErrCorrupt, version)
+	}
+	seq := binary.BigEndian.Uint64(head[len(snapshotMagic)+1:])
+	
+	var (
+		keys   []uint32
+		values [][]byte
+	)
+	prev := int64(-1)
+	for {
+		delta, err := binary.ReadUvarint(tr)
+		if err != nil {
+			return fmt.Errorf("%w: snapshot keys: %v", ErrCorrupt, err)
+		}
+		if delta == 0 {
+			break
+		}
+		key := prev + int64(delta)
+		if key > math.MaxUint32 {
+			return fmt.Errorf("%w: snapshot key out of range", ErrCorrupt)
+		}
+		keys = append(keys, uint32(key))
+		prev = key
+		
+		if version == snapshotValues {
+			value, err := readValue(tr, dec != nil)
+			if err != nil {
+				return fmt.Errorf("%w: snapshot value of key %d: %v", ErrCorrupt, key, err)
+			}
+			values = append(values, value)
+		}
+	}
+	
+	var stored [4]byte
+	if _, err := io.ReadFull(br, stored[:]); err != nil {
+		return fmt.Errorf("%w: snapshot checksum: %v", ErrCorrupt, err)
+	}
+	if binary.BigEndian.Uint32(stored[:]) != tr.sum {
+		return fmt.Errorf("%w: snapshot checksum mismatch", ErrCorrupt)
+	}
+	
+	if dec == nil || values == nil {
+		st.InsertBatch(keys)
+	} else {
+		var fing finger
+		for i, key := range keys {
+			v, err := dec(values[i])
+			if err != nil {
+				return fmt.Errorf("%w: snapshot value of key %d: %v", ErrCorrupt, key, err)
+			}
+			st.insertNodeFrom(key, func(node *Node) { node.value.Store(&v) }, &fing)
+		}
+	}
+	info.Seq, info.Keys, info.Checksum = seq, len(keys), tr.sum
+	return nil
+}
+
+// readValue reads a uvarint length and that many bytes from r, returning
+// them if keep is set
+func readValue(r *checksumReader, keep bool) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("value length %d", n)
+	}
+	if !keep {
+		_, err = io.CopyN(io.Discard, r, int64(n))
+		return nil, err
+	}
+	
+	// Grow as the bytes arrive rather than trusting a corrupt length
+	var b bytes.Buffer
+	_, err = io.CopyN(&b, r, int64(n))
+	return b.Bytes(), err
+}
+
+// replayWAL applies the log records after sequence number info.Seq,
+// restoring values with dec unless it is nil, and records the records
+// read and the last sequence number in info
+func (st *SkipTrie) replayWAL(r io.Reader, info *SnapshotInfo, dec valueDecoder) error {
+	br := bufio.NewReader(r)
+	tr := &checksumReader{r: br}
+	var rec [walRecordSize]byte
+	for {
+		tr.sum = 0
+		n, err := io.ReadFull(tr, rec[:13])
+		var value []byte
+		if err == nil && rec[0]&walValue != 0 {
+			value, err = readValue(tr, dec != nil)
+		}
+		sum := tr.sum
+		if err == nil {
+			_, err = io.ReadFull(br, rec[13:])
+		}
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				info.TornTail = n > 0
+				return nil // end of log, possibly a torn record
+			}
+			return err
+		}
+		if binary.BigEndian.Uint32(rec[13:]) != sum {
+			return fmt.Errorf("%w: log record after sequence number %d", ErrCorrupt, info.LastSeq)
+		}
+		info.Records++
+		
+		seq := binary.BigEndian.Uint64(rec[1:])
+		if seq <= info.Seq {
+			continue
+		}
+		key := binary.BigEndian.Uint32(rec[9:])
+		op := EventOp(rec[0] &^ walValue)
+		switch {
+		case op == EventDelete:
+			st.Delete(key)
+		case op != EventInsert && op != EventUpdate:
+			return fmt.Errorf("%w: log record %d has op %d", ErrCorrupt, seq, rec[0])
+		case dec != nil && rec[0]&walValue != 0:
+			v, err := dec(value)
+			if err != nil {
+				return fmt.Errorf("%w: log record %d value: %v", ErrCorrupt, seq, err)
+			}
+			st.storeValue(key, &v)
+		case op == EventInsert:
+			st.Insert(key)
+		}
+		info.Replayed++
+		info.LastSeq = seq
+	}
+}
+
+// storeValue inserts key with the boxed value, or replaces the value of a
+// present key; it is only for replay, which runs alone
+func (st *SkipTrie) storeValue(key uint32, boxed *any) {
+	node, inserted := st.insertNode(key, func(node *Node) { node.value.Store(boxed) })
+	if !inserted {
+		node.value.Store(boxed)
+	}
+}
@@ -0,0 +1,54 @@
+package skiptrie
+
+import (
+	"sort"
+	"sync"
+)
+
+// Pool hands out empty SkipTrie instances and takes them back for reuse,
+// saving the sentinel, tower and RNG setup done by NewSkipTrie
+//
+// Instances are kept in size tiers so that short-lived small sets and
+// long-lived large sets are recycled separately
+type Pool struct {
+	tiers []int       // ascending upper bounds on the expected key count
+	pools []sync.Pool // one free list per tier plus one for larger sets
+}
+
+// NewPool creates a pool with the given size tier bounds
+func NewPool(tiers ...int) *Pool {
+	bounds := append([]int(nil), tiers...)
+	sort.Ints(bounds)
+	
+	p := &Pool{
+		tiers: bounds,
+		pools: make([]sync.Pool, len(bounds)+1),
+	}
+	for i := range p.pools {
+		tier := i
+		p.pools[i].New = func() any {
+			st := NewSkipTrie()
+			st.tier = tier
+			return st
+		}
+	}
+	return p
+}
+
+// Get returns an empty instance from the tier matching sizeHint
+func (p *Pool) Get(sizeHint int) *SkipTrie {
+	tier := sort.SearchInts(p.tiers, sizeHint)
+	return p.pools[tier].Get().(*SkipTrie)
+}
+
+// Put empties st and returns it to the tier it was taken from
+// The caller must not use st after Put
+func (p *Pool) Put(st *SkipTrie) {
+	tier := st.tier
+	if tier >= len(p.pools) {
+		tier = len(p.pools) - 1
+	}
+	
+	st.reset()
+	p.pools[tier].Put(st)
+}
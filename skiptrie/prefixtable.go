@@ -0,0 +1,227 @@
+package skiptrie
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// prefix is a key prefix packed into one word: a presence bit above the
+// prefix length above the prefix bits, so that no prefix packs to zero
+type prefix uint64
+
+// prefixOf returns the prefix of key of length n, 0 <= n <= 32
+func prefixOf(key uint32, n int) prefix {
+	return prefix(1<<40 | uint64(n)<<32 | uint64(key>>(32-n)))
+}
+
+// len returns the length of p in bits
+func (p prefix) len() int {
+	return int(p >> 32 & 0xff)
+}
+
+// covers checks if p is a prefix of key
+func (p prefix) covers(key uint32) bool {
+	return prefixOf(key, p.len()) == p
+}
+
+// minPrefixSlots is the size of a new table
+const minPrefixSlots = 64
+
+// prefixTable is an open-addressing hash table from prefixes to trie nodes
+//
+// Lookups are lock-free. Insertions claim a slot by CAS on its key and set
+// the value by CAS; deletions swap the value out and leave the key behind as
+// a tombstone that a later insertion of the same prefix revives. Writers
+// hold resize for reading, so the only blocking step is the copy into a
+// larger table, which also drops the tombstones
+type prefixTable struct {
+	slots  atomic.Pointer[prefixSlots]
+	resize sync.RWMutex
+	secret *[16]byte // keys the slot hash (WithSeededPrefixes), nil if unkeyed
+}
+
+// prefixSlots is one generation of the table
+type prefixSlots struct {
+	slot   []prefixSlot
+	shift  uint         // 64 - log2(len(slot))
+	used   atomic.Int64 // slots holding a key, live or tombstone
+	secret *[16]byte    // the table's secret
+}
+
+// prefixSlot holds one prefix; key is 0 while the slot is free
+type prefixSlot struct {
+	key atomic.Uint64
+	val atomic.Pointer[TreeNode]
+}
+
+// newPrefixSlots allocates a table of n slots, a power of two, hashing
+// with secret if non-nil
+func newPrefixSlots(n int, secret *[16]byte) *prefixSlots {
+	return &prefixSlots{
+		slot:   make([]prefixSlot, n),
+		shift:  uint(64 - bits.TrailingZeros(uint(n))),
+		secret: secret,
+	}
+}
+
+// home returns the first slot probed for p: Fibonacci hashing, or a
+// SipHash keyed with the secret
+func (s *prefixSlots) home(p prefix) uint64 {
+	if s.secret != nil {
+		return sipHash64(s.secret, uint64(p)) >> s.shift
+	}
+	return (uint64(p) * 0x9e3779b97f4a7c15) >> s.shift
+}
+
+// load returns the node stored for p
+func (t *prefixTable) load(p prefix) (*TreeNode, bool) {
+	s := t.slots.Load()
+	if s == nil {
+		return nil, false
+	}
+	
+	mask := uint64(len(s.slot) - 1)
+	for i, n := s.home(p), 0; n < len(s.slot); i, n = (i+1)&mask, n+1 {
+		switch s.slot[i].key.Load() {
+		case 0:
+			return nil, false
+		case uint64(p):
+			tn := s.slot[i].val.Load()
+			return tn, tn != nil
+		}
+	}
+	return nil, false
+}
+
+// loadOrStore returns the node stored for p if any, otherwise stores tn;
+// loaded reports which happened
+func (t *prefixTable) loadOrStore(p prefix, tn *TreeNode) (actual *TreeNode, loaded bool) {
+	for {
+		t.resize.RLock()
+		s := t.slots.Load()
+		if s != nil && 2*s.used.Load() < int64(len(s.slot)) {
+			if slot := s.claim(p); slot != nil {
+				actual, loaded = slot.store(tn)
+				t.resize.RUnlock()
+				return actual, loaded
+			}
+		}
+		t.resize.RUnlock()
+		t.grow(s)
+	}
+}
+
+// claim returns the slot holding p, claiming a free one if p is absent, or
+// nil if the table is full
+func (s *prefixSlots) claim(p prefix) *prefixSlot {
+	mask := uint64(len(s.slot) - 1)
+	for i, n := s.home(p), 0; n < len(s.slot); i, n = (i+1)&mask, n+1 {
+		slot := &s.slot[i]
+		k := slot.key.Load()
+		if k == 0 {
+			if slot.key.CompareAndSwap(0, uint64(p)) {
+				s.used.Add(1)
+				return slot
+			}
+			k = slot.key.Load()
+		}
+		if k == uint64(p) {
+			return slot
+		}
+	}
+	return nil
+}
+
+// store sets the slot's value to tn unless it already holds one
+func (slot *prefixSlot) store(tn *TreeNode) (*TreeNode, bool) {
+	for {
+		if curr := slot.val.Load(); curr != nil {
+			return curr, true
+		}
+		if slot.val.CompareAndSwap(nil, tn) {
+			return tn, false
+		}
+	}
+}
+
+// delete removes p, reporting whether it was present
+func (t *prefixTable) delete(p prefix) bool {
+	t.resize.RLock()
+	defer t.resize.RUnlock()
+	
+	s := t.slots.Load()
+	if s == nil {
+		return false
+	}
+	mask := uint64(len(s.slot) - 1)
+	for i, n := s.home(p), 0; n < len(s.slot); i, n = (i+1)&mask, n+1 {
+		switch s.slot[i].key.Load() {
+		case 0:
+			return false
+		case uint64(p):
+			return s.slot[i].val.Swap(nil) != nil
+		}
+	}
+	return false
+}
+
+// rangeEntries calls fn for each stored prefix until fn returns false
+// Entries stored or deleted during the walk may or may not be visited
+func (t *prefixTable) rangeEntries(fn func(p prefix, tn *TreeNode) bool) {
+	t.rangeEntriesPart(0, 1, fn)
+}
+
+// rangeEntriesPart is rangeEntries over slice part of parts of the slots,
+// so that disjoint parts can be walked concurrently
+func (t *prefixTable) rangeEntriesPart(part, parts int, fn func(p prefix, tn *TreeNode) bool) {
+	s := t.slots.Load()
+	if s == nil {
+		return
+	}
+	for i := len(s.slot) * part / parts; i < len(s.slot)*(part+1)/parts; i++ {
+		if tn := s.slot[i].val.Load(); tn != nil && !fn(prefix(s.slot[i].key.Load()), tn) {
+			return
+		}
+	}
+}
+
+// grow replaces old, which is too full, with a table sized for its live
+// entries; it does nothing if another writer already replaced old
+func (t *prefixTable) grow(old *prefixSlots) {
+	t.resize.Lock()
+	defer t.resize.Unlock()
+	
+	if t.slots.Load() != old {
+		return
+	}
+	if old == nil {
+		t.slots.Store(newPrefixSlots(minPrefixSlots, t.secret))
+		return
+	}
+	
+	live := 0
+	for i := range old.slot {
+		if old.slot[i].val.Load() != nil {
+			live++
+		}
+	}
+	n := minPrefixSlots
+	for n < 4*live {
+		n *= 2
+	}
+	
+	s := newPrefixSlots(n, t.secret)
+	for i := range old.slot {
+		if tn := old.slot[i].val.Load(); tn != nil {
+			s.claim(prefix(old.slot[i].key.Load())).val.Store(tn)
+		}
+	}
+	t.slots.Store(s)
+}
+
+// reset empties the table; it must not run concurrently with other
+// operations
+func (t *prefixTable) reset() {
+	t.slots.Store(nil)
+}
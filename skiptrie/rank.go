@@ -0,0 +1,81 @@
+package skiptrie
+
+// sparseCount is the estimated number of keys in a range below which
+// CountRange hops between successors instead of walking the bottom level
+const sparseCount = 8
+
+// Rank returns the number of keys less than or equal to key
+//
+// With WithOrderStatistics the counts give the keys below the stretch of
+// 65536 key values holding key, and only that stretch is walked; otherwise
+// it counts every key up to key, taking O(n) steps. Under concurrent
+// updates the answer reflects the counts and the keys as they were read
+func (st *SkipTrie) Rank(key uint32) int {
+	if st.order == nil {
+		return st.CountRange(0, key)
+	}
+	from := key &^ 0xFFFF
+	return int(st.order.before(from)) + st.CountRange(from, key)
+}
+
+// Count returns the number of keys in the closed range [lo, hi]
+//
+// With WithOrderStatistics a range spanning several stretches of 65536 key
+// values is counted as the difference of two ranks; otherwise it is
+// CountRange, which walks the keys of the range, O(n) for wide ranges
+func (st *SkipTrie) Count(lo, hi uint32) int {
+	if st.order == nil || lo>>16 == hi>>16 || lo > hi {
+		return st.CountRange(lo, hi)
+	}
+	n := st.Rank(hi)
+	if lo > 0 {
+		n -= st.Rank(lo - 1)
+	}
+	return max(n, 0)
+}
+
+// CountRange returns the number of keys in the closed range [lo, hi],
+// choosing how to find them from the density of the range
+//
+// The expected number of keys in the range is estimated from Len, as if
+// keys were spread evenly. A range expected to hold fewer than sparseCount
+// keys is counted by successor hops, each a trie-accelerated predecessor
+// query that lands next to the following key; a denser range is counted by
+// walking the bottom level. If the hops find more keys than estimated, the
+// count continues by walking from the last key found
+func (st *SkipTrie) CountRange(lo, hi uint32) int {
+	defer st.unpin(st.pin())
+	
+	if lo > hi {
+		return 0
+	}
+	
+	width := uint64(hi-lo) + 1
+	if uint64(st.Len())*width>>32 >= sparseCount {
+		count := 0
+		st.ascend(lo, hi, func(*Node) bool {
+			count++
+			return true
+		})
+		return count
+	}
+	
+	count := 0
+	node := st.ceilingNode(lo)
+	for ; node != nil && node.key <= hi; node = st.ceilingNode(node.key + 1) {
+		count++
+		if count > 2*sparseCount || node.key == hi {
+			break
+		}
+	}
+	if node == nil || node.key >= hi {
+		return count
+	}
+	
+	// Denser than estimated: walk the rest
+	st.ascend(node.key+1, hi, func(*Node) bool {
+		count++
+		return true
+	})
+	return count
+}
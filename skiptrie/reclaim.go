@@ -0,0 +1,173 @@
+package skiptrie
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// WithNodeReuse recycles deleted nodes into later inserts instead of
+// leaving them to the garbage collector, through the allocator (a
+// PoolAllocator unless WithAllocator chose another). Only nodes below the
+// top level are recycled, which with the default heights is all but about
+// one in 16
+//
+// Lock-free readers may still be looking at a node after it is unlinked,
+// so reuse runs an epoch-based reclamation scheme: every operation pins
+// the current epoch while it holds nodes, and a deleted node is only freed
+// once every operation that could have reached it has finished. The pins
+// cost two atomic writes per operation, and a scan that runs for long
+// (Keys, Checkpoint, a set operation) holds back reclamation until it ends
+//
+// Positions kept between calls by an Iterator or a Finger can't be pinned,
+// so with reuse they are looked up again on each call. WithReverseLinks has
+// no effect, since its backward hints may outlive the nodes they point at,
+// and a Node returned by Predecessor may be recycled once the call returns
+func WithNodeReuse() Option {
+	return func(st *SkipTrie) {
+		st.reclaim = newReclaimer()
+	}
+}
+
+// reclaimAfter is the number of retirements between attempts to advance
+// the epoch
+const reclaimAfter = 64
+
+// reclaimer frees retired nodes once no pinned operation can reach them
+// A node retired in epoch e is reachable only by operations pinned in e
+// or earlier, so it is freed when the epoch moves from e+1 to e+2, which
+// requires every pinned operation to be in e+1
+type reclaimer struct {
+	epoch atomic.Uint64
+	slots []pinSlot // pinned epochs; a free slot holds 0
+	
+	mu      sync.Mutex
+	limbo   [3][]*Node // retired nodes, by epoch modulo 3
+	retired int        // retirements since the last attempt to advance
+}
+
+// pinSlot holds the epoch of one pinned operation, shifted left with the
+// low bit set, on a cache line of its own
+type pinSlot struct {
+	v atomic.Uint64
+	_ [56]byte
+}
+
+// newReclaimer creates a reclaimer with slots for a few pinned operations
+// per processor
+func newReclaimer() *reclaimer {
+	return &reclaimer{slots: make([]pinSlot, max(64, 8*runtime.GOMAXPROCS(0)))}
+}
+
+// pin protects the nodes the calling operation reaches from being freed
+// until it is passed to unpin; it returns nil when reuse is off
+func (st *SkipTrie) pin() *pinSlot {
+	if st.reclaim == nil {
+		return nil
+	}
+	return st.reclaim.pin()
+}
+
+// unpin ends the protection taken by pin
+func (st *SkipTrie) unpin(slot *pinSlot) {
+	if slot != nil {
+		slot.v.Store(0)
+	}
+}
+
+// pin claims a free slot for the current epoch, re-publishing until the
+// epoch read matches the global one, so an advance that missed the slot
+// happened before the operation reached any node
+func (r *reclaimer) pin() *pinSlot {
+	i := rand.IntN(len(r.slots))
+	for spins := 0; ; spins++ {
+		slot := &r.slots[i]
+		e := r.epoch.Load()
+		if slot.v.Load() == 0 && slot.v.CompareAndSwap(0, e<<1|1) {
+			for {
+				now := r.epoch.Load()
+				if now == e {
+					return slot
+				}
+				e = now
+				slot.v.Store(e<<1 | 1)
+			}
+		}
+		if i++; i == len(r.slots) {
+			i = 0
+		}
+		if spins >= len(r.slots) {
+			runtime.Gosched()
+			spins = 0
+		}
+	}
+}
+
+// retire queues node, deleted and unlinked, to be freed into alloc, and
+// now and then tries to advance the epoch
+func (r *reclaimer) retire(node *Node, alloc Allocator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	e := r.epoch.Load()
+	r.limbo[e%3] = append(r.limbo[e%3], node)
+	if r.retired++; r.retired < reclaimAfter {
+		return
+	}
+	r.retired = 0
+	r.advance(alloc)
+}
+
+// advance moves the epoch on and frees the nodes retired two epochs ago,
+// unless an operation is still pinned in an earlier epoch; r.mu is held
+func (r *reclaimer) advance(alloc Allocator) {
+	e := r.epoch.Load()
+	for i := range r.slots {
+		if v := r.slots[i].v.Load(); v != 0 && v>>1 != e {
+			return // an operation is still pinned in an earlier epoch
+		}
+	}
+	
+	// Nodes retired in e-1 are now unreachable; their bucket takes the
+	// retirements of e+1
+	r.epoch.Store(e + 1)
+	old := &r.limbo[(e+2)%3]
+	for i, node := range *old {
+		alloc.Free(node)
+		(*old)[i] = nil
+	}
+	*old = (*old)[:0]
+}
+
+// Handoff bits of a node: whichever of its inserter and its deleter is
+// done with it last retires it, so a node whose tower is still being
+// raised is not freed under its inserter
+const (
+	handoffInserted uint32 = 1 << iota
+	handoffDeleted
+)
+
+// handoff records that the inserter or the deleter of node is done with
+// it, retiring it once both are
+// Nodes tall enough for the top level are left to the garbage collector:
+// trie entries and prev pointers may refer to them after they are
+// unlinked, and are not cleared in step with the epochs
+func (st *SkipTrie) handoff(node *Node, done uint32) {
+	if st.reclaim == nil || node.origHeight >= st.loglog {
+		return
+	}
+	if old := node.handoff.Or(done); old|done == handoffInserted|handoffDeleted && old != old|done {
+		st.reclaim.retire(node, st.alloc)
+	}
+}
+
+// reset drops the retired nodes of an instance being emptied
+func (r *reclaimer) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.limbo {
+		r.limbo[i] = nil
+	}
+	r.retired = 0
+}
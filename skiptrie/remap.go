@@ -0,0 +1,56 @@
+package skiptrie
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotMonotonic is returned by RemapKeys for a function that does not
+// preserve the order of the keys
+var ErrNotMonotonic = errors.New("skiptrie: key mapping is not strictly increasing")
+
+// RemapKeys rewrites every key k to fn(k), for rebasing an ID space in
+// place; fn must be strictly increasing over the keys held and must not
+// return math.MaxUint32, which is reserved
+//
+// All new keys are computed and checked first, so on error nothing has
+// changed. Because the order is preserved, the skiplist keeps its shape:
+// the keys are rewritten in one pass over the bottom level, including
+// deleted nodes not yet unlinked, and the x-fast trie is rebuilt from the
+// indexed nodes. RemapKeys must not run concurrently with other
+// operations; open iterators are invalidated, and the changelog records
+// nothing, so take a new Checkpoint when using WithWAL
+func (st *SkipTrie) RemapKeys(fn func(uint32) uint32) error {
+	defer st.unpin(st.pin())
+	
+	var nodes []*Node
+	var keys []uint32
+	for curr := st.head.next[0].Load(); curr != st.tail; curr = curr.next[0].Load() {
+		key := fn(curr.key)
+		switch {
+		case key == math.MaxUint32:
+			return fmt.Errorf("%w: %d maps to the reserved key %d", ErrNotMonotonic, curr.key, key)
+		case len(keys) > 0 && key <= keys[len(keys)-1]:
+			prev := nodes[len(nodes)-1]
+			return fmt.Errorf("%w: %d maps to %d, but %d maps to %d", ErrNotMonotonic,
+				curr.key, key, prev.key, keys[len(keys)-1])
+		}
+		nodes = append(nodes, curr)
+		keys = append(keys, key)
+	}
+	
+	st.gen.Add(1)
+	st.setDirty(true)
+	st.prefixes.reset()
+	st.entries.Store(0)
+	for i, node := range nodes {
+		node.key = keys[i]
+	}
+	for _, node := range nodes {
+		if node.indexed {
+			st.insertIntoTrie(node)
+		}
+	}
+	return nil
+}
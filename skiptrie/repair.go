@@ -0,0 +1,117 @@
+package skiptrie
+
+import (
+	"context"
+	"sync"
+)
+
+// maxPendingRepairs bounds the deleted nodes awaiting trie repair; once
+// the worker falls this far behind, deleters repair their own entries
+const maxPendingRepairs = 4096
+
+// WithLazyTrieRepair takes the x-fast trie repair off the delete path:
+// instead of rewriting the prefix entries of a deleted top-level node,
+// with a top-level search for each, the deleter queues the node for a
+// background worker, which queries noticing a deleted node in the trie
+// also wake
+//
+// The trie is only a hint, and readers already step back from deleted
+// nodes along their back pointers, so queries stay correct while entries
+// are stale, only walking further. When the worker lags more than 4096
+// nodes behind, or after Close, deletes repair inline again. RepairTrie
+// drains the queue on demand
+func WithLazyTrieRepair() Option {
+	return func(st *SkipTrie) {
+		st.repair = &trieRepair{wake: make(chan struct{}, 1)}
+	}
+}
+
+// trieRepair queues deleted indexed nodes whose trie entries are stale
+type trieRepair struct {
+	mu      sync.Mutex
+	running sync.Mutex // held while queued nodes are repaired
+	pending []*Node
+	closed  bool          // the worker has stopped
+	wake    chan struct{} // signals the worker, buffered by one
+}
+
+// push queues node, reporting false if the caller must repair it itself
+func (r *trieRepair) push(node *Node) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if r.closed || len(r.pending) >= maxPendingRepairs {
+		return false
+	}
+	r.pending = append(r.pending, node)
+	if len(r.pending) == 1 {
+		r.kick()
+	}
+	return true
+}
+
+// kick wakes the worker unless a wake-up is already pending
+func (r *trieRepair) kick() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take empties the queue and returns what it held
+func (r *trieRepair) take() []*Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	nodes := r.pending
+	r.pending = nil
+	return nodes
+}
+
+// len returns the number of queued nodes
+func (r *trieRepair) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// startTrieRepair starts the WithLazyTrieRepair worker
+func (st *SkipTrie) startTrieRepair() {
+	st.goBackground(func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				st.repair.mu.Lock()
+				st.repair.closed = true
+				st.repair.mu.Unlock()
+				st.RepairTrie()
+				return nil
+			case <-st.repair.wake:
+				st.RepairTrie()
+			}
+		}
+	})
+}
+
+// RepairTrie repairs the trie entries of every deleted node queued by
+// WithLazyTrieRepair, on the calling goroutine, and returns how many nodes
+// it repaired; without WithLazyTrieRepair there is nothing to do
+// Repairs already taken by the worker are waited for, so every delete
+// that returned before the call has its entries repaired when it returns
+func (st *SkipTrie) RepairTrie() int {
+	if st.repair == nil {
+		return 0
+	}
+	st.repair.running.Lock()
+	defer st.repair.running.Unlock()
+	defer st.unpin(st.pin())
+	
+	n := 0
+	for nodes := st.repair.take(); len(nodes) > 0; nodes = st.repair.take() {
+		for _, node := range nodes {
+			st.deleteFromTrie(node)
+		}
+		n += len(nodes)
+	}
+	return n
+}
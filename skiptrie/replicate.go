@@ -0,0 +1,149 @@
+package skiptrie
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// streamBuffer bounds the events a Stream queues for a slow writer
+const streamBuffer = 1 << 16
+
+// Stream writes a replication stream of st to w: the log records, in the
+// format of WithWAL, of the changes after sequence number since, followed
+// by each change as it is sequenced, until ctx is done or a write fails
+// If since is 0 or the changes after it are no longer retained (see
+// WithChangelog), the stream starts with a snapshot, as written by Checkpoint, and the
+// records carry on from it. Either way a Replica applying the stream
+// converges to st
+// Records are written one at a time, and w is flushed whenever the stream
+// catches up if it has a Flush method. A writer that falls streamBuffer
+// events behind ends the stream with ErrWatchOverflow; the replica resumes
+// by streaming again from its Seq
+func (st *SkipTrie) Stream(ctx context.Context, w io.Writer, since uint64) error {
+	opts := []WatchOption{WithBuffer(streamBuffer, OverflowCancel)}
+	watcher, err := st.Watch(0, math.MaxUint32, append(opts, WithSince(since))...)
+	// Keys inserted before the changelog became active have no events, so
+	// a replica starting from scratch always gets a snapshot
+	if err == ErrCompacted || err == nil && (since == 0 || since > st.Seq()) {
+		if watcher != nil {
+			watcher.Close()
+		}
+		watcher, err = st.Watch(0, math.MaxUint32, opts...)
+		if err == nil {
+			err = st.Checkpoint(w)
+		}
+	}
+	if err != nil {
+		if watcher != nil {
+			watcher.Close()
+		}
+		return err
+	}
+	defer watcher.Close()
+	
+	flusher, _ := w.(interface{ Flush() error })
+	for {
+		var ev Event
+		var ok bool
+		select {
+		case ev, ok = <-watcher.C:
+		default:
+			if flusher != nil {
+				if err := flusher.Flush(); err != nil {
+					return err
+				}
+			}
+			select {
+			case ev, ok = <-watcher.C:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		
+		switch {
+		case !ok:
+			if err := st.Err(); err != nil {
+				return err
+			}
+			return ErrClosed
+		case ev.Op == EventOverflow:
+			return ErrWatchOverflow
+		}
+		rec := walRecord(ev)
+		if _, err := w.Write(rec[:]); err != nil {
+			return err
+		}
+	}
+}
+
+// Replica applies the replication streams of another SkipTrie, written by
+// Stream, to a local one
+// The local SkipTrie stays readable throughout; it should not be updated
+// other than through the Replica
+type Replica struct {
+	st  *SkipTrie
+	seq atomic.Uint64 // sequence number of the last change applied
+}
+
+// NewReplica creates a Replica that applies streams to st
+func NewReplica(st *SkipTrie) *Replica {
+	return &Replica{st: st}
+}
+
+// SkipTrie returns the local copy
+func (r *Replica) SkipTrie() *SkipTrie {
+	return r.st
+}
+
+// Seq returns the sequence number, in the numbering of the source, of the
+// last change applied; a new stream resumes from it
+func (r *Replica) Seq() uint64 {
+	return r.seq.Load()
+}
+
+// Apply reads a replication stream until it ends, applying its snapshot
+// and then the records after Seq
+// A snapshot replaces the local keys without emptying the set in between,
+// and a record torn by a broken connection is ignored, so Apply can be
+// repeated with a fresh stream from Seq
+func (r *Replica) Apply(stream io.Reader) error {
+	br := bufio.NewReader(stream)
+	info := SnapshotInfo{Seq: r.Seq()}
+	defer func() {
+		r.seq.Store(info.LastSeq)
+	}()
+	info.LastSeq = info.Seq
+	
+	magic, err := br.Peek(len(snapshotMagic))
+	if err == io.EOF {
+		return nil
+	}
+	if string(magic) == snapshotMagic {
+		src := NewSkipTrie()
+		if err := src.loadSnapshot(br, &info, nil); err != nil {
+			return err
+		}
+		r.st.converge(src)
+		info.LastSeq = info.Seq
+	}
+	return r.st.replayWAL(br, &info, nil)
+}
+
+// converge makes the keys of st those of src, deleting the keys src lacks
+// before inserting the rest
+func (st *SkipTrie) converge(src *SkipTrie) {
+	var stale []uint32
+	st.ascend(0, math.MaxUint32, func(node *Node) bool {
+		if !src.Contains(node.key) {
+			stale = append(stale, node.key)
+		}
+		return true
+	})
+	for _, key := range stale {
+		st.Delete(key)
+	}
+	st.InsertBatch(src.Keys())
+}
@@ -0,0 +1,207 @@
+package skiptrie
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reproRadius is how many live keys on each side of the affected keys a
+// report keeps for its reproducing state
+const reproRadius = 8
+
+// CorruptionReport describes a violated structural invariant, with enough
+// context to reproduce it outside the process that found it
+// It is returned as an error by the invariant checks
+type CorruptionReport struct {
+	Problem  string        // the invariant that does not hold
+	Keys     []uint32      // keys of the nodes involved
+	Levels   []int         // skiplist levels involved; -1 stands for the trie
+	Prefixes []PrefixEntry // trie entries involved
+	Recent   []Event       // retained changelog events for Keys (WithChangelog)
+	Window   []uint32      // live keys around Keys when the report was made
+}
+
+// PrefixEntry is a snapshot of one x-fast trie entry
+type PrefixEntry struct {
+	Len      int      // prefix length in bits
+	Bits     uint32   // prefix bits, right-aligned
+	Pointers [2]int64 // keys of the largest node in the 0-subtree and the smallest in the 1-subtree, -1 if none
+}
+
+// String formats the entry as its bit pattern and pointers
+func (e PrefixEntry) String() string {
+	bits := ""
+	if e.Len > 0 {
+		bits = fmt.Sprintf("%0*b", e.Len, e.Bits)
+	}
+	return fmt.Sprintf("%s* -> [%d %d]", bits, e.Pointers[0], e.Pointers[1])
+}
+
+// Error summarizes the report
+func (r *CorruptionReport) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "skiptrie: corrupt structure: %s (keys %v", r.Problem, r.Keys)
+	if len(r.Levels) > 0 {
+		fmt.Fprintf(&b, ", levels %v", r.Levels)
+	}
+	for _, e := range r.Prefixes {
+		fmt.Fprintf(&b, ", entry %v", e)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// report builds the CorruptionReport of a violation involving nodes at
+// level, and the trie entry for p if non-nil
+func (st *SkipTrie) report(problem string, level int, p *prefix, nodes ...*Node) *CorruptionReport {
+	r := &CorruptionReport{Problem: problem, Levels: []int{level}}
+	for _, node := range nodes {
+		if node != st.head && node != st.tail {
+			r.Keys = append(r.Keys, node.key)
+		}
+	}
+	if p != nil {
+		r.Prefixes = append(r.Prefixes, st.prefixEntry(*p))
+	}
+	
+	affected := make(map[uint32]bool, len(r.Keys))
+	for _, key := range r.Keys {
+		affected[key] = true
+	}
+	l := &st.changes
+	l.mu.Lock()
+	for i := range l.ring {
+		if ev := l.ring[(l.start+i)%len(l.ring)]; affected[ev.Key] {
+			r.Recent = append(r.Recent, ev)
+		}
+	}
+	l.mu.Unlock()
+	
+	r.Window = st.window(r.Keys)
+	return r
+}
+
+// prefixEntry captures the trie entry for p
+func (st *SkipTrie) prefixEntry(p prefix) PrefixEntry {
+	e := PrefixEntry{Len: p.len(), Bits: uint32(p), Pointers: [2]int64{-1, -1}}
+	if tn, ok := st.prefixes.load(p); ok {
+		for dir := range tn.pointers {
+			if node := tn.pointers[dir].Load(); node != nil {
+				e.Pointers[dir] = int64(node.key)
+			}
+		}
+	}
+	return e
+}
+
+// window returns the live keys within reproRadius keys of the range spanned
+// by keys, read from the bottom level in at most Len steps past it, so that
+// a damaged list cannot trap the walk
+func (st *SkipTrie) window(keys []uint32) []uint32 {
+	if len(keys) == 0 {
+		return nil
+	}
+	lo, hi := keys[0], keys[0]
+	for _, key := range keys {
+		lo, hi = min(lo, key), max(hi, key)
+	}
+	
+	var before, out []uint32
+	after := 0
+	limit := st.Len() + 2*reproRadius + len(keys)
+	curr := st.head.next[0].Load()
+	for steps := 0; curr != nil && curr != st.tail && steps < limit && after < reproRadius; steps++ {
+		switch {
+		case curr.marked.Load():
+		case curr.key < lo:
+			before = append(before, curr.key)
+			if len(before) > reproRadius {
+				before = before[1:]
+			}
+		case curr.key <= hi:
+			out = append(out, curr.key)
+		default:
+			out = append(out, curr.key)
+			after++
+		}
+		curr = curr.next[0].Load()
+	}
+	return append(before, out...)
+}
+
+// WriteRepro writes the smallest state known to reproduce the report, as
+// a snapshot and a log that Recover loads: the window of keys as it was
+// before the recent events, and those events
+// Run the invariant checks on the recovered instance to confirm the
+// reproduction
+func (r *CorruptionReport) WriteRepro(snapshot, wal io.Writer) error {
+	// Undo the recent events to get the state they were applied to
+	present := make(map[uint32]bool, len(r.Window))
+	for _, key := range r.Window {
+		present[key] = true
+	}
+	undone := make(map[uint32]bool)
+	for _, ev := range r.Recent {
+		if ev.Op != EventUpdate && !undone[ev.Key] {
+			present[ev.Key] = ev.Op == EventDelete
+			undone[ev.Key] = true
+		}
+	}
+	keys := make([]uint32, 0, len(present))
+	for key, ok := range present {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	
+	var seq uint64
+	if len(r.Recent) > 0 {
+		seq = r.Recent[0].Seq - 1
+	}
+	if err := writeSnapshot(snapshot, seq, false, func(fn func(uint32, []byte)) error {
+		for _, key := range keys {
+			fn(key, nil)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, ev := range r.Recent {
+		rec := walRecord(ev)
+		if _, err := wal.Write(rec[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveRepro writes WriteRepro's snapshot and log to new files in dir and
+// returns their paths
+func (r *CorruptionReport) SaveRepro(dir string) (snapshotPath, walPath string, err error) {
+	snap, err := os.CreateTemp(dir, "skiptrie-repro-*.snap")
+	if err != nil {
+		return "", "", err
+	}
+	defer snap.Close()
+	walPath = strings.TrimSuffix(snap.Name(), ".snap") + ".wal"
+	wal, err := os.Create(walPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer wal.Close()
+	
+	if err := r.WriteRepro(snap, wal); err != nil {
+		return "", "", err
+	}
+	if err := snap.Close(); err != nil {
+		return "", "", err
+	}
+	if err := wal.Close(); err != nil {
+		return "", "", err
+	}
+	return snap.Name(), walPath, nil
+}
@@ -0,0 +1,399 @@
+// Package resp serves a SkipTrie over a minimal subset of the Redis
+// protocol (RESP), so that existing Redis clients and load generators can
+// drive it
+//
+//	PING [message]
+//	SADD key member [member ...]          number of members added
+//	SREM key member [member ...]          number of members removed
+//	SISMEMBER key member                  1 or 0
+//	SCARD key                             number of members
+//	ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+//	QUIT
+//
+// Members are decimal uint32s, and a member's score is the member itself,
+// so ZRANGEBYSCORE is a range query; min and max take the usual -inf, +inf
+// and ( exclusive forms. The key argument is accepted for compatibility but
+// every command acts on the one SkipTrie being served
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// maxArgs and maxBulk bound a request, so a malformed length cannot make
+// the server allocate without limit
+const (
+	maxArgs = 1 << 20
+	maxBulk = 512 << 20
+)
+
+// errProtocol is reported, and ends the connection, for input that is not
+// RESP
+var errProtocol = errors.New("ERR Protocol error")
+
+// Server serves one SkipTrie to RESP clients
+type Server struct {
+	st *skiptrie.SkipTrie
+	
+	mu     sync.Mutex
+	ln     []net.Listener
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// New creates a Server for st
+func New(st *skiptrie.SkipTrie) *Server {
+	return &Server{st: st, conns: make(map[net.Conn]bool)}
+}
+
+// Serve accepts connections on l and serves each on its own goroutine
+// until l fails or Close is called, returning net.ErrClosed in that case
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.ln = append(s.ln, l)
+	s.mu.Unlock()
+	
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// Close stops the listeners and closes the open connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, l := range s.ln {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// ServeConn serves the requests of one client until it quits or the
+// connection fails, and then closes conn
+func (s *Server) ServeConn(conn net.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.conns[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				writeError(w, err.Error())
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		
+		quit := s.exec(w, args)
+		// Pipelined requests are answered together
+		if quit || r.Buffered() == 0 {
+			if err := w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// readCommand reads one request: an array of bulk strings, or an inline
+// command of space-separated words
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+	args := make([]string, 0, max(n, 0))
+	for range n {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulk {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// readLine reads a line terminated by CRLF, or a bare LF for inline
+// commands typed by hand
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// exec runs one command and writes its reply, reporting whether the
+// client asked to quit
+func (s *Server) exec(w *bufio.Writer, args []string) bool {
+	switch cmd := strings.ToUpper(args[0]); cmd {
+	case "PING":
+		switch len(args) {
+		case 1:
+			w.WriteString("+PONG\r\n")
+		case 2:
+			writeBulk(w, args[1])
+		default:
+			writeArity(w, cmd)
+		}
+	case "QUIT":
+		w.WriteString("+OK\r\n")
+		return true
+	case "SADD", "SREM":
+		if len(args) < 3 {
+			writeArity(w, cmd)
+			break
+		}
+		members, err := parseMembers(args[2:])
+		if err != nil {
+			writeError(w, err.Error())
+			break
+		}
+		update := s.st.Insert
+		if cmd == "SREM" {
+			update = s.st.Delete
+		}
+		n := 0
+		for _, m := range members {
+			if update(m) {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "SISMEMBER":
+		if len(args) != 3 {
+			writeArity(w, cmd)
+			break
+		}
+		m, err := parseMember(args[2])
+		switch {
+		case err != nil:
+			// No such member can be in the set
+			writeInt(w, 0)
+		case s.st.Contains(m):
+			writeInt(w, 1)
+		default:
+			writeInt(w, 0)
+		}
+	case "SCARD":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			break
+		}
+		writeInt(w, s.st.Len())
+	case "ZRANGEBYSCORE":
+		if len(args) < 4 {
+			writeArity(w, cmd)
+			break
+		}
+		s.rangeByScore(w, args[2], args[3], args[4:])
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	return false
+}
+
+// rangeByScore serves ZRANGEBYSCORE
+func (s *Server) rangeByScore(w *bufio.Writer, minArg, maxArg string, opts []string) {
+	lo, okLo, err := parseBound(minArg, true)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	hi, okHi, err := parseBound(maxArg, false)
+	if err != nil {
+		writeError(w, err.Error())
+		return
+	}
+	
+	withScores := false
+	offset, count := 0, -1
+	for i := 0; i < len(opts); i++ {
+		switch {
+		case strings.EqualFold(opts[i], "WITHSCORES"):
+			withScores = true
+		case strings.EqualFold(opts[i], "LIMIT") && i+2 < len(opts):
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(opts[i+1])
+			count, err2 = strconv.Atoi(opts[i+2])
+			if err1 != nil || err2 != nil {
+				writeError(w, "ERR value is not an integer or out of range")
+				return
+			}
+			i += 2
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+	
+	var keys []uint32
+	if okLo && okHi && lo <= hi && offset >= 0 {
+		for key := range s.st.Between(lo, hi) {
+			if count >= 0 && len(keys) == count {
+				break
+			}
+			if offset > 0 {
+				offset--
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	
+	n := len(keys)
+	if withScores {
+		n *= 2
+	}
+	fmt.Fprintf(w, "*%d\r\n", n)
+	for _, key := range keys {
+		m := strconv.FormatUint(uint64(key), 10)
+		writeBulk(w, m)
+		if withScores {
+			writeBulk(w, m)
+		}
+	}
+}
+
+// parseMember parses a member as a key
+func parseMember(arg string) (uint32, error) {
+	key, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || key == math.MaxUint32 {
+		return 0, errors.New("ERR member is not an integer or out of range")
+	}
+	return uint32(key), nil
+}
+
+// parseMembers parses every member before any is applied, so a bad one
+// fails the whole command as Redis does
+func parseMembers(args []string) ([]uint32, error) {
+	members := make([]uint32, len(args))
+	for i, arg := range args {
+		m, err := parseMember(arg)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = m
+	}
+	return members, nil
+}
+
+// parseBound parses a ZRANGEBYSCORE bound into the closed key bound it
+// amounts to, as a lower bound if lower is set; ok is false if no key
+// satisfies the bound
+func parseBound(arg string, lower bool) (key uint32, ok bool, err error) {
+	switch strings.ToLower(arg) {
+	case "-inf":
+		return 0, lower, nil
+	case "+inf", "inf":
+		return math.MaxUint32 - 1, !lower, nil
+	}
+	exclusive := strings.HasPrefix(arg, "(")
+	if exclusive {
+		arg = arg[1:]
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false, errors.New("ERR min or max is not a float")
+	}
+	
+	// Round towards the keys the bound admits
+	if lower {
+		if exclusive {
+			f = math.Floor(f) + 1
+		} else {
+			f = math.Ceil(f)
+		}
+		if f > math.MaxUint32-1 {
+			return 0, false, nil
+		}
+		return uint32(max(f, 0)), true, nil
+	}
+	if exclusive {
+		f = math.Ceil(f) - 1
+	} else {
+		f = math.Floor(f)
+	}
+	if f < 0 {
+		return 0, false, nil
+	}
+	return uint32(min(f, math.MaxUint32-1)), true, nil
+}
+
+// writeInt writes an integer reply
+func writeInt(w *bufio.Writer, n int) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+// writeBulk writes a bulk string reply
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+// writeError writes an error reply
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
+
+// writeArity writes the error for a command with the wrong number of
+// arguments
+func writeArity(w *bufio.Writer, cmd string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
@@ -0,0 +1,158 @@
+package skiptrie
+
+import "math"
+
+// linkBottomPrev publishes the backward hints around a freshly linked node
+func (st *SkipTrie) linkBottomPrev(pred, node, succ *Node) {
+	node.prevBottom.Store(pred)
+	
+	for {
+		old := succ.prevBottom.Load()
+		if old != nil && old != pred && !old.marked.Load() && old.key > node.key {
+			return // A closer node was linked concurrently
+		}
+		if succ.prevBottom.CompareAndSwap(old, node) {
+			return
+		}
+	}
+}
+
+// bottomPrev returns the live bottom-level predecessor of node, or head
+// The backward hint is only a starting point: it is confirmed by walking
+// forward, since nodes may have been linked after the hint was published
+func (st *SkipTrie) bottomPrev(node *Node) *Node {
+	start := node.prevBottom.Load()
+	for start != nil && start != st.head && (start.marked.Load() || start.key >= node.key) {
+		start = start.prevBottom.Load()
+	}
+	if start == nil {
+		start = st.head
+	}
+	
+	last := start
+	for curr := start.next[0].Load(); curr != nil && curr != st.tail && curr.key < node.key; curr = curr.next[0].Load() {
+		if !curr.marked.Load() {
+			last = curr
+		}
+	}
+	return last
+}
+
+// prevNode returns the live node preceding node, or nil at the beginning
+func (st *SkipTrie) prevNode(node *Node) *Node {
+	if st.reverseLinks {
+		if prev := st.bottomPrev(node); prev != st.head {
+			return prev
+		}
+		return nil
+	}
+	
+	for {
+		pred := st.Predecessor(node.key)
+		if pred == nil || !pred.marked.Load() {
+			return pred
+		}
+		node = pred
+	}
+}
+
+// floorNode returns the live node with the largest key less than or equal
+// to key, or nil if there is none
+func (st *SkipTrie) floorNode(key uint32) *Node {
+	if key == math.MaxUint32 {
+		return st.prevNode(st.tail)
+	}
+	
+	node := st.Predecessor(key + 1)
+	if node != nil && node.marked.Load() {
+		node = st.prevNode(node)
+	}
+	return node
+}
+
+// Descend calls fn for each key in [lo, hi] in descending order until fn
+// returns false
+//
+// With WithReverseLinks each step follows a backward hint. Otherwise the
+// walk goes back along the top level through the prev pointers, and lists
+// the bottom level between each top-level node and the last one forward,
+// calling fn on the stretch in reverse; with the default heights a stretch
+// holds about 16 keys. Keys changed during the walk may or may not be seen
+func (st *SkipTrie) Descend(hi, lo uint32, fn func(key uint32) bool) {
+	defer st.unpin(st.pin())
+	
+	if st.reverseLinks {
+		for curr := st.floorNode(hi); curr != nil && curr.key >= lo; curr = st.prevNode(curr) {
+			if !fn(curr.key) {
+				return
+			}
+		}
+		return
+	}
+	if lo > hi {
+		return
+	}
+	
+	// Each stretch holds the keys in [max(lo, anchor.key), upper)
+	upper := uint64(hi) + 1
+	anchor := st.topBefore(uint32(min(upper, math.MaxUint32)))
+	var stretch []uint32
+	for {
+		stretch = stretch[:0]
+		if anchor != st.head && anchor.key >= lo && !anchor.marked.Load() {
+			stretch = append(stretch, anchor.key)
+		}
+		for curr := anchor.next[0].Load(); curr != nil && curr != st.tail && uint64(curr.key) < upper; curr = curr.next[0].Load() {
+			if curr.key >= lo && !curr.marked.Load() {
+				stretch = append(stretch, curr.key)
+			}
+		}
+		for i := len(stretch) - 1; i >= 0; i-- {
+			if !fn(stretch[i]) {
+				return
+			}
+		}
+		
+		if anchor == st.head || anchor.key <= lo {
+			return
+		}
+		upper = uint64(anchor.key)
+		anchor = st.topPrev(anchor)
+	}
+}
+
+// topBefore returns a live top-level node with key below key, or head
+func (st *SkipTrie) topBefore(key uint32) *Node {
+	start := st.xFastTriePred(key, nil)
+	if start == nil || start.marked.Load() {
+		start = st.head
+	}
+	left, _ := st.listSearch(key, start, st.loglog-1)
+	return left
+}
+
+// topPrev returns a live top-level node before node, or head: its prev
+// pointer, which may lag behind nodes linked since, or a fresh search if
+// that node has been deleted
+func (st *SkipTrie) topPrev(node *Node) *Node {
+	if prev := st.loadPrev(node); prev != nil && !prev.marked.Load() && (prev == st.head || prev.key < node.key) {
+		return prev
+	}
+	return st.topBefore(node.key)
+}
+
+// LargestK returns up to k of the largest keys in descending order
+func (st *SkipTrie) LargestK(k int) []uint32 {
+	defer st.unpin(st.pin())
+	
+	var keys []uint32
+	if k <= 0 {
+		return keys
+	}
+	
+	st.Descend(math.MaxUint32, 0, func(key uint32) bool {
+		keys = append(keys, key)
+		return len(keys) < k
+	})
+	return keys
+}
@@ -0,0 +1,208 @@
+// Package server exposes a SkipTrie as a small ordered-set service over
+// HTTP with JSON bodies, for trying out distributed designs against a
+// real network boundary
+//
+//	PUT    /keys/{key}              insert     {"inserted": bool}
+//	DELETE /keys/{key}              delete     {"deleted": bool}
+//	GET    /keys/{key}              contains   {"present": bool}
+//	GET    /predecessor/{key}       largest key below key   {"key": k, "found": bool}
+//	GET    /successor/{key}         smallest key above key  {"key": k, "found": bool}
+//	GET    /range?lo=&hi=&limit=    keys in [lo, hi]        {"keys": [...], "more": bool}
+//	GET    /stream?since=           replication stream, as written by Stream
+//
+// Keys are decimal uint32s; the largest one is reserved by the SkipTrie
+// and rejected. Errors are reported with a status code and {"error": msg}
+//
+// There is no gRPC service: the module has no dependencies beyond the
+// standard library, and gRPC would need google.golang.org/grpc and
+// generated protobuf code, so this HTTP/JSON facade is the only transport
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// DefaultLimit caps the keys of a range response when the request gives
+// no limit
+const DefaultLimit = 1000
+
+// errBadKey is reported for keys that don't parse or are reserved
+var errBadKey = errors.New("key must be a uint32 below 4294967295")
+
+// Server serves one SkipTrie
+type Server struct {
+	st  *skiptrie.SkipTrie
+	mux *http.ServeMux
+}
+
+// New creates a Server for st
+func New(st *skiptrie.SkipTrie) *Server {
+	s := &Server{st: st, mux: http.NewServeMux()}
+	s.mux.HandleFunc("PUT /keys/{key}", s.insert)
+	s.mux.HandleFunc("DELETE /keys/{key}", s.delete)
+	s.mux.HandleFunc("GET /keys/{key}", s.contains)
+	s.mux.HandleFunc("GET /predecessor/{key}", s.predecessor)
+	s.mux.HandleFunc("GET /successor/{key}", s.successor)
+	s.mux.HandleFunc("GET /range", s.between)
+	s.mux.HandleFunc("GET /stream", s.stream)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// parseKey parses a key from a path or query value
+func parseKey(v string) (uint32, error) {
+	key, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || key == math.MaxUint32 {
+		return 0, errBadKey
+	}
+	return uint32(key), nil
+}
+
+// pathKey parses the {key} path value of r, replying with an error if it
+// is invalid
+func pathKey(w http.ResponseWriter, r *http.Request) (uint32, bool) {
+	key, err := parseKey(r.PathValue("key"))
+	if err != nil {
+		reply(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return 0, false
+	}
+	return key, true
+}
+
+// reply writes v as a JSON response with the given status
+func reply(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// fail replies with a client error
+func fail(w http.ResponseWriter, format string, args ...any) {
+	reply(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// insert serves PUT /keys/{key}
+func (s *Server) insert(w http.ResponseWriter, r *http.Request) {
+	if key, ok := pathKey(w, r); ok {
+		reply(w, http.StatusOK, map[string]bool{"inserted": s.st.Insert(key)})
+	}
+}
+
+// delete serves DELETE /keys/{key}
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	if key, ok := pathKey(w, r); ok {
+		reply(w, http.StatusOK, map[string]bool{"deleted": s.st.Delete(key)})
+	}
+}
+
+// contains serves GET /keys/{key}
+func (s *Server) contains(w http.ResponseWriter, r *http.Request) {
+	if key, ok := pathKey(w, r); ok {
+		reply(w, http.StatusOK, map[string]bool{"present": s.st.Contains(key)})
+	}
+}
+
+// neighbour is the response of predecessor and successor
+type neighbour struct {
+	Key   uint32 `json:"key"`
+	Found bool   `json:"found"`
+}
+
+// predecessor serves GET /predecessor/{key}
+func (s *Server) predecessor(w http.ResponseWriter, r *http.Request) {
+	if key, ok := pathKey(w, r); ok {
+		pred, found := s.st.PredecessorKey(key)
+		reply(w, http.StatusOK, neighbour{pred, found})
+	}
+}
+
+// successor serves GET /successor/{key}
+func (s *Server) successor(w http.ResponseWriter, r *http.Request) {
+	if key, ok := pathKey(w, r); ok {
+		succ, found := s.st.SuccessorKey(key)
+		reply(w, http.StatusOK, neighbour{succ, found})
+	}
+}
+
+// keyRange is the response of between
+type keyRange struct {
+	Keys []uint32 `json:"keys"`
+	More bool     `json:"more"` // the limit cut the range short
+}
+
+// between lists the keys in [lo, hi], both defaulting to the ends of the
+// key space, up to limit of them
+func (s *Server) between(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lo, hi := uint32(0), uint32(math.MaxUint32-1)
+	limit := DefaultLimit
+	var err error
+	if v := q.Get("lo"); v != "" {
+		if lo, err = parseKey(v); err != nil {
+			fail(w, "lo: %v", err)
+			return
+		}
+	}
+	if v := q.Get("hi"); v != "" {
+		if hi, err = parseKey(v); err != nil {
+			fail(w, "hi: %v", err)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			fail(w, "limit must be a positive integer")
+			return
+		}
+	}
+	
+	out := keyRange{Keys: []uint32{}}
+	for key := range s.st.Between(lo, hi) {
+		if len(out.Keys) == limit {
+			out.More = true
+			break
+		}
+		out.Keys = append(out.Keys, key)
+	}
+	reply(w, http.StatusOK, out)
+}
+
+// flushWriter flushes an http.ResponseWriter for Stream
+type flushWriter struct {
+	http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Flush sends the buffered stream to the client
+func (f flushWriter) Flush() error {
+	return f.rc.Flush()
+}
+
+// stream serves a replication stream until the client goes away
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	var since uint64
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+			fail(w, "since must be a sequence number")
+			return
+		}
+	}
+	
+	w.Header().Set("Content-Type", "application/octet-stream")
+	fw := flushWriter{w, http.NewResponseController(w)}
+	// The status is sent with the first bytes, so errors past this point
+	// can only end the stream; the replica resumes from its Seq
+	s.st.Stream(r.Context(), fw, since)
+}
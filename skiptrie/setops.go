@@ -0,0 +1,62 @@
+package skiptrie
+
+// setOp merges st and other in one pass over their bottom levels and
+// bulk-loads the keys keep accepts into a new SkipTrie configured by opts
+// Keys changed during the pass may or may not be seen
+func (st *SkipTrie) setOp(other *SkipTrie, keep func(inA, inB bool) bool, opts []Option) *SkipTrie {
+	var keys []uint32
+	mergeJoin(st, other, func(key uint32, an, bn *Node) bool {
+		if keep(an != nil, bn != nil) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	
+	out := NewSkipTrie(opts...)
+	out.InsertBatch(keys)
+	return out
+}
+
+// Union returns a new SkipTrie holding the keys of either st or other
+func (st *SkipTrie) Union(other *SkipTrie, opts ...Option) *SkipTrie {
+	return st.setOp(other, func(inA, inB bool) bool { return inA || inB }, opts)
+}
+
+// Intersect returns a new SkipTrie holding the keys of both st and other
+func (st *SkipTrie) Intersect(other *SkipTrie, opts ...Option) *SkipTrie {
+	return st.setOp(other, func(inA, inB bool) bool { return inA && inB }, opts)
+}
+
+// Difference returns a new SkipTrie holding the keys of st missing from
+// other
+func (st *SkipTrie) Difference(other *SkipTrie, opts ...Option) *SkipTrie {
+	return st.setOp(other, func(inA, inB bool) bool { return inA && !inB }, opts)
+}
+
+// SymmetricDifference returns a new SkipTrie holding the keys of exactly
+// one of st and other
+func (st *SkipTrie) SymmetricDifference(other *SkipTrie, opts ...Option) *SkipTrie {
+	return st.setOp(other, func(inA, inB bool) bool { return inA != inB }, opts)
+}
+
+// Equal checks if st and other hold the same keys, stopping at the first
+// key only one of them holds
+func (st *SkipTrie) Equal(other *SkipTrie) bool {
+	equal := true
+	mergeJoin(st, other, func(_ uint32, an, bn *Node) bool {
+		equal = an != nil && bn != nil
+		return equal
+	})
+	return equal
+}
+
+// IsSubsetOf checks if every key of st is also in other, stopping at the
+// first key missing from other
+func (st *SkipTrie) IsSubsetOf(other *SkipTrie) bool {
+	subset := true
+	mergeJoin(st, other, func(_ uint32, an, bn *Node) bool {
+		subset = an == nil || bn != nil
+		return subset
+	})
+	return subset
+}
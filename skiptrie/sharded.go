@@ -0,0 +1,216 @@
+package skiptrie
+
+import (
+	"math"
+	"math/bits"
+)
+
+// MaxShards bounds the number of shards of a ShardedSkipTrie
+const MaxShards = 1 << 16
+
+// ShardedSkipTrie partitions the key space by its high bits across
+// independent SkipTries, so that updates to distant keys never touch the
+// same nodes or trie entries
+// Point operations go to a single shard; ordered queries continue into
+// the neighbouring shards when their own shard has no answer. Each shard
+// is linearizable on its own, but a query spanning shards is not atomic
+// with respect to updates in the shards it crosses
+type ShardedSkipTrie struct {
+	shards []*SkipTrie
+	shift  uint // key >> shift is the shard index
+}
+
+// NewShardedSkipTrie creates a ShardedSkipTrie with n shards, rounded up
+// to a power of two and clamped to [1, MaxShards]; opts apply to every
+// shard
+func NewShardedSkipTrie(n int, opts ...Option) *ShardedSkipTrie {
+	n = max(1, min(n, MaxShards))
+	logN := bits.Len(uint(n - 1))
+	
+	s := &ShardedSkipTrie{
+		shards: make([]*SkipTrie, 1<<logN),
+		shift:  uint(32 - logN),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewSkipTrie(opts...)
+	}
+	return s
+}
+
+// shardOf returns the index of the shard holding key
+func (s *ShardedSkipTrie) shardOf(key uint32) int {
+	return int(uint64(key) >> s.shift)
+}
+
+// Shard returns the SkipTrie holding key, for operations the
+// ShardedSkipTrie does not forward
+func (s *ShardedSkipTrie) Shard(key uint32) *SkipTrie {
+	return s.shards[s.shardOf(key)]
+}
+
+// Shards returns the number of shards
+func (s *ShardedSkipTrie) Shards() int {
+	return len(s.shards)
+}
+
+// Insert inserts a key into its shard
+func (s *ShardedSkipTrie) Insert(key uint32) bool {
+	return s.Shard(key).Insert(key)
+}
+
+// Delete deletes a key from its shard
+func (s *ShardedSkipTrie) Delete(key uint32) bool {
+	return s.Shard(key).Delete(key)
+}
+
+// Contains checks if a key exists
+func (s *ShardedSkipTrie) Contains(key uint32) bool {
+	return s.Shard(key).Contains(key)
+}
+
+// Len returns the number of keys across all shards
+func (s *ShardedSkipTrie) Len() int {
+	n := 0
+	for _, st := range s.shards {
+		n += st.Len()
+	}
+	return n
+}
+
+// Floor returns the largest key less than or equal to key
+func (s *ShardedSkipTrie) Floor(key uint32) (uint32, bool) {
+	i := s.shardOf(key)
+	if k, ok := s.shards[i].Floor(key); ok {
+		return k, true
+	}
+	for i--; i >= 0; i-- {
+		if k, ok := s.shards[i].Last(); ok {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Ceiling returns the smallest key greater than or equal to key
+func (s *ShardedSkipTrie) Ceiling(key uint32) (uint32, bool) {
+	i := s.shardOf(key)
+	if k, ok := s.shards[i].Ceiling(key); ok {
+		return k, true
+	}
+	for i++; i < len(s.shards); i++ {
+		if k, ok := s.shards[i].First(); ok {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// PredecessorKey returns the largest key strictly less than key
+func (s *ShardedSkipTrie) PredecessorKey(key uint32) (uint32, bool) {
+	if key == 0 {
+		return 0, false
+	}
+	return s.Floor(key - 1)
+}
+
+// SuccessorKey returns the smallest key strictly greater than key
+func (s *ShardedSkipTrie) SuccessorKey(key uint32) (uint32, bool) {
+	if key == math.MaxUint32 {
+		return 0, false
+	}
+	return s.Ceiling(key + 1)
+}
+
+// First returns the smallest key
+func (s *ShardedSkipTrie) First() (uint32, bool) {
+	return s.Ceiling(0)
+}
+
+// Last returns the largest key
+func (s *ShardedSkipTrie) Last() (uint32, bool) {
+	return s.Floor(math.MaxUint32)
+}
+
+// Ascend calls fn for each key in [lo, hi] in ascending order until fn
+// returns false, visiting the shards the range covers in turn
+func (s *ShardedSkipTrie) Ascend(lo, hi uint32, fn func(key uint32) bool) {
+	if lo > hi {
+		return
+	}
+	
+	more := true
+	for i := s.shardOf(lo); more && i <= s.shardOf(hi); i++ {
+		s.shards[i].ascend(lo, hi, func(node *Node) bool {
+			more = fn(node.key)
+			return more
+		})
+	}
+}
+
+// Descend calls fn for each key in [lo, hi] in descending order until fn
+// returns false
+func (s *ShardedSkipTrie) Descend(hi, lo uint32, fn func(key uint32) bool) {
+	if lo > hi {
+		return
+	}
+	
+	more := true
+	for i := s.shardOf(hi); more && i >= s.shardOf(lo); i-- {
+		s.shards[i].Descend(hi, lo, func(key uint32) bool {
+			more = fn(key)
+			return more
+		})
+	}
+}
+
+// Count returns the number of keys in the closed range [lo, hi]
+// Shards lying entirely inside the range are counted by their length
+func (s *ShardedSkipTrie) Count(lo, hi uint32) int {
+	if lo > hi {
+		return 0
+	}
+	
+	first, last := s.shardOf(lo), s.shardOf(hi)
+	if first == last {
+		return s.shards[first].Count(lo, hi)
+	}
+	n := s.shards[first].Count(lo, math.MaxUint32) + s.shards[last].Count(0, hi)
+	for i := first + 1; i < last; i++ {
+		n += s.shards[i].Len()
+	}
+	return n
+}
+
+// Rank returns the number of keys less than or equal to key
+func (s *ShardedSkipTrie) Rank(key uint32) int {
+	return s.Count(0, key)
+}
+
+// DeleteRange deletes all keys in the closed range [lo, hi] and returns how
+// many were deleted
+func (s *ShardedSkipTrie) DeleteRange(lo, hi uint32) int {
+	if lo > hi {
+		return 0
+	}
+	
+	n := 0
+	for i := s.shardOf(lo); i <= s.shardOf(hi); i++ {
+		n += s.shards[i].DeleteRange(lo, hi)
+	}
+	return n
+}
+
+// DeleteAll deletes every key of every shard, one shard after another, as
+// SkipTrie.DeleteAll
+func (s *ShardedSkipTrie) DeleteAll() {
+	for _, st := range s.shards {
+		st.DeleteAll()
+	}
+}
+
+// Close stops the background work of every shard
+func (s *ShardedSkipTrie) Close() {
+	for _, st := range s.shards {
+		st.Close()
+	}
+}
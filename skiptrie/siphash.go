@@ -0,0 +1,63 @@
+package skiptrie
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// sipRound is one SipHash round over the state words
+func sipRound(v0, v1, v2, v3 uint64) (uint64, uint64, uint64, uint64) {
+	v0 += v1
+	v1 = bits.RotateLeft64(v1, 13) ^ v0
+	v0 = bits.RotateLeft64(v0, 32)
+	v2 += v3
+	v3 = bits.RotateLeft64(v3, 16) ^ v2
+	v0 += v3
+	v3 = bits.RotateLeft64(v3, 21) ^ v0
+	v2 += v1
+	v1 = bits.RotateLeft64(v1, 17) ^ v2
+	v2 = bits.RotateLeft64(v2, 32)
+	return v0, v1, v2, v3
+}
+
+// sipInit returns the initial state keyed with secret
+func sipInit(secret *[16]byte) (uint64, uint64, uint64, uint64) {
+	k0 := binary.LittleEndian.Uint64(secret[0:8])
+	k1 := binary.LittleEndian.Uint64(secret[8:16])
+	return k0 ^ 0x736f6d6570736575, k1 ^ 0x646f72616e646f6d, k0 ^ 0x6c7967656e657261, k1 ^ 0x7465646279746573
+}
+
+// sipBlock compresses one message block into the state
+func sipBlock(v0, v1, v2, v3, m uint64) (uint64, uint64, uint64, uint64) {
+	v3 ^= m
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0 ^= m
+	return v0, v1, v2, v3
+}
+
+// sipFinal runs the finalization rounds and folds the state
+func sipFinal(v0, v1, v2, v3 uint64) uint64 {
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// sipHash32 computes SipHash-2-4 of a 4-byte little-endian message
+func sipHash32(secret *[16]byte, key uint32) uint64 {
+	// The only (final) block holds the 4 message bytes and the length
+	v0, v1, v2, v3 := sipInit(secret)
+	v0, v1, v2, v3 = sipBlock(v0, v1, v2, v3, uint64(key)|4<<56)
+	return sipFinal(v0, v1, v2, v3)
+}
+
+// sipHash64 computes SipHash-2-4 of an 8-byte little-endian message
+func sipHash64(secret *[16]byte, word uint64) uint64 {
+	// A full block, then a final block holding only the length
+	v0, v1, v2, v3 := sipInit(secret)
+	v0, v1, v2, v3 = sipBlock(v0, v1, v2, v3, word)
+	v0, v1, v2, v3 = sipBlock(v0, v1, v2, v3, 8<<56)
+	return sipFinal(v0, v1, v2, v3)
+}
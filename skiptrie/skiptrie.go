@@ -2,67 +2,134 @@ package skiptrie
 
 import (
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"sync"
 	"sync/atomic"
-	"unsafe"
+	"time"
 )
 
 const (
-	MaxKey = 1 << 32 // u = 2^32
-	LogLogU = 5      // log log u = 5 for u = 2^32
+	MaxKey    = 1 << 32 // u = 2^32
+	LogLogU   = 5       // log log u = 5 for u = 2^32
+	MaxHeight = 32      // tallest tower WithMaxHeight allows
 )
 
 // Node represents a skiplist node
 type Node struct {
 	key        uint32
-	next       []*atomic.Pointer[Node] // next pointers for each level
-	prev       *atomic.Pointer[Node]    // backward pointer (top level only)
-	back       *atomic.Pointer[Node]    // recovery pointer for deleted nodes
-	marked     atomic.Bool              // logical deletion flag
-	ready      atomic.Bool              // indicates prev pointer is set
-	stop       atomic.Bool              // stop flag for tower operations
-	origHeight int                      // original height of the node
-	down       []*Node                  // pointers to lower level nodes
+	stamp      uint64                // distinguishes this use of the node from earlier ones (ref)
+	next       []nextPtr             // next pointers for each level, marked once unlinking starts
+	prev       *atomic.Pointer[Node] // backward pointer (top level only)
+	back       *atomic.Pointer[Node] // recovery pointer for deleted nodes
+	prevBottom *atomic.Pointer[Node] // bottom-level backward hint (WithReverseLinks only)
+	value      atomic.Pointer[any]   // payload (SkipTrieMap only)
+	flags      atomic.Uint32         // user annotation bits (low 16 bits used)
+	priority   Priority              // eviction class (capacity-bounded mode)
+	dcss       *dcssDesc             // set only on markers installed by dcss
+	marked     atomic.Bool           // logical deletion flag
+	ready      atomic.Bool           // indicates prev pointer is set
+	stop       atomic.Bool           // stop flag for tower operations
+	origHeight int                   // original height of the node
+	expires    atomic.Int64          // expiry in Unix nanoseconds, 0 if none (InsertWithTTL, Cache)
+	indexed    bool                  // published in the x-fast trie
+	handoff    atomic.Uint32         // inserter and deleter done with the node (WithNodeReuse)
 }
 
 // TreeNode represents an x-fast trie node
 type TreeNode struct {
-	pointers [2]*atomic.Pointer[Node] // [0] = largest in 0-subtree, [1] = smallest in 1-subtree
+	pointers [2]atomic.Pointer[Node] // [0] = largest in 0-subtree, [1] = smallest in 1-subtree
 }
 
 // SkipTrie is the main data structure
 type SkipTrie struct {
-	prefixes sync.Map                 // concurrent hash table for x-fast trie
+	prefixes prefixTable              // concurrent hash table for x-fast trie
+	entries  atomic.Int64             // prefixes held in the hash table
 	head     *Node                    // sentinel head of skiplist
 	tail     *Node                    // sentinel tail of skiplist
 	rng      *rand.Rand               // random number generator
+	alloc    Allocator                // source of nodes (WithAllocator)
 	mu       sync.Mutex               // mutex for RNG
+	tier     int                      // pool tier this instance is recycled into
+	levels   int                      // skiplist levels, loglog unless WithMaxHeight
+	keyBits  int                      // bits of the key universe (WithUniverse)
+	loglog   int                      // levels indexed by the trie, log log u of the universe
+	gen      atomic.Uint64            // bumped whenever the contents are replaced wholesale
+	stamps   atomic.Uint64            // last stamp given to a node
+	
+	reverseLinks bool                 // maintain bottom-level backward hints
+	livePred     bool                 // Predecessor is PredecessorLive (WithLivePredecessor)
+	help         HelpPolicy           // whether queries unlink deleted nodes (WithHelpPolicy)
+	heightFn     func(key uint32) int // overrides random tower heights
+	bucketSize   int                  // top-level nodes per trie representative (WithBuckets)
+	
+	size       atomic.Int64                // number of live keys
+	classCount [NumPriorities]atomic.Int64 // live keys per priority class
+	classFrom  [NumPriorities]atomic.Uint32 // no live key of the class is smaller (capacity-bounded mode)
+	capacity   int                         // key limit, 0 if unbounded
+	sweepEvery time.Duration               // interval of the expiry sweep (WithExpirySweep)
+	evictions  [NumPriorities]atomic.Uint64 // keys evicted per priority class
+	
+	waits  waitRegistry   // goroutines blocked in WaitFor
+	bg     workers        // background goroutines
+	expire expireCounters // ExpireWhere progress
+	
+	analysis func(OpReport) // receives queries exceeding the cost model
+	analyzed atomic.Uint64  // queries checked against the cost model
+	flagged  atomic.Uint64  // queries that exceeded it
+	
+	fallbackFn func(FallbackEvent) // receives fallback path activations
+	fallbacks  atomic.Uint64       // fallback paths taken
+	
+	ops     *opCounters // per-operation counters (WithOpStats only)
+	reclaim *reclaimer  // frees deleted nodes for reuse (WithNodeReuse only)
+	order   *orderStats // key counts by range (WithOrderStatistics only)
+	repair  *trieRepair // deferred trie repairs (WithLazyTrieRepair only)
+	
+	changes changeLog // sequenced events for Watch
+	alarms  alarms    // key-count watermarks
+	hooks   testHooks // failure injection (testhooks build tag only)
+	
+	dirty [validateBuckets]atomic.Bool // key ranges changed since the last ValidateDirty
 }
 
 // NewSkipTrie creates a new SkipTrie instance
-func NewSkipTrie() *SkipTrie {
-	st := &SkipTrie{
-		rng: rand.New(rand.NewSource(rand.Int63())),
+func NewSkipTrie(opts ...Option) *SkipTrie {
+	st := &SkipTrie{}
+	for _, opt := range opts {
+		opt(st)
+	}
+	if st.rng == nil {
+		st.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	if st.alloc == nil {
+		st.alloc = HeapAllocator{}
+	}
+	if st.reclaim != nil {
+		if _, ok := st.alloc.(HeapAllocator); ok {
+			st.alloc = NewPoolAllocator()
+		}
+		st.reverseLinks = false
+	}
+	if st.keyBits == 0 {
+		st.keyBits, st.loglog = 32, LogLogU
 	}
+	st.levels = max(st.levels, st.loglog)
 	
 	// Initialize sentinel nodes
 	st.head = &Node{
 		key:        0,
-		next:       make([]*atomic.Pointer[Node], LogLogU),
-		origHeight: LogLogU,
+		next:       make([]nextPtr, st.levels),
+		origHeight: st.levels,
 	}
 	st.tail = &Node{
-		key:        MaxKey,
-		next:       make([]*atomic.Pointer[Node], LogLogU),
-		origHeight: LogLogU,
+		key:        math.MaxUint32,
+		next:       make([]nextPtr, st.levels),
+		origHeight: st.levels,
 	}
 	
 	// Initialize all levels to point from head to tail
-	for i := 0; i < LogLogU; i++ {
-		st.head.next[i] = &atomic.Pointer[Node]{}
+	for i := 0; i < st.levels; i++ {
 		st.head.next[i].Store(st.tail)
-		st.tail.next[i] = &atomic.Pointer[Node]{}
 	}
 	
 	// Initialize top-level prev pointers
@@ -70,173 +137,402 @@ func NewSkipTrie() *SkipTrie {
 	st.tail.prev = &atomic.Pointer[Node]{}
 	st.tail.prev.Store(st.head)
 	
+	if st.reverseLinks {
+		st.head.prevBottom = &atomic.Pointer[Node]{}
+		st.tail.prevBottom = &atomic.Pointer[Node]{}
+		st.tail.prevBottom.Store(st.head)
+	}
+	
+	if st.sweepEvery > 0 {
+		st.startExpirySweep()
+	}
+	if st.repair != nil {
+		st.startTrieRepair()
+	}
 	return st
 }
 
+// reset empties the structure in place, keeping its sentinels and RNG
+// It must not run concurrently with other operations
+func (st *SkipTrie) reset() {
+	st.gen.Add(1)
+	st.freeAll()
+	for i := 0; i < st.levels; i++ {
+		st.head.next[i].Store(st.tail)
+	}
+	st.tail.prev.Store(st.head)
+	if st.reverseLinks {
+		st.tail.prevBottom.Store(st.head)
+	}
+	
+	st.prefixes.reset()
+	st.entries.Store(0)
+	if st.reclaim != nil {
+		st.reclaim.reset()
+	}
+	if st.order != nil {
+		st.order.reset()
+	}
+	if st.repair != nil {
+		st.repair.take()
+	}
+	
+	st.size.Store(0)
+	st.setDirty(false)
+	for class := range st.classCount {
+		st.classCount[class].Store(0)
+		st.evictions[class].Store(0)
+		st.classFrom[class].Store(0)
+	}
+}
+
 // randomHeight generates a random height for a new node
 func (st *SkipTrie) randomHeight() int {
 	st.mu.Lock()
 	defer st.mu.Unlock()
 	
 	height := 1
-	for height < LogLogU && st.rng.Float32() < 0.5 {
+	for height < st.levels && st.rng.Float32() < 0.5 {
 		height++
 	}
 	return height
 }
 
 // listSearch finds the predecessor and successor of a key at a given level
+// The loop has no retry cap: a failed CAS or a stale bracket means another
+// thread changed the list, so every restart follows progress elsewhere
 func (st *SkipTrie) listSearch(key uint32, start *Node, level int) (*Node, *Node) {
-	var left, right *Node
+	left, right := st.search(key, start, level)
+	return left, right.node
+}
+
+// search is listSearch returning the successor as the ref read from the
+// predecessor's pointer, for a CAS on that pointer
+func (st *SkipTrie) search(key uint32, start *Node, level int) (*Node, ref) {
+	var left *Node
+	var right ref
 	
-	for {
+	for attempt := 1; ; attempt++ {
+		if st.ops != nil {
+			st.ops.searchIterations.Add(1)
+		}
 		left = start
-		right = left.next[level].Load()
+		right = left.next[level].LoadRef()
 		
 		// Skip over marked nodes
-		for right != nil && right.marked.Load() {
-			nextRight := right.next[level].Load()
+		for right.node != nil && right.node.marked.Load() {
 			// Try to unlink the marked node
-			if left.next[level].CompareAndSwap(right, nextRight) {
+			if nextRight, ok := st.unlink(left, right, level); ok {
 				right = nextRight
 			} else {
 				// Retry if CAS failed
+				st.countCASRetry()
 				break
 			}
 		}
 		
 		// Find the correct position
-		for right != nil && right.key < key && !right.marked.Load() {
-			left = right
-			right = left.next[level].Load()
+		for right.node != nil && right.node.key < key && !right.node.marked.Load() {
+			left = right.node
+			right = left.next[level].LoadRef()
 			
 			// Skip marked nodes again
-			for right != nil && right.marked.Load() {
-				nextRight := right.next[level].Load()
-				if left.next[level].CompareAndSwap(right, nextRight) {
+			for right.node != nil && right.node.marked.Load() {
+				if nextRight, ok := st.unlink(left, right, level); ok {
 					right = nextRight
 				} else {
+					st.countCASRetry()
 					break
 				}
 			}
 		}
 		
 		// Verify we have a valid bracket
-		if right == nil || !right.marked.Load() {
-			leftNext := left.next[level].Load()
-			if leftNext == right && !left.marked.Load() {
+		if right.node == nil || !right.node.marked.Load() {
+			if left.next[level].LoadRef() == right && !left.marked.Load() {
+				st.fenceObserve("link", left)
+				st.fenceObserve("link", right.node)
 				return left, right
 			}
 		}
+		
+		// Restart from head if start itself was deleted meanwhile
+		if start.marked.Load() {
+			start = st.head
+			st.fallback(FallbackSearchRestart, key, level, attempt)
+		}
+	}
+}
+
+// towerHeight picks the height of a new node holding key
+func (st *SkipTrie) towerHeight(key uint32) int {
+	height, forced := st.hooks.forcedHeight()
+	switch {
+	case forced:
+	case st.heightFn == nil:
+		return st.randomHeight()
+	default:
+		height = st.heightFn(key)
 	}
+	return max(1, min(height, st.levels))
 }
 
-// skiplistInsert inserts a key into the skiplist
-func (st *SkipTrie) skiplistInsert(key uint32) *Node {
-	height := st.randomHeight()
+// skiplistInsert inserts a key into the skiplist, calling init on the new
+// node before it is linked; searches start from fing when it is non-nil
+// If the key already exists the existing node is returned with false
+func (st *SkipTrie) skiplistInsert(key uint32, init func(*Node), fing *finger) (*Node, bool) {
+	height := st.towerHeight(key)
 	
 	// Create new node
-	newNode := &Node{
-		key:        key,
-		next:       make([]*atomic.Pointer[Node], height),
-		origHeight: height,
-		down:       make([]*Node, height),
+	newNode := st.alloc.New()
+	newNode.key = key
+	newNode.stamp = st.stamps.Add(1)
+	newNode.next = make([]nextPtr, height)
+	newNode.origHeight = height
+	newNode.indexed = height >= st.loglog && st.representative()
+	if init != nil {
+		init(newNode)
 	}
 	
 	// Initialize atomic pointers
-	for i := 0; i < height; i++ {
-		newNode.next[i] = &atomic.Pointer[Node]{}
-	}
-	if height == LogLogU {
+	if height >= st.loglog {
 		newNode.prev = &atomic.Pointer[Node]{}
 		newNode.back = &atomic.Pointer[Node]{}
 	}
+	if st.reverseLinks {
+		newNode.prevBottom = &atomic.Pointer[Node]{}
+	}
 	
 	// Find insertion points at each level
-	preds := make([]*Node, height)
-	succs := make([]*Node, height)
+	// On the stack unless WithMaxHeight allows taller towers
+	var predBuf [LogLogU]*Node
+	var succBuf [LogLogU]ref
+	preds, succs := predBuf[:], succBuf[:]
+	if height > LogLogU {
+		preds, succs = make([]*Node, height), make([]ref, height)
+	}
 	
+	// Descend from the head, carrying each level's predecessor down to the
+	// next and jumping ahead to the finger, or to the trie predecessor on
+	// the levels its tower spans, when that is closer to key
+	hint := st.head
+	if fing == nil {
+		if pred := st.xFastTriePred(key, nil); pred != nil && !pred.marked.Load() && pred.key < key {
+			hint = pred
+		}
+	}
 	start := st.head
-	for level := LogLogU - 1; level >= 0; level-- {
+	for level := st.levels - 1; level >= 0; level-- {
+		from := start
+		if fing != nil {
+			hint = fing.start(st, level, key)
+		}
+		if hint != st.head && level < hint.origHeight && (from == st.head || hint.key > from.key) {
+			from = hint
+		}
+		left, right := st.search(key, from, level)
+		if right.node != nil && right.node.key == key {
+			// Key already exists
+			st.alloc.Free(newNode)
+			return right.node, false
+		}
+		start = left
 		if level < height {
-			left, right := st.listSearch(key, start, level)
-			if right != nil && right.key == key {
-				// Key already exists
-				return nil
-			}
 			preds[level] = left
 			succs[level] = right
 		}
-		if level > 0 && start.down != nil && len(start.down) > level-1 {
-			start = start.down[level-1]
-		}
 	}
 	
 	// Insert from bottom to top
 	for level := 0; level < height; level++ {
 		for {
 			if newNode.stop.Load() {
-				return newNode
+				return newNode, true
 			}
 			
-			newNode.next[level].Store(succs[level])
-			if preds[level].next[level].CompareAndSwap(succs[level], newNode) {
+			st.hooks.pause(PauseInsertSearched, key)
+			
+			// Fails once a deleter has marked the level
+			if !newNode.next[level].Set(succs[level]) {
+				return newNode, true
+			}
+			st.fencePublish("link", newNode)
+			if !st.hooks.failCAS() && st.link(preds[level], succs[level], newNode, level) {
+				st.fenceLinked(newNode, level)
+				if level == 0 && st.reverseLinks {
+					st.linkBottomPrev(preds[0], newNode, succs[0].node)
+				}
+				if newNode.marked.Load() {
+					// The deleter may have searched this level before the
+					// link, so unlink the node rather than leave it behind
+					st.listSearch(key, preds[level], level)
+				}
+				if level == 0 {
+					st.hooks.pause(PauseInsertLinked, key)
+				}
 				break
 			}
+			st.countCASRetry()
 			
 			// Retry with updated positions
-			left, right := st.listSearch(key, preds[level], level)
-			if right != nil && right.key == key {
-				return nil
+			left, right := st.search(key, preds[level], level)
+			if right.node != nil && right.node.key == key {
+				if level == 0 {
+					st.alloc.Free(newNode) // never linked
+					return right.node, false
+				}
+				// Another node for key rose past the bottom level, so this
+				// one was deleted there; its insertion still took effect
+				return newNode, true
 			}
 			preds[level] = left
 			succs[level] = right
 		}
 	}
 	
-	// Set prev pointer for top-level nodes
-	if height == LogLogU {
-		st.fixPrev(preds[LogLogU-1], newNode)
+	// Set prev pointer for top-level nodes, and point the successor back
+	// at the new node
+	if top := st.loglog - 1; height > top {
+		st.fixPrev(preds[top], newNode)
+		st.fixPrev(newNode, newNode.next[top].Load())
+	}
+	
+	if fing != nil {
+		for level := 0; level < height; level++ {
+			fing[level] = newNode
+		}
 	}
 	
-	return newNode
+	return newNode, true
 }
 
 // fixPrev sets the prev pointer of a node
+// The pointer is only set while its left neighbour still links to node, so
+// the top level stays a consistent doubly linked list under contention
+// Like listSearch it retries until it succeeds or node is deleted; each
+// failed DCSS is caused by a concurrent change to left or node.prev
 func (st *SkipTrie) fixPrev(pred *Node, node *Node) {
-	for !node.marked.Load() {
-		left, right := st.listSearch(node.key, pred, LogLogU-1)
+	top := st.loglog - 1
+	for attempt := 1; !node.marked.Load(); attempt++ {
+		left, right := st.listSearch(node.key, pred, top)
 		if right == node {
-			node.prev.Store(left)
-			node.ready.Store(true)
-			return
+			old := st.loadPrev(node)
+			st.hooks.pause(PauseFixPrev, node.key)
+			st.fencePublish("prev", left)
+			if old == left || dcss(&left.next[top], node, node.prev, old, left) {
+				node.ready.Store(true)
+				st.fenceReady(node)
+				return
+			}
+			if _, marked := left.next[top].LoadMarked(); marked {
+				// left is being unlinked; search again for its replacement
+				st.fallback(FallbackPrevRetry, node.key, top, attempt)
+			}
 		}
 		pred = left
 	}
 }
 
+// loadPrev returns the prev pointer of node, helping any DCSS in progress
+func (st *SkipTrie) loadPrev(node *Node) *Node {
+	for {
+		prev := node.prev.Load()
+		if prev == nil || prev.dcss == nil {
+			st.fenceReady(node)
+			st.fenceObserve("prev", prev)
+			return prev
+		}
+		prev.dcss.complete()
+	}
+}
+
+// markNode marks node deleted and stops its tower from rising, returning
+// false if another deleter marked it first
+// A top-level node first gets its back pointer aimed at its prev, so that
+// trie walks reaching the marked node step back to a smaller key; the
+// pointer is moved to the node's left neighbour as it is unlinked
+func (st *SkipTrie) markNode(node *Node) bool {
+	if node.back != nil && !node.marked.Load() {
+		back := st.loadPrev(node)
+		if back == nil {
+			back = st.head // prev not set yet
+		}
+		node.back.Store(back)
+	}
+	if !st.markDeleted(node) {
+		return false
+	}
+	node.stop.Store(true)
+	return true
+}
+
+// setBack points the back pointer of a marked node at left before the
+// node is unlinked from the top level behind it
+// left is a top-level node or the head, which are never recycled, so the
+// pointer stays valid for as long as the trie may lead to node
+func (st *SkipTrie) setBack(node, left *Node, level int) {
+	if level == st.loglog-1 && node.back != nil {
+		node.back.Store(left)
+	}
+}
+
+// link swings the pointer of pred at level from old to node, linking it
+// there; the bottom-level link is where the insert takes effect, so it
+// goes through linkBottom to be sequenced in the changelog
+func (st *SkipTrie) link(pred *Node, old ref, node *Node, level int) bool {
+	if level == 0 {
+		return st.linkBottom(pred, old, node)
+	}
+	return pred.next[level].CompareAndSwap(old, refOf(node))
+}
+
+// unlink swings the pointer of left at level past its marked successor
+// old, returning the new successor and whether the CAS succeeded
+// Every unlink goes through here so that none skips the repairs that
+// follow: the node's pointer is frozen first so nothing is linked behind
+// it, its back pointer moves to left, and the successor's prev (at the top
+// level) or bottom-level hint is pointed past it
+func (st *SkipTrie) unlink(left *Node, old ref, level int) (ref, bool) {
+	node := old.node
+	next := node.next[level].Mark()
+	st.setBack(node, left, level)
+	if !left.next[level].CompareAndSwap(old, next) {
+		return next, false
+	}
+	if level == 0 && st.reverseLinks {
+		next.node.prevBottom.CompareAndSwap(node, left)
+	}
+	if level == st.loglog-1 {
+		st.fixPrev(left, next.node)
+	}
+	return next, true
+}
+
 // skiplistDelete deletes a node from the skiplist
 func (st *SkipTrie) skiplistDelete(node *Node) bool {
-	// Mark the node
-	if !node.marked.CompareAndSwap(false, true) {
+	// Mark the node and stop its tower from rising
+	if !st.markNode(node) {
 		return false // Already deleted
 	}
+	st.hooks.pause(PauseDeleteMarked, node.key)
 	
-	// Set stop flag to prevent further tower raising
-	node.stop.Store(true)
-	
-	// Remove from all levels top-down
+	// Remove from all levels top-down, starting from the trie predecessor
+	// and carrying each level's left neighbour down to the next
+	start := st.xFastTriePred(node.key, nil)
+	if start == nil || start.marked.Load() || start.origHeight < node.origHeight {
+		start = st.head
+	}
 	for level := node.origHeight - 1; level >= 0; level-- {
 		for {
-			left, right := st.listSearch(node.key, st.head, level)
-			if right != node {
-				break // Already removed from this level
+			left, right := st.search(node.key, start, level)
+			start = left
+			if right.node != node {
+				break // A helping search already unlinked it from this level
 			}
-			
-			next := node.next[level].Load()
-			if left.next[level].CompareAndSwap(node, next) {
+			if _, ok := st.unlink(left, right, level); ok {
 				break
 			}
+			st.countCASRetry()
 		}
 	}
 	
@@ -244,114 +540,110 @@ func (st *SkipTrie) skiplistDelete(node *Node) bool {
 }
 
 // xFastTriePred finds the predecessor in the x-fast trie
-func (st *SkipTrie) xFastTriePred(key uint32) *Node {
-	curr := st.lowestAncestor(key)
-	
-	// Traverse backward if necessary
-	for curr != nil && curr.key > key {
-		if curr.marked.Load() {
-			if curr.back != nil {
-				curr = curr.back.Load()
-			} else {
-				break
-			}
-		} else if curr.prev != nil {
-			curr = curr.prev.Load()
-		} else {
+func (st *SkipTrie) xFastTriePred(key uint32, tr *opTrace) *Node {
+	if st.hooks.missTrie() {
+		st.fallback(FallbackTrieDeadEnd, key, -1, 1)
+		return nil
+	}
+	
+	curr := st.lowestAncestor(key, tr)
+	
+	// Traverse backward if necessary: along back pointers past deleted
+	// nodes, and prev pointers past live ones not below key
+	for curr != nil {
+		marked := curr.marked.Load()
+		if !marked && (curr.key < key || curr.prev == nil) {
 			break
 		}
+		if tr != nil {
+			tr.backSteps++
+		}
+		if !marked {
+			curr = st.loadPrev(curr)
+			continue
+		}
+		
+		if st.repair != nil {
+			st.repair.kick() // a stale entry may be waiting for the worker
+		}
+		if curr.back != nil {
+			curr = curr.back.Load()
+		} else {
+			curr = nil
+		}
+		if curr == nil {
+			st.fallback(FallbackTrieDeadEnd, key, -1, 1)
+		}
 	}
 	
 	return curr
 }
 
-// lowestAncestor performs binary search on prefix length
-func (st *SkipTrie) lowestAncestor(key uint32) *Node {
-	var ancestor *Node
-	
-	// Start with empty prefix
-	if val, ok := st.prefixes.Load(""); ok {
-		tn := val.(*TreeNode)
-		direction := 0
-		if key&(1<<31) != 0 {
-			direction = 1
+// lowestAncestor finds the longest prefix of key held by the trie, by
+// binary search on the prefix length, and returns the indexed node next
+// to key that it points at, or head if no prefix of key is held
+// The subtree of the held prefix on key's side is empty (or key itself
+// is indexed), so the pointer into the other subtree is next to key: the
+// largest key of the 0-subtree is below key, and the smallest key of the
+// 1-subtree is above it and its prev is below
+func (st *SkipTrie) lowestAncestor(key uint32, tr *opTrace) *Node {
+	// Lengths up to lo are held; lengths above hi are not. The root, the
+	// prefix shared by the whole universe, is probed last, as only an
+	// empty trie lacks it
+	var found *TreeNode
+	root := 32 - st.keyBits
+	lo, hi := root, 32
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tr != nil {
+			tr.trieProbes++
 		}
-		if tn.pointers[direction] != nil {
-			ancestor = tn.pointers[direction].Load()
+		if tn, ok := st.loadPrefix(prefixOf(key, mid)); ok {
+			found, lo = tn, mid
+		} else {
+			hi = mid - 1
 		}
 	}
-	
-	// Binary search on prefix length
-	commonPrefix := ""
-	start := 0
-	size := 16 // log u / 2 for u = 2^32
-	
-	for size > 0 {
-		// Create query prefix
-		query := st.extractPrefix(key, start, start+size)
-		if commonPrefix != "" {
-			query = commonPrefix + query
+	if found == nil {
+		if tr != nil {
+			tr.trieProbes++
 		}
-		
-		if val, ok := st.prefixes.Load(query); ok {
-			tn := val.(*TreeNode)
-			
-			// Determine direction for next bit
-			direction := 0
-			if start+size < 32 && (key&(1<<(31-start-size))) != 0 {
-				direction = 1
-			}
-			
-			if tn.pointers[direction] != nil {
-				candidate := tn.pointers[direction].Load()
-				if candidate != nil && st.isPrefixOf(query, candidate.key) {
-					if ancestor == nil || st.distance(key, candidate.key) < st.distance(key, ancestor.key) {
-						ancestor = candidate
-					}
-					commonPrefix = query
-					start = start + size
-				}
-			}
+		tn, ok := st.loadPrefix(prefixOf(key, root))
+		if !ok {
+			return st.head
 		}
-		
-		size = size / 2
+		found = tn
 	}
 	
-	if ancestor == nil {
-		return st.head
+	// The bit of key after the prefix; a full-length prefix is key itself,
+	// stored under 0
+	side := 0
+	if lo < 32 {
+		side = int(key >> (31 - lo) & 1)
 	}
-	return ancestor
-}
-
-// extractPrefix extracts bits from start to end (exclusive) as a string
-func (st *SkipTrie) extractPrefix(key uint32, start, end int) string {
-	if end > 32 {
-		end = 32
+	candidate := found.pointers[1-side].Load()
+	if lo == 32 || candidate == nil {
+		// Racing updates may leave only the pointer on key's side
+		candidate = found.pointers[side].Load()
 	}
-	
-	result := ""
-	for i := start; i < end; i++ {
-		if key&(1<<(31-i)) != 0 {
-			result += "1"
-		} else {
-			result += "0"
-		}
+	st.fenceObserve("trie", candidate)
+	if candidate == nil {
+		return st.head
 	}
-	return result
+	return candidate
 }
 
-// isPrefixOf checks if prefix is a prefix of key
-func (st *SkipTrie) isPrefixOf(prefix string, key uint32) bool {
-	for i, bit := range prefix {
-		keyBit := (key >> (31 - i)) & 1
-		if bit == '0' && keyBit != 0 {
-			return false
-		}
-		if bit == '1' && keyBit != 1 {
-			return false
+// loadPrefix looks up a prefix in the trie's hash table
+func (st *SkipTrie) loadPrefix(p prefix) (*TreeNode, bool) {
+	tn, ok := st.prefixes.load(p)
+	if st.ops != nil {
+		if ok {
+			st.ops.trieHits.Add(1)
+		} else {
+			st.ops.trieMisses.Add(1)
 		}
 	}
-	return true
+	return tn, ok
 }
 
 // distance calculates the distance between two keys
@@ -363,42 +655,70 @@ func (st *SkipTrie) distance(a, b uint32) uint32 {
 }
 
 // Insert inserts a key into the SkipTrie
+// math.MaxUint32 is reserved as the key of the tail sentinel and is never
+// stored: Insert returns false for it, and TryInsert ErrReservedKey
 func (st *SkipTrie) Insert(key uint32) bool {
-	node := st.skiplistInsert(key)
-	if node == nil {
-		return false // Key already exists
+	_, inserted := st.insertNode(key, nil)
+	return inserted
+}
+
+// insertNode inserts a key whose node is prepared by init, returning the
+// existing node with false if the key is already present
+func (st *SkipTrie) insertNode(key uint32, init func(*Node)) (*Node, bool) {
+	return st.insertNodeFrom(key, init, nil)
+}
+
+// insertNodeFrom is insertNode with searches starting from fing
+func (st *SkipTrie) insertNodeFrom(key uint32, init func(*Node), fing *finger) (*Node, bool) {
+	defer st.unpin(st.pin())
+	
+	node, inserted := st.skiplistInsert(key, init, fing)
+	if !inserted {
+		return node, false // Key already exists
 	}
 	
-	// If node reached top level, insert into x-fast trie
-	if node.origHeight == LogLogU {
+	// If node is a top-level representative, insert into x-fast trie
+	if node.indexed {
 		st.insertIntoTrie(node)
 	}
 	
-	return true
+	st.checkAlarms(st.size.Add(1))
+	st.markDirty(key)
+	st.classCount[node.priority].Add(1)
+	if st.order != nil {
+		st.order.add(key, 1)
+	}
+	if st.ops != nil {
+		st.ops.inserts.Add(1)
+	}
+	if st.capacity > 0 {
+		st.lowerClassFrom(node)
+		st.evict(node)
+	}
+	st.waits.notify(key)
+	st.handoff(node, handoffInserted)
+	
+	return node, true
 }
 
 // insertIntoTrie inserts a top-level node into the x-fast trie
 func (st *SkipTrie) insertIntoTrie(node *Node) {
-	// Insert all prefixes of the key
-	for i := 31; i >= 0; i-- {
-		prefix := st.extractPrefix(node.key, 0, i+1)
+	// Insert every prefix of the key down to the universe's root
+	for i := 31; i >= 31-st.keyBits; i-- {
+		prefix := prefixOf(node.key, i+1)
 		direction := 0
 		if i < 31 && (node.key&(1<<(30-i))) != 0 {
 			direction = 1
 		}
 		
 		for !node.marked.Load() {
-			val, loaded := st.prefixes.LoadOrStore(prefix, &TreeNode{
-				pointers: [2]*atomic.Pointer[Node]{
-					&atomic.Pointer[Node]{},
-					&atomic.Pointer[Node]{},
-				},
-			})
-			
-			tn := val.(*TreeNode)
+			st.hooks.pause(PauseTrieInsert, node.key)
+			st.fencePublish("trie", node)
+			tn, loaded := st.prefixes.loadOrStore(prefix, &TreeNode{})
 			
 			if !loaded {
 				// New entry created
+				st.entries.Add(1)
 				tn.pointers[direction].Store(node)
 				break
 			}
@@ -427,52 +747,85 @@ func (st *SkipTrie) insertIntoTrie(node *Node) {
 
 // Delete deletes a key from the SkipTrie
 func (st *SkipTrie) Delete(key uint32) bool {
-	// Find the node
-	pred := st.Predecessor(key - 1)
-	curr := pred
+	return st.deleteKey(key) == nil
+}
+
+// deleteKey implements Delete, returning ErrNotFound if key is absent and
+// ErrContention if a concurrent deleter removed it first
+func (st *SkipTrie) deleteKey(key uint32) error {
+	defer st.unpin(st.pin())
 	
-	// Search for exact key
-	for curr != nil && curr.key < key {
-		curr = curr.next[0].Load()
+	// The trie predecessor is strictly smaller than key, so a bottom-level
+	// search from it brackets the node without scanning from the head
+	start := st.Predecessor(key)
+	if start == nil || start.marked.Load() {
+		start = st.head
 	}
 	
-	if curr == nil || curr.key != key {
-		return false // Key not found
+	_, node := st.listSearch(key, start, 0)
+	if node == nil || node == st.tail || node.key != key {
+		return ErrNotFound
 	}
 	
+	if !st.deleteNode(node) {
+		return ErrContention
+	}
+	return nil
+}
+
+// deleteNode removes node from the skiplist and the trie
+// It returns false if another deleter got there first
+func (st *SkipTrie) deleteNode(node *Node) bool {
 	// Delete from skiplist
-	if !st.skiplistDelete(curr) {
+	if !st.skiplistDelete(node) {
 		return false
 	}
 	
-	// If it was a top-level node, update the trie
-	if curr.origHeight == LogLogU {
-		st.deleteFromTrie(curr)
+	st.retire(node)
+	return true
+}
+
+// retire updates the trie and counters once node is marked and unlinked
+func (st *SkipTrie) retire(node *Node) {
+	// If it was a top-level representative, update the trie
+	if node.indexed {
+		st.hooks.pause(PauseTrieDelete, node.key)
+		if st.repair == nil || !st.repair.push(node) {
+			st.deleteFromTrie(node)
+		}
 	}
 	
-	return true
+	st.checkAlarms(st.size.Add(-1))
+	st.markDirty(node.key)
+	st.classCount[node.priority].Add(-1)
+	if st.order != nil {
+		st.order.add(node.key, -1)
+	}
+	if st.ops != nil {
+		st.ops.deletes.Add(1)
+	}
+	st.handoff(node, handoffDeleted)
 }
 
 // deleteFromTrie removes references to a deleted node from the x-fast trie
 func (st *SkipTrie) deleteFromTrie(node *Node) {
-	for i := 0; i < 32; i++ {
-		prefix := st.extractPrefix(node.key, 0, i+1)
+	for i := 31 - st.keyBits; i < 32; i++ {
+		prefix := prefixOf(node.key, i+1)
 		direction := 0
 		if i < 31 && (node.key&(1<<(30-i))) != 0 {
 			direction = 1
 		}
 		
-		val, ok := st.prefixes.Load(prefix)
+		tn, ok := st.prefixes.load(prefix)
 		if !ok {
 			continue
 		}
 		
-		tn := val.(*TreeNode)
 		curr := tn.pointers[direction].Load()
 		
 		for curr == node {
 			// Find replacement
-			left, right := st.listSearch(node.key, st.head, LogLogU-1)
+			left, right := st.indexedAround(st.listSearch(node.key, st.head, st.loglog-1))
 			
 			var replacement *Node
 			if direction == 0 {
@@ -481,7 +834,8 @@ func (st *SkipTrie) deleteFromTrie(node *Node) {
 				replacement = right
 			}
 			
-			if replacement != nil && st.isPrefixOf(prefix, replacement.key) {
+			if replacement != nil && replacement != st.head && replacement != st.tail &&
+				prefix.covers(replacement.key) {
 				tn.pointers[direction].CompareAndSwap(curr, replacement)
 			} else {
 				// Subtree is empty
@@ -493,39 +847,76 @@ func (st *SkipTrie) deleteFromTrie(node *Node) {
 		
 		// If both pointers are nil, remove the entry
 		if tn.pointers[0].Load() == nil && tn.pointers[1].Load() == nil {
-			st.prefixes.Delete(prefix)
+			if st.prefixes.delete(prefix) {
+				st.entries.Add(-1)
+			}
 		}
 	}
 }
 
-// Predecessor finds the predecessor of a key
+// Predecessor finds the predecessor of a key, whose Key and Valid methods
+// read it
+// The node may be deleted by the time it is returned, or may already be
+// marked for deletion when the search passes it; PredecessorLive, or
+// WithLivePredecessor, only returns keys that were present
+// With WithNodeReuse the Node may be recycled once the call returns, unless
+// the caller is itself inside an operation that holds it
 func (st *SkipTrie) Predecessor(key uint32) *Node {
-	// Start from x-fast trie
-	start := st.xFastTriePred(key)
-	if start == nil {
+	defer st.unpin(st.pin())
+	
+	if st.livePred {
+		return st.predecessorLive(key)
+	}
+	if st.analysis == nil {
+		return st.predecessor(key, nil)
+	}
+	
+	var tr opTrace
+	node := st.predecessor(key, &tr)
+	st.analyze("Predecessor", key, &tr)
+	return node
+}
+
+// predecessor implements Predecessor, counting its work in tr if non-nil
+func (st *SkipTrie) predecessor(key uint32, tr *opTrace) *Node {
+	// Start from x-fast trie; a deleted node's frozen pointers may lead to
+	// nodes WithNodeReuse has recycled since
+	start := st.xFastTriePred(key, tr)
+	if start == nil || start.marked.Load() {
 		start = st.head
 	}
 	
-	// Search through skiplist
+	// Search through skiplist, from the top of the start node's tower
 	curr := start
-	for level := LogLogU - 1; level >= 0; level-- {
+	for level := start.origHeight - 1; level >= 0; level-- {
 		for {
-			next := curr.next[level].Load()
-			if next == nil || next.key >= key {
+			next := curr.next[level].LoadRef()
+			if next.node == nil || next.node.key >= key {
 				break
 			}
-			if !next.marked.Load() {
-				curr = next
-			} else {
-				// Skip marked node
-				nextNext := next.next[level].Load()
-				curr.next[level].CompareAndSwap(next, nextNext)
+			if tr != nil {
+				tr.listHops++
+			}
+			if !next.node.marked.Load() {
+				curr = next.node
+			} else if !st.readerHelps() {
+				// Step over without unlinking: the frozen pointers of
+				// deleted nodes still lead forward
+				skip := pastMarked(next.node, level)
+				if skip == nil || skip.key >= key {
+					break
+				}
+				curr = skip
+			} else if _, ok := st.unlink(curr, next, level); !ok {
+				// Skip marked node; if curr is being unlinked too its
+				// pointer is frozen, so restart from the head
+				if _, marked := curr.next[level].LoadMarked(); marked {
+					curr = st.head
+					level = st.levels - 1
+				}
 			}
 		}
-		
-		if level > 0 && curr.down != nil && len(curr.down) > level-1 {
-			curr = curr.down[level-1]
-		}
+		// curr carries all of its levels, so descending keeps the same node
 	}
 	
 	if curr == st.head {
@@ -536,30 +927,102 @@ func (st *SkipTrie) Predecessor(key uint32) *Node {
 
 // Contains checks if a key exists in the SkipTrie
 func (st *SkipTrie) Contains(key uint32) bool {
+	defer st.unpin(st.pin())
+	
+	return st.findNode(key) != nil
+}
+
+// Len returns the number of keys in the SkipTrie
+func (st *SkipTrie) Len() int {
+	return int(st.size.Load())
+}
+
+// findNode returns the live node holding key, or nil
+func (st *SkipTrie) findNode(key uint32) *Node {
 	pred := st.Predecessor(key)
-	if pred == nil {
-		curr := st.head.next[0].Load()
-		return curr != nil && curr.key == key && !curr.marked.Load()
+	if pred == nil || pred.marked.Load() {
+		pred = st.head
 	}
 	
+	// Step over keys linked after pred since the predecessor query
 	next := pred.next[0].Load()
-	return next != nil && next.key == key && !next.marked.Load()
+	for next != nil && next != st.tail && next.key < key {
+		next = next.next[0].Load()
+	}
+	if next == nil || next == st.tail || next.key != key || next.marked.Load() {
+		return nil
+	}
+	if next.expires.Load() != 0 && next.expired(time.Now().UnixNano()) {
+		st.deleteNode(next)
+		return nil
+	}
+	return next
+}
+
+// ascend calls fn for each live node with key in [lo, hi] in ascending order
+// until fn returns false
+func (st *SkipTrie) ascend(lo, hi uint32, fn func(*Node) bool) {
+	defer st.unpin(st.pin())
+	
+	start := st.Predecessor(lo)
+	if start == nil {
+		start = st.head
+	}
+	
+	for curr := start.next[0].Load(); curr != nil && curr != st.tail && curr.key <= hi; curr = curr.next[0].Load() {
+		if curr.key < lo || curr.marked.Load() {
+			continue
+		}
+		if !fn(curr) {
+			return
+		}
+	}
 }
 
-// Helper function for CAS operations on pointers
-func cas(ptr **Node, old, new *Node) bool {
-	return atomic.CompareAndSwapPointer(
-		(*unsafe.Pointer)(unsafe.Pointer(ptr)),
-		unsafe.Pointer(old),
-		unsafe.Pointer(new),
-	)
+// dcssDesc describes a pending restricted double-compare single-swap:
+// target goes from old to new provided guard still holds expect unmarked
+type dcssDesc struct {
+	guard   *nextPtr
+	expect  *Node
+	target  *atomic.Pointer[Node]
+	old     *Node
+	new     *Node
+	marker  *Node        // stand-in installed in target while pending
+	outcome atomic.Int32 // 0 pending, 1 succeeded, 2 failed
 }
 
-// DCSS simulates double-compare-single-swap
-// In production, this would need more sophisticated implementation
-func dcss(target **Node, oldTarget, newTarget *Node, guard *atomic.Bool, guardValue bool) bool {
-	if guard.Load() != guardValue {
-		return false
+// dcss atomically sets target from old to new if guard holds expect
+// A marker node carrying the descriptor is first swapped into target, which
+// locks it against other writers; the guard is then read and whoever
+// completes the descriptor first decides the outcome, so helpers agree
+func dcss(guard *nextPtr, expect *Node, target *atomic.Pointer[Node], old, new *Node) bool {
+	d := &dcssDesc{guard: guard, expect: expect, target: target, old: old, new: new}
+	d.marker = &Node{dcss: d}
+	
+	for !target.CompareAndSwap(old, d.marker) {
+		curr := target.Load()
+		if curr == nil || curr.dcss == nil {
+			return false // target no longer holds old
+		}
+		curr.dcss.complete()
+	}
+	
+	return d.complete()
+}
+
+// complete finishes a pending DCSS on behalf of its owner or a helper and
+// reports whether it succeeded
+func (d *dcssDesc) complete() bool {
+	decision := int32(2)
+	if next, marked := d.guard.LoadMarked(); next == d.expect && !marked {
+		decision = 1
+	}
+	d.outcome.CompareAndSwap(0, decision)
+	
+	if d.outcome.Load() == 1 {
+		d.target.CompareAndSwap(d.marker, d.new)
+		return true
 	}
-	return cas(target, oldTarget, newTarget)
+	d.target.CompareAndSwap(d.marker, d.old)
+	return false
 }
\ No newline at end of file
@@ -0,0 +1,60 @@
+package skiptrie
+
+// Stats is a point-in-time snapshot of SkipTrie counters
+type Stats struct {
+	Len        int                          // live keys
+	Capacity   int                          // key limit, 0 if unbounded
+	Evictions  uint64                       // keys removed to respect Capacity
+	ByPriority [NumPriorities]PriorityStats // per-class breakdown
+	
+	ExpireRunning int    // ExpireWhere passes in progress
+	ExpireScanned uint64 // keys tested by ExpireWhere predicates
+	ExpireDeleted uint64 // keys deleted by ExpireWhere
+	
+	AnalyzedOps uint64 // queries checked against the cost model (WithAnalysis)
+	FlaggedOps  uint64 // queries that exceeded it
+	
+	Fallbacks uint64 // fallback paths taken (see WithFallbackEvents)
+	
+	TrieEntries    int // prefixes held by the x-fast trie (see WithBuckets)
+	PendingRepairs int // deleted nodes awaiting trie repair (WithLazyTrieRepair)
+	
+	WatchDrops uint64 // events discarded by bounded watchers (see WithBuffer)
+	
+	Ops OpStats // per-operation counters, zero unless WithOpStats
+}
+
+// PriorityStats holds the counters of one priority class
+type PriorityStats struct {
+	Len       int    // live keys in the class
+	Evictions uint64 // keys of the class removed to respect Capacity
+}
+
+// Stats returns a snapshot of the SkipTrie counters
+func (st *SkipTrie) Stats() Stats {
+	stats := Stats{
+		Len:           st.Len(),
+		Capacity:      st.capacity,
+		ExpireRunning: int(st.expire.running.Load()),
+		ExpireScanned: st.expire.scanned.Load(),
+		ExpireDeleted: st.expire.deleted.Load(),
+		AnalyzedOps:   st.analyzed.Load(),
+		FlaggedOps:    st.flagged.Load(),
+		Fallbacks:     st.fallbacks.Load(),
+		TrieEntries:   int(st.entries.Load()),
+		WatchDrops:    st.changes.drops.Load(),
+		Ops:           st.opStats(),
+	}
+	if st.repair != nil {
+		stats.PendingRepairs = st.repair.len()
+	}
+	for class := range stats.ByPriority {
+		evicted := st.evictions[class].Load()
+		stats.ByPriority[class] = PriorityStats{
+			Len:       int(st.classCount[class].Load()),
+			Evictions: evicted,
+		}
+		stats.Evictions += evicted
+	}
+	return stats
+}
@@ -0,0 +1,445 @@
+//go:build stress
+
+package skiptrie
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StressConfig sizes a stress run; zero fields take the defaults
+type StressConfig struct {
+	Goroutines int           // concurrent workers, default 2*GOMAXPROCS (at least 4)
+	Keys       int           // size of the key space, default 64
+	Duration   time.Duration // length of the run, default 200ms
+}
+
+// withDefaults fills in the zero fields of c
+func (c StressConfig) withDefaults() StressConfig {
+	if c.Goroutines <= 0 {
+		c.Goroutines = max(4, 2*runtime.GOMAXPROCS(0))
+	}
+	if c.Keys <= 0 {
+		c.Keys = 64
+	}
+	if c.Duration <= 0 {
+		c.Duration = 200 * time.Millisecond
+	}
+	return c
+}
+
+// stressRun runs body on n goroutines until d has passed or one of them
+// returns an error, which it returns
+func stressRun(n int, d time.Duration, body func(g int, done func() bool) error) error {
+	deadline := time.Now().Add(d)
+	var failed atomic.Bool
+	done := func() bool {
+		return failed.Load() || time.Now().After(deadline)
+	}
+	
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[g] = body(g, done); errs[g] != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StressMarkUnlink hammers a small key space with inserts and deletes so
+// that neighbouring nodes are marked and unlinked at the same time, while
+// readers run searches through the half-unlinked stretches
+// Each key is written by a single goroutine, so every result is checked
+// against the writer's own view, and the final set against all of them
+func StressMarkUnlink(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie()
+	return stressOwned(st, cfg)
+}
+
+// StressTrieRepair is StressMarkUnlink with every node at the top level,
+// so each insert and delete also updates the x-fast trie, and deletes
+// repair prefix entries while concurrent inserts claim the same ones
+func StressTrieRepair(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithHeightFunc(func(uint32) int { return LogLogU }))
+	return stressOwned(st, cfg)
+}
+
+// StressTallTowers is StressMarkUnlink with towers up to 12 levels tall,
+// derived from the trailing zeros of each key, so inserts descend through
+// the express levels above the trie and link every level of tall towers
+// while neighbours on each level come and go; the final structure check
+// verifies the linkage of every level
+func StressTallTowers(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithMaxHeight(12), WithHeightFunc(func(key uint32) int {
+		return 1 + bits.TrailingZeros32(key+1)
+	}))
+	return stressOwned(st, cfg)
+}
+
+// StressLazyRepair is StressTrieRepair with WithLazyTrieRepair, so trie
+// entries of deleted nodes stay behind while the worker catches up, and
+// queries and inserts keep running into them
+func StressLazyRepair(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithLazyTrieRepair(), WithHeightFunc(func(uint32) int { return LogLogU }))
+	defer st.Close()
+	return stressOwned(st, cfg)
+}
+
+// StressNoHelp is StressMarkUnlink with queries that never unlink
+// deleted nodes (HelpNever), so readers step over stretches of marked
+// nodes that only writers clear
+func StressNoHelp(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithHelpPolicy(HelpNever))
+	return stressOwned(st, cfg)
+}
+
+// StressRecycle is StressMarkUnlink with WithNodeReuse and an allocator
+// that hands freed nodes straight back out, so the same addresses keep
+// coming back while searches and helping unlinks still hold refs read in
+// an earlier life. A CAS that succeeded on a recycled address (ABA) would
+// corrupt the lists, which the final structure check reports
+func StressRecycle(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	alloc := &recycler{}
+	st := NewSkipTrie(WithNodeReuse(), WithAllocator(alloc))
+	if err := stressOwned(st, cfg); err != nil {
+		return err
+	}
+	
+	// Whether the writers retired enough nodes for the epoch to move on in
+	// time depends on the scheduler, so drive the epochs here: with every
+	// operation finished, each flush frees all the nodes retired so far, and
+	// a key deleted and inserted again must then take a recycled node (unless
+	// its tower is tall enough to be left to the garbage collector)
+	for key := uint32(0); alloc.reused.Load() == 0; key++ {
+		if key == 1024 {
+			return fmt.Errorf("no node was recycled after %d deletes", key)
+		}
+		st.reclaim.flush(alloc)
+		st.Insert(key)
+		st.Delete(key)
+		st.reclaim.flush(alloc)
+		st.Insert(key)
+	}
+	return nil
+}
+
+// flush frees every node retired so far; no operation may be running
+func (r *reclaimer) flush(alloc Allocator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	// A node retired in epoch e is freed by the advance from e+1 to e+2
+	r.advance(alloc)
+	r.advance(alloc)
+	r.retired = 0
+}
+
+// recycler is an Allocator reusing the most recently freed node first
+type recycler struct {
+	mu     sync.Mutex
+	free   []*Node
+	reused atomic.Int64 // nodes handed out again
+}
+
+// New implements Allocator
+func (r *recycler) New() *Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	n := len(r.free)
+	if n == 0 {
+		return new(Node)
+	}
+	node := r.free[n-1]
+	r.free = r.free[:n-1]
+	r.reused.Add(1)
+	return node
+}
+
+// Free implements Allocator
+func (r *recycler) Free(node *Node) {
+	*node = Node{}
+	r.mu.Lock()
+	r.free = append(r.free, node)
+	r.mu.Unlock()
+}
+
+// stressOwned runs writers owning interleaved keys against readers and
+// checks the results and the final structure
+func stressOwned(st *SkipTrie, cfg StressConfig) error {
+	writers := max(1, cfg.Goroutines/2)
+	present := make([]atomic.Bool, cfg.Keys)
+	
+	err := stressRun(cfg.Goroutines, cfg.Duration, func(g int, done func() bool) error {
+		rng := rand.New(rand.NewPCG(uint64(g), 1))
+		if g >= writers {
+			// Reader: results must at least be consistent with the query;
+			// PredecessorKey reads the key while the node can't be recycled
+			for !done() {
+				key := uint32(rng.IntN(cfg.Keys + 1))
+				if pred, ok := st.PredecessorKey(key); ok && pred >= key {
+					return fmt.Errorf("PredecessorKey(%d) = %d", key, pred)
+				}
+				st.Contains(key)
+			}
+			return nil
+		}
+		
+		// Writer: owns the keys congruent to g, so it knows their state
+		owned := (cfg.Keys - g + writers - 1) / writers
+		for !done() && owned > 0 {
+			key := uint32(g + writers*rng.IntN(owned))
+			if present[key].Load() {
+				if !st.Delete(key) {
+					return fmt.Errorf("Delete(%d) = false for a present key", key)
+				}
+				present[key].Store(false)
+			} else {
+				if !st.Insert(key) {
+					return fmt.Errorf("Insert(%d) = false for an absent key", key)
+				}
+				present[key].Store(true)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	if err := st.Validate(); err != nil {
+		return err
+	}
+	return st.checkContents(cfg.Keys, func(key uint32) bool { return present[key].Load() })
+}
+
+// StressTowerVsDelete deletes each key while its tower is still being
+// raised: every key gets a full-height tower, and a deleter spins on the
+// key from the moment its inserter starts. A tower level linked after the
+// delete would leave a marked node reachable, which the final structure
+// check reports
+func StressTowerVsDelete(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithHeightFunc(func(uint32) int { return LogLogU }))
+	
+	pairs := max(1, cfg.Goroutines/2)
+	failed := make([]atomic.Bool, pairs)
+	next := make([]chan uint32, pairs)
+	for p := range next {
+		next[p] = make(chan uint32)
+	}
+	
+	err := stressRun(2*pairs, cfg.Duration, func(g int, done func() bool) error {
+		p := g / 2
+		keys := (cfg.Keys - p + pairs - 1) / pairs
+		if keys <= 0 {
+			return nil
+		}
+		
+		if g%2 == 0 {
+			// Inserter: announce the key, then insert it
+			defer close(next[p])
+			for i := 0; !done(); i++ {
+				key := uint32(p + pairs*(i%keys))
+				next[p] <- key
+				if !st.Insert(key) {
+					failed[p].Store(true)
+					return fmt.Errorf("Insert(%d) = false for an absent key", key)
+				}
+			}
+			return nil
+		}
+		
+		// Deleter: spin until the announced key is gone again
+		for key := range next[p] {
+			for spins := 1; !st.Delete(key); spins++ {
+				if failed[p].Load() {
+					break // the inserter reported the failure
+				}
+				if spins%1024 == 0 && done() && st.Contains(key) && spins > 1<<20 {
+					return fmt.Errorf("Delete(%d) keeps failing for a present key", key)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	if err := st.Validate(); err != nil {
+		return err
+	}
+	return st.checkContents(cfg.Keys, func(uint32) bool { return false })
+}
+
+// StressTrieBack races predecessor queries with deletes of top-level
+// nodes: every node is indexed by the trie, even keys stay present and
+// odd keys are inserted and deleted by their owners, so queries keep
+// landing on trie entries whose nodes are being unlinked. Each answer must
+// lie between the even key below the query and the query, and no walk may
+// dead-end on a deleted node without a back pointer
+func StressTrieBack(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	var deadEnds atomic.Int64
+	st := NewSkipTrie(
+		WithHeightFunc(func(uint32) int { return LogLogU }),
+		WithFallbackEvents(func(ev FallbackEvent) {
+			if ev.Kind == FallbackTrieDeadEnd {
+				deadEnds.Add(1)
+			}
+		}),
+	)
+	n := 2 * max(1, cfg.Keys/2)
+	for key := 0; key < n; key += 2 {
+		st.Insert(uint32(key))
+	}
+	
+	writers := max(1, cfg.Goroutines/2)
+	present := make([]atomic.Bool, n)
+	err := stressRun(cfg.Goroutines, cfg.Duration, func(g int, done func() bool) error {
+		rng := rand.New(rand.NewPCG(uint64(g), 2))
+		if g >= writers {
+			for !done() {
+				key := uint32(1 + rng.IntN(n))
+				pred, ok := st.PredecessorKey(key)
+				if floor := (key - 1) &^ 1; !ok || pred >= key || pred < floor {
+					return fmt.Errorf("PredecessorKey(%d) = %d, %v, want a key in [%d, %d)", key, pred, ok, floor, key)
+				}
+			}
+			return nil
+		}
+		
+		// Writer: owns the odd keys 2*(g + writers*i) + 1
+		owned := (n/2 - g + writers - 1) / writers
+		for !done() && owned > 0 {
+			key := uint32(2*(g+writers*rng.IntN(owned)) + 1)
+			if present[key].Load() {
+				if !st.Delete(key) {
+					return fmt.Errorf("Delete(%d) = false for a present key", key)
+				}
+				present[key].Store(false)
+			} else {
+				if !st.Insert(key) {
+					return fmt.Errorf("Insert(%d) = false for an absent key", key)
+				}
+				present[key].Store(true)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if d := deadEnds.Load(); d > 0 {
+		return fmt.Errorf("%d trie walks dead-ended on a deleted node", d)
+	}
+	
+	if err := st.Validate(); err != nil {
+		return err
+	}
+	return st.checkContents(n, func(key uint32) bool { return key%2 == 0 || present[key].Load() })
+}
+
+// StressBulkDelete checks the lists left behind by DeleteRange and
+// DeleteBatch: random sets over 256 keys, a quarter of their nodes at the
+// top level, lose a random range and then a random batch of keys, and
+// Validate must pass after each, including the prev pointers of the nodes
+// following the removed ones. The goroutines build sets of their own, so
+// cfg.Keys is not used
+func StressBulkDelete(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	return stressRun(cfg.Goroutines, cfg.Duration, func(g int, done func() bool) error {
+		rng := rand.New(rand.NewPCG(uint64(g), 4))
+		for !done() {
+			st := NewSkipTrie(WithHeightFunc(func(key uint32) int {
+				if key%4 == 0 {
+					return LogLogU
+				}
+				return 1 + int(key%3)
+			}))
+			var present [256]bool
+			for key := range present {
+				if rng.IntN(2) == 0 {
+					present[key] = true
+					st.Insert(uint32(key))
+				}
+			}
+			
+			lo := rng.IntN(256)
+			hi := lo + rng.IntN(256-lo)
+			st.DeleteRange(uint32(lo), uint32(hi))
+			for key := lo; key <= hi; key++ {
+				present[key] = false
+			}
+			if err := st.Validate(); err != nil {
+				return fmt.Errorf("after DeleteRange(%d, %d): %w", lo, hi, err)
+			}
+			
+			keys := make([]uint32, rng.IntN(64))
+			for i := range keys {
+				keys[i] = uint32(rng.IntN(256))
+			}
+			st.DeleteBatch(keys)
+			for _, key := range keys {
+				present[key] = false
+			}
+			if err := st.Validate(); err != nil {
+				return fmt.Errorf("after DeleteBatch(%v): %w", keys, err)
+			}
+			if err := st.checkContents(256, func(key uint32) bool { return present[key] }); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkContents compares Contains and Predecessor for every key below n,
+// and n itself, with the set described by want
+func (st *SkipTrie) checkContents(n int, want func(key uint32) bool) error {
+	var last *uint32
+	for k := 0; k <= n; k++ {
+		key := uint32(k)
+		pred := st.Predecessor(key)
+		switch {
+		case last == nil && pred != nil:
+			return fmt.Errorf("Predecessor(%d) = %d, want none", key, pred.key)
+		case last != nil && (pred == nil || pred.key != *last):
+			return fmt.Errorf("Predecessor(%d) = %v, want %d", key, pred, *last)
+		}
+		if k == n {
+			break
+		}
+		if st.Contains(key) != want(key) {
+			return fmt.Errorf("Contains(%d) = %v, want %v", key, !want(key), want(key))
+		}
+		if want(key) {
+			last = &key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+// Package stress runs a configurable concurrent workload against a
+// SkipTrie and checks it against a sequential replay of its changelog
+//
+// The changelog orders every successful insert and delete, consistently
+// with the order in which they took effect on each key, so it is a
+// recorded linearization of the updates. After the run the replay must
+// accept every event (no insert of a present key, no delete of an absent
+// one), count exactly the successful updates the workers saw for each key,
+// and end in the state the SkipTrie holds. Reads are checked for
+// consistency with their query as they run
+//
+// The package is opt-in: nothing runs it but cmd/stress, which is meant
+// for the race detector,
+//
+//	SKIPTRIE_STRESS_DURATION=30s SKIPTRIE_STRESS_MIX=insert=1,delete=1 \
+//		go run -race -tags stress ./cmd/stress -run Replay -rounds 1
+//
+// and FromEnv reads the run's size from the environment:
+//
+//	SKIPTRIE_STRESS_GOROUTINES  concurrent workers
+//	SKIPTRIE_STRESS_KEYS        size of the key space
+//	SKIPTRIE_STRESS_DURATION    length of the run, as for time.ParseDuration
+//	SKIPTRIE_STRESS_MIX         operation weights, as for ParseMix
+package stress
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// Mix weighs the operations the workers draw; only the ratios matter
+type Mix struct {
+	Insert      int
+	Delete      int
+	Contains    int
+	Predecessor int
+	Range       int // Range over a short stretch of keys
+}
+
+// DefaultMix is an even split between updates and reads
+var DefaultMix = Mix{Insert: 3, Delete: 3, Contains: 2, Predecessor: 1, Range: 1}
+
+// total returns the sum of the weights
+func (m Mix) total() int {
+	return m.Insert + m.Delete + m.Contains + m.Predecessor + m.Range
+}
+
+// ParseMix parses weights written as name=weight pairs separated by
+// commas, such as "insert=4,delete=4,contains=1"; names left out weigh 0
+func ParseMix(s string) (Mix, error) {
+	var m Mix
+	for _, field := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return Mix{}, fmt.Errorf("stress: mix entry %q is not name=weight", field)
+		}
+		w, err := strconv.Atoi(value)
+		if err != nil || w < 0 {
+			return Mix{}, fmt.Errorf("stress: mix entry %q: weight must be a non-negative integer", field)
+		}
+		
+		switch strings.ToLower(name) {
+		case "insert":
+			m.Insert = w
+		case "delete":
+			m.Delete = w
+		case "contains":
+			m.Contains = w
+		case "predecessor":
+			m.Predecessor = w
+		case "range":
+			m.Range = w
+		default:
+			return Mix{}, fmt.Errorf("stress: unknown operation %q in mix", name)
+		}
+	}
+	if m.total() == 0 {
+		return Mix{}, errors.New("stress: mix weighs every operation 0")
+	}
+	return m, nil
+}
+
+// Config sizes a run; zero fields take the defaults
+type Config struct {
+	Goroutines int               // concurrent workers, default 2*GOMAXPROCS (at least 4)
+	Keys       int               // size of the key space, default 256
+	Duration   time.Duration     // length of the run, default 1s
+	Mix        Mix               // operation weights, default DefaultMix
+	Options    []skiptrie.Option // configure the SkipTrie under test
+}
+
+// withDefaults fills in the zero fields of c
+func (c Config) withDefaults() Config {
+	if c.Goroutines <= 0 {
+		c.Goroutines = max(4, 2*runtime.GOMAXPROCS(0))
+	}
+	if c.Keys <= 0 {
+		c.Keys = 256
+	}
+	if c.Duration <= 0 {
+		c.Duration = time.Second
+	}
+	if c.Mix.total() == 0 {
+		c.Mix = DefaultMix
+	}
+	return c
+}
+
+// FromEnv returns a Config with the fields set by the SKIPTRIE_STRESS_*
+// variables; unset variables leave their fields zero
+func FromEnv() (Config, error) {
+	var c Config
+	atoi := func(name string, dst *int) error {
+		if s := os.Getenv(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return fmt.Errorf("stress: %s=%q is not a non-negative integer", name, s)
+			}
+			*dst = n
+		}
+		return nil
+	}
+	if err := atoi("SKIPTRIE_STRESS_GOROUTINES", &c.Goroutines); err != nil {
+		return Config{}, err
+	}
+	if err := atoi("SKIPTRIE_STRESS_KEYS", &c.Keys); err != nil {
+		return Config{}, err
+	}
+	if s := os.Getenv("SKIPTRIE_STRESS_DURATION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("stress: SKIPTRIE_STRESS_DURATION: %w", err)
+		}
+		c.Duration = d
+	}
+	if s := os.Getenv("SKIPTRIE_STRESS_MIX"); s != "" {
+		m, err := ParseMix(s)
+		if err != nil {
+			return Config{}, err
+		}
+		c.Mix = m
+	}
+	return c, nil
+}
+
+// Report summarizes a successful run
+type Report struct {
+	Ops     uint64 // operations performed
+	Inserts uint64 // successful inserts
+	Deletes uint64 // successful deletes
+	Events  int    // changelog events replayed
+	Final   int    // keys present at the end
+}
+
+// String formats the report on one line
+func (r Report) String() string {
+	return fmt.Sprintf("%d ops, %d inserts, %d deletes, %d events, %d keys left",
+		r.Ops, r.Inserts, r.Deletes, r.Events, r.Final)
+}
+
+// tally counts one worker's operations and its successful updates per key
+type tally struct {
+	ops     uint64
+	inserts []uint32
+	deletes []uint32
+}
+
+// Run drives cfg.Goroutines workers against a new SkipTrie for
+// cfg.Duration and checks the outcome against the changelog replay
+func Run(cfg Config) (Report, error) {
+	cfg = cfg.withDefaults()
+	st := skiptrie.NewSkipTrie(cfg.Options...)
+	defer st.Close()
+	
+	w, err := st.Watch(0, math.MaxUint32)
+	if err != nil {
+		return Report{}, err
+	}
+	defer w.Close()
+	
+	// The collector stops at the sequence number it is sent, once every
+	// update has returned and so has been published
+	var events []skiptrie.Event
+	until := make(chan uint64)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		var last uint64
+		stop := uint64(math.MaxUint64)
+		for last < stop {
+			select {
+			case ev, ok := <-w.C:
+				if !ok {
+					return
+				}
+				events = append(events, ev)
+				last = ev.Seq
+			case stop = <-until:
+				until = nil
+			}
+		}
+	}()
+	
+	tallies := make([]tally, cfg.Goroutines)
+	for g := range tallies {
+		tallies[g].inserts = make([]uint32, cfg.Keys)
+		tallies[g].deletes = make([]uint32, cfg.Keys)
+	}
+	
+	deadline := time.Now().Add(cfg.Duration)
+	var failed atomic.Bool
+	errs := make([]error, cfg.Goroutines)
+	var wg sync.WaitGroup
+	for g := range tallies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := func() bool {
+				return failed.Load() || time.Now().After(deadline)
+			}
+			if errs[g] = work(st, cfg, uint64(g), done, &tallies[g]); errs[g] != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return Report{}, err
+	}
+	
+	until <- st.Seq()
+	<-collected
+	return replay(st, cfg.Keys, tallies, events)
+}
+
+// work runs one worker's share of the mix until done
+func work(st *skiptrie.SkipTrie, cfg Config, g uint64, done func() bool, t *tally) error {
+	rng := rand.New(rand.NewPCG(g, uint64(time.Now().UnixNano())))
+	mix := cfg.Mix
+	keys := uint32(cfg.Keys)
+	
+	for ; !done(); t.ops++ {
+		key := rng.Uint32N(keys)
+		op := rng.IntN(mix.total())
+		switch {
+		case op < mix.Insert:
+			if st.Insert(key) {
+				t.inserts[key]++
+			}
+		case op < mix.Insert+mix.Delete:
+			if st.Delete(key) {
+				t.deletes[key]++
+			}
+		case op < mix.Insert+mix.Delete+mix.Contains:
+			st.Contains(key)
+		case op < mix.Insert+mix.Delete+mix.Contains+mix.Predecessor:
+			if pred, ok := st.PredecessorKey(key); ok && (pred >= key || pred >= keys) {
+				return fmt.Errorf("stress: PredecessorKey(%d) = %d", key, pred)
+			}
+		default:
+			hi := min(key+16, keys-1)
+			prev, first := uint32(0), true
+			var err error
+			st.Range(key, hi, func(k uint32) bool {
+				if k < key || k > hi || (!first && k <= prev) {
+					err = fmt.Errorf("stress: Range(%d, %d) yielded %d after %d", key, hi, k, prev)
+					return false
+				}
+				prev, first = k, false
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replay applies events in sequence order to a model, starting from the
+// empty set, and checks them against the workers' tallies and st
+func replay(st *skiptrie.SkipTrie, keys int, tallies []tally, events []skiptrie.Event) (Report, error) {
+	var r Report
+	model := skiptrie.NewLockedSet[uint32]()
+	inserts := make([]uint64, keys)
+	deletes := make([]uint64, keys)
+	
+	for i, ev := range events {
+		if ev.Seq != uint64(i)+1 {
+			return r, fmt.Errorf("stress: event %d has sequence number %d", i+1, ev.Seq)
+		}
+		if int(ev.Key) >= keys {
+			return r, fmt.Errorf("stress: event %d is for key %d outside the key space", ev.Seq, ev.Key)
+		}
+		switch ev.Op {
+		case skiptrie.EventInsert:
+			if !model.Insert(ev.Key) {
+				return r, fmt.Errorf("stress: event %d inserts %d, which the replay holds", ev.Seq, ev.Key)
+			}
+			inserts[ev.Key]++
+		case skiptrie.EventDelete:
+			if !model.Delete(ev.Key) {
+				return r, fmt.Errorf("stress: event %d deletes %d, which the replay lacks", ev.Seq, ev.Key)
+			}
+			deletes[ev.Key]++
+		default:
+			return r, fmt.Errorf("stress: event %d is an unexpected %v", ev.Seq, ev.Op)
+		}
+	}
+	r.Events = len(events)
+	
+	for _, t := range tallies {
+		r.Ops += t.ops
+		for key := range keys {
+			inserts[key] -= uint64(t.inserts[key])
+			deletes[key] -= uint64(t.deletes[key])
+			r.Inserts += uint64(t.inserts[key])
+			r.Deletes += uint64(t.deletes[key])
+		}
+	}
+	for key := range keys {
+		if inserts[key] != 0 || deletes[key] != 0 {
+			return r, fmt.Errorf("stress: key %d: the log and the workers disagree on its updates", key)
+		}
+	}
+	
+	got := st.Keys()
+	var want []uint32
+	model.Range(0, math.MaxUint32, func(key uint32) bool {
+		want = append(want, key)
+		return true
+	})
+	if !slices.Equal(got, want) {
+		return r, fmt.Errorf("stress: final state holds %d keys, the replay %d", len(got), len(want))
+	}
+	r.Final = len(got)
+	return r, st.Validate()
+}
@@ -0,0 +1,78 @@
+//go:build stress && arm64
+
+package skiptrie
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// StressPublication races readers against writers inserting and deleting
+// full-height nodes, for arm64, where loads and stores may be reordered
+// unless the publication points order them. Readers check that every node
+// they reach through the lists or the trie is fully formed; building with
+// the fences tag as well audits each publication point on the way
+func StressPublication(cfg StressConfig) error {
+	cfg = cfg.withDefaults()
+	st := NewSkipTrie(WithHeightFunc(func(uint32) int { return LogLogU }))
+	writers := max(1, cfg.Goroutines/2)
+	
+	err := stressRun(cfg.Goroutines, cfg.Duration, func(g int, done func() bool) error {
+		rng := rand.New(rand.NewPCG(uint64(g), 3))
+		if g < writers {
+			// Writer: owns the keys congruent to g
+			owned := (cfg.Keys - g + writers - 1) / writers
+			for !done() && owned > 0 {
+				key := uint32(g + writers*rng.IntN(owned))
+				if !st.Delete(key) {
+					st.Insert(key)
+				}
+			}
+			return nil
+		}
+		
+		// Reader: every node reached must carry its whole tower
+		for !done() {
+			key := uint32(rng.IntN(cfg.Keys + 1))
+			if pred := st.Predecessor(key); pred != nil {
+				if err := st.checkPublished(pred); err != nil {
+					return err
+				}
+				if pred.key >= key {
+					return fmt.Errorf("Predecessor(%d) = %d", key, pred.key)
+				}
+			}
+			for curr := st.head.next[LogLogU-1].Load(); curr != nil && curr != st.tail; curr = curr.next[LogLogU-1].Load() {
+				if err := st.checkPublished(curr); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return st.Validate()
+}
+
+// checkPublished reports a full-height node whose fields are not all
+// visible to the reader
+func (st *SkipTrie) checkPublished(node *Node) error {
+	switch {
+	case node.origHeight != LogLogU:
+		return fmt.Errorf("key %d: height %d visible, want %d", node.key, node.origHeight, LogLogU)
+	case len(node.next) != LogLogU:
+		return fmt.Errorf("key %d: %d next pointers visible", node.key, len(node.next))
+	case node.prev == nil || node.back == nil:
+		return fmt.Errorf("key %d: prev or back pointer not visible", node.key)
+	case !node.indexed:
+		return fmt.Errorf("key %d: indexed flag not visible", node.key)
+	}
+	for level := range node.next {
+		if node.next[level].Load() == nil {
+			return fmt.Errorf("key %d: successor at level %d not visible", node.key, level)
+		}
+	}
+	return nil
+}
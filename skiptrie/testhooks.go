@@ -0,0 +1,180 @@
+//go:build testhooks
+
+package skiptrie
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// TestHooks injects failures into a SkipTrie so that code wrapping it can
+// exercise its own retry and fallback paths deterministically
+// It is only available with the testhooks build tag; without it the hook
+// checks compile away
+type TestHooks struct {
+	// CASFailureRate is the probability, in [0, 1), that an insert's link
+	// CAS is failed as if another writer had won; the insert retries from a
+	// fresh search and the retry is counted in the CAS retry statistics
+	CASFailureRate float64
+	// Heights are used as tower heights, in order, by the next inserts
+	// before random or WithHeightFunc heights resume; values are clamped
+	// to [1, the maximum height]
+	Heights []int
+	// TrieMiss makes every trie lookup come back empty, so predecessor
+	// searches start from head and report FallbackTrieDeadEnd
+	TrieMiss bool
+	// Seed seeds the generator deciding CAS failures
+	Seed uint64
+	// Pause, if set, is called at every PausePoint an operation reaches,
+	// with the operation's key; blocking in it holds the operation there
+	// It is called concurrently and outside any lock, so it may itself
+	// run operations on the SkipTrie. PauseScheduler implements it
+	Pause func(point PausePoint, key uint32)
+}
+
+// WithTestHooks installs h at construction time
+func WithTestHooks(h TestHooks) Option {
+	return func(st *SkipTrie) {
+		st.hooks.set(h)
+	}
+}
+
+// SetTestHooks replaces the installed hooks; the zero TestHooks removes
+// them
+func (st *SkipTrie) SetTestHooks(h TestHooks) {
+	st.hooks.set(h)
+}
+
+// testHooks holds the installed TestHooks and their progress
+type testHooks struct {
+	mu      sync.Mutex
+	h       TestHooks
+	heights []int // forced heights not yet used
+	rng     *rand.Rand
+}
+
+// set installs h
+func (t *testHooks) set(h TestHooks) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	t.h = h
+	t.heights = append([]int(nil), h.Heights...)
+	t.rng = rand.New(rand.NewPCG(h.Seed, h.Seed))
+}
+
+// failCAS reports whether to fail the next link CAS
+func (t *testHooks) failCAS() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.h.CASFailureRate > 0 && t.rng.Float64() < t.h.CASFailureRate
+}
+
+// forcedHeight returns the next forced tower height, if any
+func (t *testHooks) forcedHeight() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if len(t.heights) == 0 {
+		return 0, false
+	}
+	height := t.heights[0]
+	t.heights = t.heights[1:]
+	return height, true
+}
+
+// missTrie reports whether the next trie lookup should miss
+func (t *testHooks) missTrie() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.h.TrieMiss
+}
+
+// pause is called at point by an operation on key
+func (t *testHooks) pause(point PausePoint, key uint32) {
+	t.mu.Lock()
+	pause := t.h.Pause
+	t.mu.Unlock()
+	
+	if pause != nil {
+		pause(point, key)
+	}
+}
+
+// PauseScheduler holds operations at chosen pause points, for tests that
+// need one exact interleaving, such as a delete landing between an
+// insert's search and its link CAS:
+//
+//	sched := skiptrie.NewPauseScheduler()
+//	st := skiptrie.NewSkipTrie(skiptrie.WithTestHooks(skiptrie.TestHooks{Pause: sched.Pause}))
+//	gate := sched.Hold(skiptrie.PauseInsertSearched, 7)
+//	go st.Insert(7)
+//	gate.Wait()    // the insert has its neighbours
+//	st.Delete(5)   // change them under it
+//	gate.Release() // let its CAS fail and retry
+type PauseScheduler struct {
+	mu    sync.Mutex
+	gates map[pauseAt]*PauseGate
+}
+
+// pauseAt is a point reached by an operation on a key
+type pauseAt struct {
+	point PausePoint
+	key   uint32
+}
+
+// PauseGate holds one operation at a pause point
+type PauseGate struct {
+	reached chan struct{} // closed when an operation arrives
+	release chan struct{} // closed to let it continue
+	once    sync.Once
+}
+
+// NewPauseScheduler creates a scheduler holding nothing
+func NewPauseScheduler() *PauseScheduler {
+	return &PauseScheduler{gates: make(map[pauseAt]*PauseGate)}
+}
+
+// Hold arms a gate for the next operation on key to reach point; the
+// operations after it pass freely unless Hold is called again
+func (s *PauseScheduler) Hold(point PausePoint, key uint32) *PauseGate {
+	g := &PauseGate{
+		reached: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	
+	s.mu.Lock()
+	s.gates[pauseAt{point, key}] = g
+	s.mu.Unlock()
+	return g
+}
+
+// Pause is the TestHooks.Pause function that applies the armed gates
+func (s *PauseScheduler) Pause(point PausePoint, key uint32) {
+	at := pauseAt{point, key}
+	s.mu.Lock()
+	g := s.gates[at]
+	delete(s.gates, at)
+	s.mu.Unlock()
+	
+	if g != nil {
+		close(g.reached)
+		<-g.release
+	}
+}
+
+// Reached returns a channel closed once an operation is held at the gate
+func (g *PauseGate) Reached() <-chan struct{} {
+	return g.reached
+}
+
+// Wait blocks until an operation is held at the gate
+func (g *PauseGate) Wait() {
+	<-g.reached
+}
+
+// Release lets the held operation continue, or lets the next one pass
+// straight through if none has arrived yet
+func (g *PauseGate) Release() {
+	g.once.Do(func() { close(g.release) })
+}
@@ -0,0 +1,114 @@
+package treeset
+
+// Iterator is a stateful cursor over a Set, matching the gods iterator
+// It starts one-before-first; each move is a successor or predecessor
+// query from the current value, so an iterator keeps working while the
+// set changes, and Index counts the moves made rather than a position
+type Iterator struct {
+	set   *Set
+	index int    // -1 before the first item, Size() past the last
+	value uint32 // current item while index is inside the set
+	end   bool   // past the last item
+}
+
+// Iterator returns an iterator positioned one-before-first
+func (set *Set) Iterator() Iterator {
+	return Iterator{set: set, index: -1}
+}
+
+// Next moves to the next item and reports whether there is one
+func (it *Iterator) Next() bool {
+	if it.end {
+		return false
+	}
+	var v uint32
+	var ok bool
+	if it.index < 0 {
+		v, ok = it.set.st.First()
+	} else {
+		v, ok = it.set.st.SuccessorKey(it.value)
+	}
+	it.index++
+	if !ok {
+		it.end = true
+		return false
+	}
+	it.value = v
+	return true
+}
+
+// Prev moves to the previous item and reports whether there is one
+func (it *Iterator) Prev() bool {
+	if it.index < 0 {
+		return false
+	}
+	var v uint32
+	var ok bool
+	if it.end {
+		v, ok = it.set.st.Last()
+		it.end = false
+	} else {
+		v, ok = it.set.st.PredecessorKey(it.value)
+	}
+	it.index--
+	if !ok {
+		it.index = -1
+		return false
+	}
+	it.value = v
+	return true
+}
+
+// Value returns the current item
+func (it *Iterator) Value() uint32 {
+	return it.value
+}
+
+// Index returns the index of the current item
+func (it *Iterator) Index() int {
+	return it.index
+}
+
+// Begin moves the iterator one-before-first
+func (it *Iterator) Begin() {
+	it.index, it.end = -1, false
+}
+
+// End moves the iterator one-past-last
+func (it *Iterator) End() {
+	it.index, it.end = it.set.Size(), true
+}
+
+// First moves to the first item and reports whether there is one
+func (it *Iterator) First() bool {
+	it.Begin()
+	return it.Next()
+}
+
+// Last moves to the last item and reports whether there is one
+func (it *Iterator) Last() bool {
+	it.End()
+	return it.Prev()
+}
+
+// NextTo moves to the next item for which f is true and reports whether
+// there is one
+func (it *Iterator) NextTo(f func(index int, value uint32) bool) bool {
+	for it.Next() {
+		if f(it.index, it.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrevTo moves to the previous item for which f is true and reports
+// whether there is one
+func (it *Iterator) PrevTo(f func(index int, value uint32) bool) bool {
+	for it.Prev() {
+		if f(it.index, it.value) {
+			return true
+		}
+	}
+	return false
+}
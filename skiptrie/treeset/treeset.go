@@ -0,0 +1,213 @@
+// Package treeset adapts a SkipTrie to the API of the TreeSet of
+// emirpasic/gods (v2, specialised to uint32), so that code using that set
+// can switch to a concurrent one by changing only its constructor
+//
+// Method names, argument order and results follow gods: Add and Remove are
+// variadic, Contains reports whether all its arguments are present,
+// Values returns the keys in ascending order and String prints "TreeSet"
+// followed by the keys. Unlike the gods set, every method is safe for
+// concurrent use; a method touching several keys is not atomic, and
+// iterators see concurrent changes as they move
+package treeset
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	
+	"github.com/gaarutyunov/skiptrie-go/skiptrie"
+)
+
+// Set is an ordered set of uint32 values backed by a SkipTrie
+type Set struct {
+	st *skiptrie.SkipTrie
+}
+
+// New creates a set holding values
+func New(values ...uint32) *Set {
+	return NewWith(nil, values...)
+}
+
+// NewWith creates a set with a SkipTrie configured by opts, holding values
+func NewWith(opts []skiptrie.Option, values ...uint32) *Set {
+	set := &Set{st: skiptrie.NewSkipTrie(opts...)}
+	set.Add(values...)
+	return set
+}
+
+// SkipTrie returns the underlying SkipTrie
+func (set *Set) SkipTrie() *skiptrie.SkipTrie {
+	return set.st
+}
+
+// Add adds the items, as a batch when there are several
+func (set *Set) Add(items ...uint32) {
+	if len(items) > 1 {
+		set.st.InsertBatch(items)
+		return
+	}
+	for _, item := range items {
+		set.st.Insert(item)
+	}
+}
+
+// Remove removes the items, one at a time
+func (set *Set) Remove(items ...uint32) {
+	for _, item := range items {
+		set.st.Delete(item)
+	}
+}
+
+// Contains checks if all items are present; it is true without items
+func (set *Set) Contains(items ...uint32) bool {
+	for _, item := range items {
+		if !set.st.Contains(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty checks if the set holds no items
+func (set *Set) Empty() bool {
+	return set.st.Len() == 0
+}
+
+// Size returns the number of items
+func (set *Set) Size() int {
+	return set.st.Len()
+}
+
+// Clear removes all items
+func (set *Set) Clear() {
+	set.st.DeleteRange(0, math.MaxUint32)
+}
+
+// Values returns the items in ascending order
+func (set *Set) Values() []uint32 {
+	return set.st.Keys()
+}
+
+// String prints the set as gods does: "TreeSet" on one line and the items
+// separated by ", " on the next
+func (set *Set) String() string {
+	var b strings.Builder
+	b.WriteString("TreeSet\n")
+	for i, v := range set.Values() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%v", v)
+	}
+	return b.String()
+}
+
+// Intersection returns a new set with the items of both sets
+func (set *Set) Intersection(another *Set) *Set {
+	return &Set{st: set.st.Intersect(another.st)}
+}
+
+// Union returns a new set with the items of either set
+func (set *Set) Union(another *Set) *Set {
+	return &Set{st: set.st.Union(another.st)}
+}
+
+// Difference returns a new set with the items of set missing from another
+func (set *Set) Difference(another *Set) *Set {
+	return &Set{st: set.st.Difference(another.st)}
+}
+
+// Each calls f for each item in ascending order with its index
+func (set *Set) Each(f func(index int, value uint32)) {
+	index := 0
+	for v := range set.st.All() {
+		f(index, v)
+		index++
+	}
+}
+
+// Map returns a new set holding f applied to each item
+func (set *Set) Map(f func(index int, value uint32) uint32) *Set {
+	var values []uint32
+	set.Each(func(index int, value uint32) {
+		values = append(values, f(index, value))
+	})
+	return New(values...)
+}
+
+// Select returns a new set holding the items for which f is true
+func (set *Set) Select(f func(index int, value uint32) bool) *Set {
+	var values []uint32
+	set.Each(func(index int, value uint32) {
+		if f(index, value) {
+			values = append(values, value)
+		}
+	})
+	return New(values...)
+}
+
+// Any checks if f is true for some item, stopping at the first
+func (set *Set) Any(f func(index int, value uint32) bool) bool {
+	_, _, found := set.find(f)
+	return found
+}
+
+// All checks if f is true for every item, stopping at the first failure
+func (set *Set) All(f func(index int, value uint32) bool) bool {
+	_, _, found := set.find(func(index int, value uint32) bool {
+		return !f(index, value)
+	})
+	return !found
+}
+
+// Find returns the index and value of the first item for which f is true,
+// or -1 and 0
+func (set *Set) Find(f func(index int, value uint32) bool) (int, uint32) {
+	index, value, found := set.find(f)
+	if !found {
+		return -1, 0
+	}
+	return index, value
+}
+
+// find returns the first item for which f is true
+func (set *Set) find(f func(index int, value uint32) bool) (int, uint32, bool) {
+	index := 0
+	for v := range set.st.All() {
+		if f(index, v) {
+			return index, v, true
+		}
+		index++
+	}
+	return 0, 0, false
+}
+
+// ToJSON encodes the items as a JSON array
+func (set *Set) ToJSON() ([]byte, error) {
+	return json.Marshal(set.Values())
+}
+
+// FromJSON replaces the items with those of a JSON array
+func (set *Set) FromJSON(data []byte) error {
+	var values []uint32
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	set.Clear()
+	set.Add(values...)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (set *Set) MarshalJSON() ([]byte, error) {
+	return set.ToJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (set *Set) UnmarshalJSON(data []byte) error {
+	if set.st == nil {
+		set.st = skiptrie.NewSkipTrie()
+	}
+	return set.FromJSON(data)
+}
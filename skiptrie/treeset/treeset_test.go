@@ -0,0 +1,167 @@
+package treeset
+
+import (
+	"reflect"
+	"testing"
+)
+
+// result collects an iterator walk: the values seen and the index after
+// each move
+type result struct {
+	Values  []uint32
+	Indexes []int
+}
+
+// TestConformance checks the adapter against the results the gods TreeSet
+// gives for the same calls
+func TestConformance(t *testing.T) {
+	isOdd := func(_ int, v uint32) bool { return v%2 == 1 }
+	tests := []struct {
+		name string
+		init []uint32
+		run  func(set *Set) any
+		want any
+	}{
+		{"New sorts and dedups", []uint32{5, 1, 3, 1}, func(set *Set) any { return set.Values() }, []uint32{1, 3, 5}},
+		{"Values of empty", nil, func(set *Set) any { return set.Values() }, []uint32{}},
+		{"Add variadic", []uint32{2}, func(set *Set) any {
+			set.Add(9, 4, 2)
+			return set.Values()
+		}, []uint32{2, 4, 9}},
+		{"Add nothing", []uint32{2}, func(set *Set) any {
+			set.Add()
+			return set.Values()
+		}, []uint32{2}},
+		{"Remove variadic and absent", []uint32{1, 2, 3, 4}, func(set *Set) any {
+			set.Remove(4, 1, 7)
+			return set.Values()
+		}, []uint32{2, 3}},
+		{"Contains all", []uint32{1, 3, 5}, func(set *Set) any { return set.Contains(5, 1) }, true},
+		{"Contains some", []uint32{1, 3, 5}, func(set *Set) any { return set.Contains(1, 2) }, false},
+		{"Contains nothing", nil, func(set *Set) any { return set.Contains() }, true},
+		{"Empty", nil, func(set *Set) any { return set.Empty() }, true},
+		{"Not empty", []uint32{0}, func(set *Set) any { return set.Empty() }, false},
+		{"Size", []uint32{7, 7, 8}, func(set *Set) any { return set.Size() }, 2},
+		{"Clear", []uint32{1, 2}, func(set *Set) any {
+			set.Clear()
+			return []any{set.Empty(), set.Size()}
+		}, []any{true, 0}},
+		{"String", []uint32{3, 1, 2}, func(set *Set) any { return set.String() }, "TreeSet\n1, 2, 3"},
+		{"String of empty", nil, func(set *Set) any { return set.String() }, "TreeSet\n"},
+		{"Intersection", []uint32{1, 2, 3}, func(set *Set) any {
+			return set.Intersection(New(2, 3, 4)).Values()
+		}, []uint32{2, 3}},
+		{"Union", []uint32{1, 2}, func(set *Set) any { return set.Union(New(2, 5)).Values() }, []uint32{1, 2, 5}},
+		{"Difference", []uint32{1, 2, 3}, func(set *Set) any {
+			return set.Difference(New(2, 9)).Values()
+		}, []uint32{1, 3}},
+		{"Each", []uint32{30, 10, 20}, func(set *Set) any {
+			var got [][2]uint32
+			set.Each(func(index int, value uint32) { got = append(got, [2]uint32{uint32(index), value}) })
+			return got
+		}, [][2]uint32{{0, 10}, {1, 20}, {2, 30}}},
+		{"Map", []uint32{1, 2, 3}, func(set *Set) any {
+			return set.Map(func(_ int, v uint32) uint32 { return v % 2 }).Values()
+		}, []uint32{0, 1}},
+		{"Select", []uint32{1, 2, 3}, func(set *Set) any { return set.Select(isOdd).Values() }, []uint32{1, 3}},
+		{"Any", []uint32{2, 4, 5}, func(set *Set) any { return set.Any(isOdd) }, true},
+		{"Any of empty", nil, func(set *Set) any { return set.Any(isOdd) }, false},
+		{"All", []uint32{1, 3, 4}, func(set *Set) any { return set.All(isOdd) }, false},
+		{"All of empty", nil, func(set *Set) any { return set.All(isOdd) }, true},
+		{"Find", []uint32{2, 4, 5, 7}, func(set *Set) any {
+			index, value := set.Find(isOdd)
+			return []any{index, value}
+		}, []any{2, uint32(5)}},
+		{"Find nothing", []uint32{2}, func(set *Set) any {
+			index, value := set.Find(isOdd)
+			return []any{index, value}
+		}, []any{-1, uint32(0)}},
+		{"ToJSON", []uint32{3, 1}, func(set *Set) any {
+			data, err := set.ToJSON()
+			return []any{string(data), err}
+		}, []any{"[1,3]", nil}},
+		{"FromJSON replaces", []uint32{8}, func(set *Set) any {
+			err := set.FromJSON([]byte("[5,2,5]"))
+			return []any{set.Values(), err}
+		}, []any{[]uint32{2, 5}, nil}},
+		{"FromJSON keeps on error", []uint32{8}, func(set *Set) any {
+			err := set.FromJSON([]byte("[5,"))
+			return []any{set.Values(), err != nil}
+		}, []any{[]uint32{8}, true}},
+		{"Iterator forward", []uint32{1, 2, 3}, func(set *Set) any {
+			var r result
+			it := set.Iterator()
+			for it.Next() {
+				r.Values = append(r.Values, it.Value())
+				r.Indexes = append(r.Indexes, it.Index())
+			}
+			ok := it.Next() // stays past the end
+			r.Indexes = append(r.Indexes, it.Index())
+			return []any{r, ok}
+		}, []any{result{Values: []uint32{1, 2, 3}, Indexes: []int{0, 1, 2, 3}}, false}},
+		{"Iterator backward from end", []uint32{1, 2, 3}, func(set *Set) any {
+			var r result
+			it := set.Iterator()
+			it.End()
+			for it.Prev() {
+				r.Values = append(r.Values, it.Value())
+				r.Indexes = append(r.Indexes, it.Index())
+			}
+			ok := it.Prev() // stays before the start
+			r.Indexes = append(r.Indexes, it.Index())
+			return []any{r, ok}
+		}, []any{result{Values: []uint32{3, 2, 1}, Indexes: []int{2, 1, 0, -1}}, false}},
+		{"Iterator turns around", []uint32{1, 2, 3}, func(set *Set) any {
+			var r result
+			it := set.Iterator()
+			for range 4 {
+				it.Next()
+			}
+			for range 2 {
+				if it.Prev() {
+					r.Values = append(r.Values, it.Value())
+				}
+				r.Indexes = append(r.Indexes, it.Index())
+			}
+			return r
+		}, result{Values: []uint32{3, 2}, Indexes: []int{2, 1}}},
+		{"Iterator on empty", nil, func(set *Set) any {
+			it := set.Iterator()
+			return []any{it.Next(), it.Index(), it.Prev(), it.Index(), it.First(), it.Last()}
+		}, []any{false, 0, false, -1, false, false}},
+		{"Iterator First and Last", []uint32{4, 6, 8}, func(set *Set) any {
+			it := set.Iterator()
+			first := it.First()
+			v1, i1 := it.Value(), it.Index()
+			last := it.Last()
+			return []any{first, v1, i1, last, it.Value(), it.Index()}
+		}, []any{true, uint32(4), 0, true, uint32(8), 2}},
+		{"Iterator Begin", []uint32{4, 6}, func(set *Set) any {
+			it := set.Iterator()
+			it.Last()
+			it.Begin()
+			ok := it.Next()
+			return []any{ok, it.Value(), it.Index()}
+		}, []any{true, uint32(4), 0}},
+		{"Iterator NextTo and PrevTo", []uint32{1, 2, 3, 4, 5}, func(set *Set) any {
+			it := set.Iterator()
+			even := func(_ int, v uint32) bool { return v%2 == 0 }
+			var got []uint32
+			for it.NextTo(even) {
+				got = append(got, it.Value())
+			}
+			for it.PrevTo(isOdd) {
+				got = append(got, it.Value())
+			}
+			return got
+		}, []uint32{2, 4, 5, 3, 1}},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run(New(tt.init...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
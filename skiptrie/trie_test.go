@@ -0,0 +1,87 @@
+package skiptrie
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// TestTrieExhaustive checks the trie predecessor walk against brute force
+// over a whole 256-key universe (WithUniverse(8)): for every query key of
+// a set built with every node indexed, the walk must land exactly on the
+// largest key below it, with no skiplist search to make up for a wrong
+// ancestor
+func TestTrieExhaustive(t *testing.T) {
+	sets := [][]int{
+		{},
+		{0},
+		{35},
+		{255},
+		{127, 128},
+		{0, 255},
+		{1, 2, 3, 64, 65, 200},
+	}
+	rng := rand.New(rand.NewPCG(1, 3))
+	for range 300 {
+		var set []int
+		density := rng.IntN(256)
+		for key := range 256 {
+			if rng.IntN(256) < density {
+				set = append(set, key)
+			}
+		}
+		sets = append(sets, set)
+	}
+	
+	for _, set := range sets {
+		st := NewSkipTrie(WithUniverse(8), WithHeightFunc(func(uint32) int { return LogLogU }))
+		var present [256]bool
+		for _, key := range set {
+			present[key] = true
+			st.Insert(uint32(key))
+		}
+		// Delete a few again, so the trie also holds repaired entries
+		for i := range len(set) / 8 {
+			key := set[i*7%len(set)]
+			present[key] = false
+			st.Delete(uint32(key))
+		}
+		
+		want := -1
+		for key := range 256 {
+			got := -1 // none
+			if node := st.xFastTriePred(uint32(key), nil); node != nil && node != st.head {
+				got = int(node.key)
+			}
+			if got != want {
+				t.Fatalf("set %v: trie predecessor of %d is %d, want %d (-1 for none)", set, key, got, want)
+			}
+			if present[key] {
+				want = key
+			}
+		}
+		if err := st.Validate(); err != nil {
+			t.Fatalf("set %v: %v", set, err)
+		}
+	}
+}
+
+// TestTrieRoot checks that queries whose top bit differs from every key
+// find their answer through the root entry instead of walking the list
+func TestTrieRoot(t *testing.T) {
+	var flagged []OpReport
+	st := NewSkipTrie(WithAnalysis(func(r OpReport) { flagged = append(flagged, r) }))
+	for i := range 20000 {
+		st.Insert(uint32(i) * 7)
+	}
+	
+	last := uint32(19999 * 7)
+	if pred, ok := st.PredecessorKey(1<<31 + 5); !ok || pred != last {
+		t.Fatalf("PredecessorKey(2^31+5) = %d, %v, want %d", pred, ok, last)
+	}
+	if key, ok := st.Last(); !ok || key != last {
+		t.Fatalf("Last() = %d, %v, want %d", key, ok, last)
+	}
+	if len(flagged) > 0 {
+		t.Fatalf("queries exceeded the cost model: %+v", flagged)
+	}
+}
@@ -0,0 +1,98 @@
+package skiptrie
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// WithExpirySweep deletes keys inserted by InsertWithTTL whose time has
+// passed, in a background sweep over the keys every interval; Close stops
+// it
+func WithExpirySweep(interval time.Duration) Option {
+	return func(st *SkipTrie) {
+		st.sweepEvery = interval
+	}
+}
+
+// startExpirySweep starts the WithExpirySweep background sweep
+func (st *SkipTrie) startExpirySweep() {
+	st.goBackground(func(ctx context.Context) error {
+		ticker := time.NewTicker(st.sweepEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				st.sweepExpired(ctx)
+			}
+		}
+	})
+}
+
+// expired reports whether node has an expiry that has passed at now
+func (n *Node) expired(now int64) bool {
+	expires := n.expires.Load()
+	return expires != 0 && expires <= now
+}
+
+// InsertWithTTL inserts key so that it expires after ttl (never if ttl <=
+// 0), reporting whether it was newly inserted; a present key keeps its
+// expiry
+//
+// An expired key is invisible to Contains at once and deleted by the
+// first lookup that finds it, by SweepExpired or by the WithExpirySweep
+// sweep; ordered queries and Len still count it until then
+func (st *SkipTrie) InsertWithTTL(key uint32, ttl time.Duration) bool {
+	defer st.unpin(st.pin())
+	
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+	init := func(node *Node) {
+		node.expires.Store(expires)
+	}
+	
+	for {
+		node, inserted := st.insertNode(key, init)
+		if inserted {
+			return true
+		}
+		if !node.marked.Load() && !node.expired(time.Now().UnixNano()) {
+			return false
+		}
+		// Expired or being removed; help the removal along and insert afresh
+		st.deleteNode(node)
+	}
+}
+
+// SweepExpired deletes the keys whose InsertWithTTL expiry has passed and
+// returns how many it deleted
+func (st *SkipTrie) SweepExpired() int {
+	return st.sweepExpired(context.Background())
+}
+
+// sweepExpired runs one pass of SweepExpired, stopping early once ctx is
+// done
+func (st *SkipTrie) sweepExpired(ctx context.Context) int {
+	defer st.unpin(st.pin())
+	
+	now := time.Now().UnixNano()
+	var expired []*Node
+	st.ascend(0, math.MaxUint32, func(node *Node) bool {
+		if node.expired(now) {
+			expired = append(expired, node)
+		}
+		return ctx.Err() == nil
+	})
+	
+	deleted := 0
+	for _, node := range expired {
+		if st.deleteNode(node) {
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,47 @@
+package skiptrie
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrNarrowUniverse is returned by Widen for a universe narrower than the
+// current one
+var ErrNarrowUniverse = errors.New("skiptrie: universe narrower than the current one")
+
+// Bounds of the key width WithUniverse accepts
+const (
+	minKeyBits = 8
+	maxKeyBits = 32
+)
+
+// WithUniverse sizes the SkipTrie for keys below 2^keyBits, clamped to
+// [8, 32], instead of the full 32-bit universe
+// The trie then holds only the keyBits prefixes below the shared high
+// bits, its binary search takes log keyBits probes, and the skiplist
+// indexes towers of log log u levels: 4 for u = 2^16, 5 for 2^24 and 2^32.
+// Larger keys are still stored and found correctly, but their searches
+// start further from them; Widen moves the keys to a wider instance
+func WithUniverse(keyBits int) Option {
+	return func(st *SkipTrie) {
+		st.keyBits = min(max(keyBits, minKeyBits), maxKeyBits)
+		st.loglog = bits.Len(uint(st.keyBits - 1))
+	}
+}
+
+// KeyBits returns the width of the key universe the SkipTrie is sized for
+func (st *SkipTrie) KeyBits() int {
+	return st.keyBits
+}
+
+// Widen returns a copy of st sized for keys below 2^keyBits, as Clone
+// does, for moving a small-key instance to a wider universe
+// Like Clone it never blocks writers of st, and keys they change during
+// the copy may or may not be carried over; once callers switch to the
+// copy, st can be dropped
+func (st *SkipTrie) Widen(keyBits int) (*SkipTrie, error) {
+	if keyBits < st.keyBits {
+		return nil, ErrNarrowUniverse
+	}
+	return st.Clone(WithUniverse(keyBits)), nil
+}
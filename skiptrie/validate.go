@@ -0,0 +1,257 @@
+package skiptrie
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Validation shares out the key space in validateBuckets ranges, one per
+// value of the top validateBits bits of a key
+const (
+	validateBits    = 8
+	validateBuckets = 1 << validateBits
+)
+
+// Validate checks the structural invariants of the SkipTrie and returns a
+// *CorruptionReport describing the first violation found, or nil
+// The checks assume no operation runs concurrently; under load they may
+// report states that are only transient. Validate walks every level and
+// the whole prefix table, so it is meant for tests, fuzzing and debugging
+func (st *SkipTrie) Validate() error {
+	if r := st.validate(); r != nil {
+		return r
+	}
+	return nil
+}
+
+// ValidateParallel checks the same invariants as Validate on up to workers
+// goroutines (GOMAXPROCS if workers <= 0), each taking whole key ranges of
+// 1/256 of the universe and slices of the prefix table, and returns a
+// *CorruptionReport for a violation found, or nil
+func (st *SkipTrie) ValidateParallel(workers int) error {
+	var all [validateBuckets]bool
+	for b := range all {
+		all[b] = true
+	}
+	if r := st.validateBuckets(&all, workers, true); r != nil {
+		return r
+	}
+	return nil
+}
+
+// ValidateDirty is ValidateParallel restricted to the key ranges in which
+// a key was inserted or deleted since the previous ValidateDirty, and the
+// prefix-table entries in them, so that routine checks of a large
+// structure cost in proportion to what changed. The bottom-level count is
+// not compared with Len, which needs every range
+// Ranges with a violation stay dirty for the next call
+func (st *SkipTrie) ValidateDirty(workers int) error {
+	var dirty [validateBuckets]bool
+	for b := range dirty {
+		dirty[b] = st.dirty[b].Swap(false)
+	}
+	r := st.validateBuckets(&dirty, workers, false)
+	if r == nil {
+		return nil
+	}
+	
+	for b, taken := range dirty {
+		if taken {
+			st.dirty[b].Store(true)
+		}
+	}
+	return r
+}
+
+// markDirty records a change to key for ValidateDirty
+func (st *SkipTrie) markDirty(key uint32) {
+	if d := &st.dirty[key>>(32-validateBits)]; !d.Load() {
+		d.Store(true)
+	}
+}
+
+// setDirty marks every key range dirty, or clean, for ValidateDirty
+func (st *SkipTrie) setDirty(dirty bool) {
+	for b := range st.dirty {
+		st.dirty[b].Store(dirty)
+	}
+}
+
+// validate checks the structural invariants of a quiescent SkipTrie and
+// returns a report of the first violation found, or nil
+//
+// Every level must be sorted, free of duplicates and deleted nodes, and a
+// subset of the level below; the bottom level must hold Len nodes;
+// top-level prev pointers must match the list; and trie entries must point
+// at indexed nodes inside their prefix and subtree. The trie is only a
+// hint, so two states left by races between inserts and deletes are not
+// reported: a missing entry, and an entry still pointing at a deleted
+// node, which lookups step back from
+func (st *SkipTrie) validate() *CorruptionReport {
+	var all [validateBuckets]bool
+	for b := range all {
+		all[b] = true
+	}
+	return st.validateBuckets(&all, 1, true)
+}
+
+// validateBuckets checks the key ranges selected by want and their trie
+// entries on workers goroutines, and the bottom-level count if full is
+// set; with one worker the ranges are checked in key order
+func (st *SkipTrie) validateBuckets(want *[validateBuckets]bool, workers int, full bool) *CorruptionReport {
+	defer st.unpin(st.pin())
+	
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var buckets []int
+	for b, ok := range want {
+		if ok {
+			buckets = append(buckets, b)
+		}
+	}
+	parts := 1
+	if workers > 1 {
+		parts = 4 * workers
+	}
+	
+	// Jobs are the ranges followed by the prefix-table slices
+	var (
+		next  atomic.Int64
+		count atomic.Int64
+		bad   atomic.Pointer[CorruptionReport]
+		wg    sync.WaitGroup
+	)
+	jobs := int64(len(buckets) + parts)
+	work := func() {
+		defer wg.Done()
+		for bad.Load() == nil {
+			j := next.Add(1) - 1
+			if j >= jobs {
+				return
+			}
+			
+			var r *CorruptionReport
+			if j < int64(len(buckets)) {
+				var n int
+				n, r = st.validateRange(buckets[j])
+				count.Add(int64(n))
+			} else {
+				r = st.validateEntries(int(j)-len(buckets), parts, want)
+			}
+			if r != nil {
+				bad.CompareAndSwap(nil, r)
+			}
+		}
+	}
+	for w := 0; w < min(workers, int(jobs)); w++ {
+		wg.Add(1)
+		go work()
+	}
+	wg.Wait()
+	
+	if r := bad.Load(); r != nil {
+		return r
+	}
+	if n := int(count.Load()); full && n != st.Len() {
+		return st.report(fmt.Sprintf("bottom level holds %d nodes, Len is %d", n, st.Len()), 0, nil)
+	}
+	return nil
+}
+
+// validateRange checks every level within the keys of bucket b, including
+// the order of the first node past it, and returns the bottom-level count
+func (st *SkipTrie) validateRange(b int) (int, *CorruptionReport) {
+	lo := uint32(b) << (32 - validateBits)
+	hi := lo | (1<<(32-validateBits) - 1)
+	top := st.loglog - 1
+	preds := st.levelPreds(lo)
+	
+	count := 0
+	var below map[*Node]bool
+	for level := 0; level < st.levels; level++ {
+		seen := map[*Node]bool{}
+		prev := preds[level]
+		for curr := prev.next[level].Load(); curr != st.tail; curr = curr.next[level].Load() {
+			switch {
+			case curr == nil:
+				return 0, st.report("list ends without reaching the tail", level, nil, prev)
+			case prev != st.head && curr.key <= prev.key:
+				return 0, st.report("keys out of order", level, nil, prev, curr)
+			}
+			if curr.key > hi {
+				break
+			}
+			
+			switch {
+			case curr.marked.Load():
+				return 0, st.report("deleted node still linked", level, nil, curr)
+			case curr.origHeight <= level:
+				return 0, st.report("node linked above its height", level, nil, curr)
+			case level > 0 && !below[curr]:
+				return 0, st.report("node missing from the level below", level, nil, curr)
+			}
+			if level == top && st.loadPrev(curr) != prev {
+				return 0, st.report("prev pointer does not match the list", level, nil, prev, curr)
+			}
+			seen[curr] = true
+			prev = curr
+		}
+		if level == 0 {
+			count = len(seen)
+		}
+		below = seen
+	}
+	return count, nil
+}
+
+// levelPreds returns for each level the last node before key, found by a
+// read-only descent from the head that, unlike listSearch, neither helps
+// unlink deleted nodes nor follows a node above its height
+func (st *SkipTrie) levelPreds(key uint32) [MaxHeight]*Node {
+	var preds [MaxHeight]*Node
+	pred := st.head
+	for level := st.levels - 1; level >= 0; level-- {
+		for {
+			next := pred.next[level].Load()
+			if next == nil || next == st.tail || next.key >= key || next.origHeight <= level {
+				break
+			}
+			pred = next
+		}
+		preds[level] = pred
+	}
+	return preds
+}
+
+// validateEntries checks slice part of parts of the prefix table, skipping
+// entries of at least validateBits bits whose range want leaves out
+func (st *SkipTrie) validateEntries(part, parts int, want *[validateBuckets]bool) *CorruptionReport {
+	var bad *CorruptionReport
+	st.prefixes.rangeEntriesPart(part, parts, func(p prefix, tn *TreeNode) bool {
+		n := p.len()
+		if n >= validateBits && !want[uint32(p)>>(n-validateBits)] {
+			return true
+		}
+		
+		for dir := range tn.pointers {
+			node := tn.pointers[dir].Load()
+			switch {
+			case node == nil:
+			case !p.covers(node.key):
+				bad = st.report("trie entry points outside its prefix", -1, &p, node)
+			case !node.indexed:
+				bad = st.report("trie entry points at an unindexed node", -1, &p, node)
+			case n < 32 && int(node.key>>(31-n)&1) != dir:
+				bad = st.report("trie entry points into the wrong subtree", -1, &p, node)
+			}
+			if bad != nil {
+				return false
+			}
+		}
+		return true
+	})
+	return bad
+}
@@ -0,0 +1,50 @@
+package skiptrie
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// SnapshotInfo describes what Verify read from a snapshot and its log
+type SnapshotInfo struct {
+	Seq      uint64 // sequence number the snapshot was taken at
+	Keys     int    // keys stored in the snapshot
+	Checksum uint32 // CRC-32 of the snapshot, recomputed and matched
+	Records  int    // log records read, all with valid checksums
+	Replayed int    // log records after the snapshot, applied on top of it
+	LastSeq  uint64 // last sequence number recovered
+	TornTail bool   // the log ends in a partial record, as after a crash
+	Len      int    // keys after recovery
+}
+
+// Verify checks a snapshot written by Checkpoint and the log written by
+// WithWAL, either of which may be nil, without touching any live instance:
+// it recomputes their checksums, recovers them into a scratch SkipTrie,
+// runs Validate on the result and recounts its keys
+// The returned info covers everything read up to the first problem
+func Verify(snapshot, wal io.Reader) (SnapshotInfo, error) {
+	var info SnapshotInfo
+	st := NewSkipTrie()
+	defer st.Close()
+	if err := st.recover(snapshot, wal, &info, nil); err != nil {
+		return info, err
+	}
+	if err := st.Validate(); err != nil {
+		return info, err
+	}
+	
+	info.Len = st.Len()
+	walked := 0
+	st.ascend(0, math.MaxUint32, func(*Node) bool {
+		walked++
+		return true
+	})
+	if walked != info.Len {
+		return info, fmt.Errorf("%w: %d keys recovered, Len reports %d", ErrCorrupt, walked, info.Len)
+	}
+	if info.Replayed == 0 && walked != info.Keys {
+		return info, fmt.Errorf("%w: snapshot holds %d keys, %d recovered", ErrCorrupt, info.Keys, walked)
+	}
+	return info, nil
+}
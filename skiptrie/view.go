@@ -0,0 +1,40 @@
+package skiptrie
+
+// ReadOnlyView gives query access to a SkipTrie without the means to
+// change it, for handing a shared index to code that should only read it
+// The view reads the live instance, so it sees changes made through the
+// SkipTrie after it was created
+type ReadOnlyView struct {
+	st *SkipTrie
+}
+
+// View returns a read-only view of st
+func (st *SkipTrie) View() ReadOnlyView {
+	return ReadOnlyView{st: st}
+}
+
+// Contains checks if a key exists
+func (v ReadOnlyView) Contains(key uint32) bool {
+	return v.st.Contains(key)
+}
+
+// Predecessor returns the largest key strictly less than key
+func (v ReadOnlyView) Predecessor(key uint32) (uint32, bool) {
+	return v.st.PredecessorKey(key)
+}
+
+// Successor returns the smallest key strictly greater than key
+func (v ReadOnlyView) Successor(key uint32) (uint32, bool) {
+	return v.st.SuccessorKey(key)
+}
+
+// Range calls fn for each key in [lo, hi] in ascending order until fn
+// returns false
+func (v ReadOnlyView) Range(lo, hi uint32, fn func(key uint32) bool) {
+	v.st.Range(lo, hi, fn)
+}
+
+// Len returns the number of keys
+func (v ReadOnlyView) Len() int {
+	return v.st.Len()
+}
@@ -0,0 +1,88 @@
+package skiptrie
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// keyWaiters is shared by all goroutines waiting for the same key
+type keyWaiters struct {
+	ch    chan struct{} // closed when the key is inserted
+	count int           // goroutines waiting on ch
+}
+
+// waitRegistry tracks goroutines blocked in WaitFor
+// Inserters only take the lock when active is non-zero
+type waitRegistry struct {
+	mu     sync.Mutex
+	keys   map[uint32]*keyWaiters
+	active atomic.Int64 // number of keys with waiters
+}
+
+// register adds a waiter for key and returns its entry
+func (r *waitRegistry) register(key uint32) *keyWaiters {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if r.keys == nil {
+		r.keys = make(map[uint32]*keyWaiters)
+	}
+	w, ok := r.keys[key]
+	if !ok {
+		w = &keyWaiters{ch: make(chan struct{})}
+		r.keys[key] = w
+		r.active.Add(1)
+	}
+	w.count++
+	return w
+}
+
+// unregister removes a waiter that gave up before key was inserted
+func (r *waitRegistry) unregister(key uint32, w *keyWaiters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	w.count--
+	if w.count == 0 && r.keys[key] == w {
+		delete(r.keys, key)
+		r.active.Add(-1)
+	}
+}
+
+// notify wakes every goroutine waiting for key
+func (r *waitRegistry) notify(key uint32) {
+	if r.active.Load() == 0 {
+		return
+	}
+	
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if w, ok := r.keys[key]; ok {
+		close(w.ch)
+		delete(r.keys, key)
+		r.active.Add(-1)
+	}
+}
+
+// WaitFor blocks until key is present or ctx is done, returning ctx.Err()
+// in the latter case
+// Waiters are woken per key by the inserting goroutine; nothing polls
+func (st *SkipTrie) WaitFor(ctx context.Context, key uint32) error {
+	// Register before checking so an insert racing with the check still
+	// finds the waiter
+	w := st.waits.register(key)
+	if st.Contains(key) {
+		st.waits.unregister(key, w)
+		return nil
+	}
+	
+	select {
+	case <-w.ch:
+		return nil
+	case <-ctx.Done():
+		st.waits.unregister(key, w)
+		return ctx.Err()
+	}
+}
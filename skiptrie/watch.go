@@ -0,0 +1,475 @@
+package skiptrie
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrCompacted is returned by Watch when the events following the
+// requested sequence number are no longer retained by the changelog
+var ErrCompacted = errors.New("skiptrie: changelog no longer holds the requested events")
+
+// ErrWatchOverflow reports a subscription cancelled by OverflowCancel
+var ErrWatchOverflow = errors.New("skiptrie: watcher fell too far behind")
+
+// EventOp is the kind of change an Event records
+type EventOp uint8
+
+const (
+	EventInsert EventOp = iota + 1
+	EventDelete
+	EventOverflow // last event of a subscription cancelled by OverflowCancel
+	EventUpdate   // value of a present key replaced (SkipTrieMap.Update)
+)
+
+// String returns the name of the change
+func (op EventOp) String() string {
+	switch op {
+	case EventInsert:
+		return "insert"
+	case EventDelete:
+		return "delete"
+	case EventOverflow:
+		return "overflow"
+	case EventUpdate:
+		return "update"
+	}
+	return "unknown"
+}
+
+// Event is one successful change to the key set, or to the value of a key
+type Event struct {
+	Seq uint64  // position in the changelog, starting at 1
+	Op  EventOp // kind of change
+	Key uint32  // key inserted or deleted
+}
+
+// changeLog sequences changes and fans them out to watchers
+// Changes are only sequenced once it is active, which WithChangelog or the
+// first Watch makes it
+type changeLog struct {
+	active atomic.Bool
+	
+	mu       sync.Mutex
+	seq      uint64                      // sequence number of the last event
+	ring     []Event                     // retained events, oldest at start
+	start    int                         // index of the oldest retained event
+	watchers map[*Watcher]bool           // live subscriptions
+	wal      io.Writer                   // write-ahead log (WithWAL)
+	walErr   error                       // first error writing it
+	encode   func(*Node) ([]byte, error) // value codec for the log (SkipTrieMap.SetCodec)
+	
+	drops atomic.Uint64 // events dropped by bounded watchers
+}
+
+// WithChangelog retains the last n events so that Watch can replay them
+// with WithSince
+// Sequencing changes serializes the bottom-level link of each insert and
+// the mark of each delete through a mutex, so it has a cost even with no
+// watchers attached
+func WithChangelog(n int) Option {
+	return func(st *SkipTrie) {
+		if n > 0 {
+			st.changes.ring = make([]Event, 0, n)
+			st.changes.active.Store(true)
+		}
+	}
+}
+
+// append sequences an event for node, retains it and hands it to the
+// watchers of its key; l.mu must be held
+func (l *changeLog) append(op EventOp, node *Node) {
+	l.seq++
+	key := node.key
+	ev := Event{Seq: l.seq, Op: op, Key: key}
+	
+	switch {
+	case cap(l.ring) == 0:
+	case len(l.ring) < cap(l.ring):
+		l.ring = append(l.ring, ev)
+	default:
+		l.ring[l.start] = ev
+		l.start = (l.start + 1) % len(l.ring)
+	}
+	l.logRecord(ev, node)
+	
+	for w := range l.watchers {
+		if w.covers(key) {
+			w.push(ev)
+		}
+	}
+}
+
+// Inserts and deletes are sequenced at the CAS that makes them take
+// effect, the bottom-level link and the mark, which run under the
+// changelog mutex while it is active, so the events of a key follow the
+// order of its changes. Nodes inserted before the log became active are
+// never published
+
+// linkBottom links node after pred at the bottom level with a CAS from old
+// and sequences its insertion
+func (st *SkipTrie) linkBottom(pred *Node, old ref, node *Node) bool {
+	l := &st.changes
+	if !l.active.Load() {
+		return pred.next[0].CompareAndSwap(old, refOf(node))
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !pred.next[0].CompareAndSwap(old, refOf(node)) {
+		return false
+	}
+	l.append(EventInsert, node)
+	return true
+}
+
+// markDeleted marks node deleted and sequences its deletion, returning
+// false if another deleter marked it first
+func (st *SkipTrie) markDeleted(node *Node) bool {
+	l := &st.changes
+	if !l.active.Load() {
+		return node.marked.CompareAndSwap(false, true)
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !node.marked.CompareAndSwap(false, true) {
+		return false
+	}
+	l.append(EventDelete, node)
+	return true
+}
+
+// publishUpdate records a change to the value of node, unless its
+// deletion is already published
+func (st *SkipTrie) publishUpdate(node *Node) {
+	if !st.changes.active.Load() {
+		return
+	}
+	
+	st.changes.mu.Lock()
+	if !node.marked.Load() {
+		st.changes.append(EventUpdate, node)
+	}
+	st.changes.mu.Unlock()
+}
+
+// Seq returns the sequence number of the last event, 0 before the first
+// one or while no changelog or watcher is active
+func (st *SkipTrie) Seq() uint64 {
+	st.changes.mu.Lock()
+	defer st.changes.mu.Unlock()
+	return st.changes.seq
+}
+
+// OverflowPolicy decides what a watcher with a bounded buffer does with an
+// event that does not fit
+type OverflowPolicy uint8
+
+const (
+	OverflowDropOldest OverflowPolicy = iota // discard the oldest queued event
+	OverflowDropNewest                       // discard the new event
+	OverflowCancel                           // discard the queue and end the subscription with an EventOverflow
+)
+
+// watchConfig collects the WatchOptions of a subscription
+type watchConfig struct {
+	since    uint64
+	hasSince bool
+	buffer   int
+	policy   OverflowPolicy
+	ranges   []keyRange
+}
+
+// WatchOption configures a Watch subscription
+type WatchOption func(*watchConfig)
+
+// WithSince replays the retained events after sequence number seq, the
+// last one the subscriber saw, before live delivery starts; use 0 for
+// everything retained. Watch fails with ErrCompacted if some of those
+// events are no longer retained
+func WithSince(seq uint64) WatchOption {
+	return func(c *watchConfig) {
+		c.since = seq
+		c.hasSince = true
+	}
+}
+
+// WithBuffer bounds the events queued for a slow subscriber to n, applying
+// policy to the events that do not fit; by default the queue is unbounded
+// A subscriber that has stopped receiving holds at most n+1 events: the n
+// queued and the one waiting on the channel
+// After an EventOverflow a subscriber can catch up by watching again
+// WithSince the last sequence number it received
+func WithBuffer(n int, policy OverflowPolicy) WatchOption {
+	return func(c *watchConfig) {
+		c.buffer = n
+		c.policy = policy
+	}
+}
+
+// WithRange adds [lo, hi] to the key ranges a subscription covers from
+// the start, replay included
+func WithRange(lo, hi uint32) WatchOption {
+	return func(c *watchConfig) {
+		c.ranges = addRange(c.ranges, lo, hi)
+	}
+}
+
+// keyRange is a closed interval of keys
+type keyRange struct {
+	lo, hi uint32
+}
+
+// addRange returns rs, sorted and disjoint, with [lo, hi] merged in
+func addRange(rs []keyRange, lo, hi uint32) []keyRange {
+	if lo > hi {
+		return rs
+	}
+	
+	// Ranges overlapping or adjacent to [lo, hi] are rs[i:j]
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].hi >= lo || rs[i].hi+1 == lo })
+	j := i
+	for j < len(rs) && (rs[j].lo <= hi || rs[j].lo-1 == hi) {
+		lo, hi = min(lo, rs[j].lo), max(hi, rs[j].hi)
+		j++
+	}
+	
+	out := append([]keyRange(nil), rs[:i]...)
+	out = append(out, keyRange{lo, hi})
+	return append(out, rs[j:]...)
+}
+
+// removeRange returns rs, sorted and disjoint, without the keys in [lo, hi]
+func removeRange(rs []keyRange, lo, hi uint32) []keyRange {
+	if lo > hi {
+		return rs
+	}
+	
+	var out []keyRange
+	for _, r := range rs {
+		if r.hi < lo || r.lo > hi {
+			out = append(out, r)
+			continue
+		}
+		if r.lo < lo {
+			out = append(out, keyRange{r.lo, lo - 1})
+		}
+		if r.hi > hi {
+			out = append(out, keyRange{hi + 1, r.hi})
+		}
+	}
+	return out
+}
+
+// Watcher is a subscription to the changes of a set of key ranges
+type Watcher struct {
+	C <-chan Event // events in sequence order; closed once the watcher stops
+	
+	st     *SkipTrie
+	ranges []keyRange // sorted and disjoint; guarded by the changelog mutex
+	buffer int
+	policy OverflowPolicy
+	
+	mu      sync.Mutex
+	queue   []Event       // events not yet delivered
+	err     error         // ErrWatchOverflow once cancelled
+	wake    chan struct{} // signalled when queue grows
+	done    chan struct{} // closed by Close
+	once    sync.Once
+	dropped atomic.Uint64 // events discarded by the overflow policy
+}
+
+// Watch subscribes to the successful inserts and deletes of keys in
+// [lo, hi], delivered on the returned Watcher's channel
+// WithRange, AddRange and RemoveRange let one subscription follow several
+// disjoint ranges instead
+// Events queue inside the Watcher until received, so a subscriber must
+// keep up or Close it, or bound the queue with WithBuffer. The channel is
+// closed by Close, after an EventOverflow, or when the SkipTrie's
+// background work stops
+func (st *SkipTrie) Watch(lo, hi uint32, opts ...WatchOption) (*Watcher, error) {
+	var cfg watchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	
+	out := make(chan Event)
+	w := &Watcher{
+		C:      out,
+		st:     st,
+		ranges: addRange(cfg.ranges, lo, hi),
+		buffer: cfg.buffer,
+		policy: cfg.policy,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	
+	l := &st.changes
+	l.mu.Lock()
+	if cfg.hasSince {
+		// Replay under the lock, so live events follow without gap
+		oldest := l.seq + 1 - uint64(len(l.ring))
+		if cfg.since+1 < oldest {
+			l.mu.Unlock()
+			return nil, ErrCompacted
+		}
+		for i := range l.ring {
+			ev := l.ring[(l.start+i)%len(l.ring)]
+			if ev.Seq > cfg.since && w.covers(ev.Key) {
+				w.enqueue(ev)
+			}
+		}
+		if w.err != nil {
+			l.mu.Unlock()
+			return nil, w.err
+		}
+	}
+	if l.watchers == nil {
+		l.watchers = make(map[*Watcher]bool)
+	}
+	l.watchers[w] = true
+	l.active.Store(true)
+	l.mu.Unlock()
+	
+	st.goBackground(func(ctx context.Context) error {
+		w.deliver(ctx, out)
+		return nil
+	})
+	return w, nil
+}
+
+// covers checks if key lies in one of the watched ranges; the changelog
+// mutex must be held
+func (w *Watcher) covers(key uint32) bool {
+	i := sort.Search(len(w.ranges), func(i int) bool { return w.ranges[i].hi >= key })
+	return i < len(w.ranges) && w.ranges[i].lo <= key
+}
+
+// AddRange extends the subscription to the keys in [lo, hi]
+// Only changes sequenced after AddRange returns are delivered for them
+func (w *Watcher) AddRange(lo, hi uint32) {
+	w.st.changes.mu.Lock()
+	w.ranges = addRange(w.ranges, lo, hi)
+	w.st.changes.mu.Unlock()
+}
+
+// RemoveRange stops the subscription from following the keys in [lo, hi]
+// Events already queued for them are still delivered
+func (w *Watcher) RemoveRange(lo, hi uint32) {
+	w.st.changes.mu.Lock()
+	w.ranges = removeRange(w.ranges, lo, hi)
+	w.st.changes.mu.Unlock()
+}
+
+// push queues an event for delivery; the changelog mutex must be held
+func (w *Watcher) push(ev Event) {
+	w.mu.Lock()
+	w.enqueue(ev)
+	if w.err != nil {
+		delete(w.st.changes.watchers, w)
+	}
+	w.mu.Unlock()
+	
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue appends ev to the queue, applying the overflow policy; w.mu or
+// exclusive access must be held
+func (w *Watcher) enqueue(ev Event) {
+	if w.err != nil {
+		return
+	}
+	if w.buffer <= 0 || len(w.queue) < w.buffer {
+		w.queue = append(w.queue, ev)
+		return
+	}
+	
+	switch w.policy {
+	case OverflowDropOldest:
+		w.queue = append(w.queue[1:], ev)
+		w.drop(1)
+	case OverflowDropNewest:
+		w.drop(1)
+	case OverflowCancel:
+		w.drop(uint64(len(w.queue)) + 1)
+		w.queue = append(w.queue[:0], Event{Seq: ev.Seq, Op: EventOverflow, Key: ev.Key})
+		w.err = ErrWatchOverflow
+	}
+}
+
+// drop counts n discarded events
+func (w *Watcher) drop(n uint64) {
+	w.dropped.Add(n)
+	w.st.changes.drops.Add(n)
+}
+
+// Dropped returns the number of events the overflow policy discarded
+func (w *Watcher) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Err returns ErrWatchOverflow once OverflowCancel has ended the
+// subscription, nil otherwise
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// deliver moves queued events to out until the watcher or ctx is done
+// Events are taken off the queue one at a time, so the one being handed
+// over is the only event outside the WithBuffer bound
+func (w *Watcher) deliver(ctx context.Context, out chan<- Event) {
+	defer close(out)
+	defer w.Close()
+	
+	for {
+		w.mu.Lock()
+		ev, ok := Event{}, len(w.queue) > 0
+		if ok {
+			ev = w.queue[0]
+			w.queue = w.queue[1:]
+		}
+		w.mu.Unlock()
+		
+		if ok {
+			select {
+			case out <- ev:
+				if ev.Op == EventOverflow {
+					return
+				}
+			case <-w.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		
+		select {
+		case <-w.wake:
+		case <-w.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close ends the subscription; events still queued are dropped
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		w.st.changes.mu.Lock()
+		delete(w.st.changes.watchers, w)
+		w.st.changes.mu.Unlock()
+		close(w.done)
+	})
+}
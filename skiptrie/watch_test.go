@@ -0,0 +1,55 @@
+//go:build testhooks
+
+package skiptrie
+
+import "testing"
+
+// TestWatchLateInsertEvent holds an insert after its bottom-level link and
+// deletes and re-inserts the key under it: the log must follow the order
+// the changes took effect in, ending with the key present
+func TestWatchLateInsertEvent(t *testing.T) {
+	sched := NewPauseScheduler()
+	st := NewSkipTrie(
+		WithChangelog(16),
+		WithHeightFunc(func(uint32) int { return LogLogU }),
+		WithTestHooks(TestHooks{Pause: sched.Pause}),
+	)
+	
+	linked := sched.Hold(PauseInsertLinked, 7)
+	inserted := make(chan bool)
+	go func() { inserted <- st.Insert(7) }()
+	linked.Wait()
+	
+	unlinked := sched.Hold(PauseTrieDelete, 7)
+	deleted := make(chan bool)
+	go func() { deleted <- st.Delete(7) }()
+	unlinked.Wait()
+	
+	if !st.Insert(7) {
+		t.Fatal("second Insert(7) = false")
+	}
+	linked.Release()
+	unlinked.Release()
+	if !<-inserted || !<-deleted {
+		t.Fatal("held Insert(7) or Delete(7) failed")
+	}
+	
+	w, err := st.Watch(0, 100, WithSince(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	
+	want := []EventOp{EventInsert, EventDelete, EventInsert}
+	if got := st.Seq(); got != uint64(len(want)) {
+		t.Fatalf("Seq() = %d, want %d", got, len(want))
+	}
+	for i, op := range want {
+		if ev := <-w.C; ev.Op != op || ev.Key != 7 {
+			t.Fatalf("event %d = %v %d, want %v 7", i, ev.Op, ev.Key, op)
+		}
+	}
+	if !st.Contains(7) {
+		t.Fatal("Contains(7) = false")
+	}
+}
@@ -0,0 +1,43 @@
+package skiptrie
+
+// WithBuckets indexes only about one top-level node in n in the x-fast
+// trie, in the manner of a y-fast trie: every indexed node, the bucket
+// representative, stands for the top-level nodes up to the next one
+//
+// Each indexed node costs up to 32 prefix entries, so for dense key sets
+// the prefix table shrinks by about a factor of n. A trie query still
+// takes O(log log u) probes, then walks about n/2 top-level nodes on
+// average to the end of the bucket; n around log u = 32 keeps that walk
+// within the cost of the probes. n <= 1 indexes every top-level node
+func WithBuckets(n int) Option {
+	return func(st *SkipTrie) {
+		st.bucketSize = n
+	}
+}
+
+// representative decides whether a new top-level node is indexed in the
+// trie
+func (st *SkipTrie) representative() bool {
+	if st.bucketSize <= 1 {
+		return true
+	}
+	
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.rng.IntN(st.bucketSize) == 0
+}
+
+// indexedAround widens the top-level bracket left, right to the nearest
+// live indexed nodes on each side, or the sentinels
+func (st *SkipTrie) indexedAround(left, right *Node) (*Node, *Node) {
+	top := st.loglog - 1
+	for left != st.head && (!left.indexed || left.marked.Load()) {
+		if left = st.loadPrev(left); left == nil {
+			left = st.head
+		}
+	}
+	for right != nil && right != st.tail && (!right.indexed || right.marked.Load()) {
+		right = right.next[top].Load()
+	}
+	return left, right
+}